@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/keyjin88/go-loyalty-system/internal/app"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"os"
 )
 
 func main() {
@@ -10,7 +11,7 @@ func main() {
 	// api server start
 	err := server.Start()
 	if nil != err {
-		logger.Log.Info("Error starting api server")
-		return
+		logger.Log.Errorf("Error starting api server: %v", err)
+		os.Exit(1)
 	}
 }
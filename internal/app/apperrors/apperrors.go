@@ -0,0 +1,106 @@
+package apperrors
+
+import (
+	"fmt"
+	"time"
+)
+
+// Code стабильный машиночитаемый код ошибки, на который могут опираться клиенты.
+type Code string
+
+const (
+	CodeInvalidRequest           Code = "INVALID_REQUEST"
+	CodeUnauthorized             Code = "UNAUTHORIZED"
+	CodeInvalidCredentials       Code = "INVALID_CREDENTIALS"
+	CodeInvalidRefreshToken      Code = "INVALID_REFRESH_TOKEN"
+	CodeInvalidResetToken        Code = "INVALID_RESET_TOKEN"
+	CodeInvalidTwoFactorCode     Code = "INVALID_TWO_FACTOR_CODE"
+	CodeInvalidLoginChallenge    Code = "INVALID_LOGIN_CHALLENGE"
+	CodeInvalidAPIKey            Code = "INVALID_API_KEY"
+	CodeInvalidOIDCState         Code = "INVALID_OIDC_STATE"
+	CodeOIDCLoginFailed          Code = "OIDC_LOGIN_FAILED"
+	CodeForbidden                Code = "FORBIDDEN"
+	CodeTwoFactorRequired        Code = "TWO_FACTOR_REQUIRED"
+	CodeTwoFactorAlreadyEnabled  Code = "TWO_FACTOR_ALREADY_ENABLED"
+	CodeTooManyAttempts          Code = "TOO_MANY_ATTEMPTS"
+	CodeRateLimitExceeded        Code = "RATE_LIMIT_EXCEEDED"
+	CodeUserAlreadyExists        Code = "USER_ALREADY_EXISTS"
+	CodePartnerAlreadyExists     Code = "PARTNER_ALREADY_EXISTS"
+	CodeIdentityAlreadyLinked    Code = "IDENTITY_ALREADY_LINKED"
+	CodeOrderUploadedByOtherUser Code = "ORDER_UPLOADED_BY_OTHER_USER"
+	CodeInvalidOrderNumber       Code = "INVALID_ORDER_NUMBER"
+	CodeInsufficientFunds        Code = "INSUFFICIENT_FUNDS"
+	CodeNotFound                 Code = "NOT_FOUND"
+	CodeInternal                 Code = "INTERNAL_ERROR"
+)
+
+var (
+	ErrInvalidRequest           = New(CodeInvalidRequest, "invalid request")
+	ErrUnauthorized             = New(CodeUnauthorized, "authorization required")
+	ErrInvalidCredentials       = New(CodeInvalidCredentials, "invalid login or password")
+	ErrInvalidRefreshToken      = New(CodeInvalidRefreshToken, "refresh token is invalid, expired or revoked")
+	ErrInvalidResetToken        = New(CodeInvalidResetToken, "password reset token is invalid, expired or already used")
+	ErrInvalidTwoFactorCode     = New(CodeInvalidTwoFactorCode, "two-factor code is invalid or has already been used")
+	ErrInvalidLoginChallenge    = New(CodeInvalidLoginChallenge, "login challenge is invalid, expired or already used")
+	ErrInvalidAPIKey            = New(CodeInvalidAPIKey, "API key is invalid or revoked")
+	ErrInvalidOIDCState         = New(CodeInvalidOIDCState, "OIDC login state is invalid, expired or already used")
+	ErrOIDCLoginFailed          = New(CodeOIDCLoginFailed, "identity provider did not confirm the login")
+	ErrForbidden                = New(CodeForbidden, "not enough privileges")
+	ErrTwoFactorRequired        = New(CodeTwoFactorRequired, "two-factor code is required for this operation")
+	ErrTwoFactorAlreadyEnabled  = New(CodeTwoFactorAlreadyEnabled, "two-factor authentication is already enabled")
+	ErrUserAlreadyExists        = New(CodeUserAlreadyExists, "user already exists")
+	ErrPartnerAlreadyExists     = New(CodePartnerAlreadyExists, "partner already exists")
+	ErrIdentityAlreadyLinked    = New(CodeIdentityAlreadyLinked, "external identity is already linked to another user")
+	ErrOrderUploadedByOtherUser = New(CodeOrderUploadedByOtherUser, "order already uploaded by another user")
+	ErrInvalidOrderNumber       = New(CodeInvalidOrderNumber, "wrong order number format")
+	ErrInsufficientFunds        = New(CodeInsufficientFunds, "not enough funds")
+	ErrNotFound                 = New(CodeNotFound, "resource not found")
+)
+
+// Error доменная ошибка с кодом. Две ошибки с одинаковым кодом считаются равными для errors.Is,
+// поэтому уточнённое сообщение не мешает сравнению с sentinel-ошибкой.
+type Error struct {
+	Code    Code
+	Message string
+	// RetryAfter через сколько клиент может повторить запрос, если это известно
+	RetryAfter time.Duration
+	// Violations перечень нарушенных правил валидации
+	Violations []string
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// InvalidRequest возвращает ошибку валидации запроса с уточняющим сообщением.
+func InvalidRequest(format string, args ...any) *Error {
+	return Newf(CodeInvalidRequest, format, args...)
+}
+
+// ValidationFailed ошибка валидации со списком всех нарушенных правил.
+func ValidationFailed(violations []string) *Error {
+	return &Error{Code: CodeInvalidRequest, Message: "request validation failed", Violations: violations}
+}
+
+// TooManyAttempts сообщает, что попытки временно заблокированы на retryAfter.
+func TooManyAttempts(retryAfter time.Duration) *Error {
+	return &Error{Code: CodeTooManyAttempts, Message: "too many failed login attempts, try again later", RetryAfter: retryAfter}
+}
+
+// RateLimitExceeded сообщает, что лимит запросов исчерпан и следующий возможен через retryAfter.
+func RateLimitExceeded(retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimitExceeded, Message: "rate limit exceeded, try again later", RetryAfter: retryAfter}
+}
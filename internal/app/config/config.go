@@ -4,17 +4,74 @@ import (
 	"flag"
 	"github.com/caarlos0/env/v6"
 	"log"
+	"time"
 )
 
 type Config struct {
-	ServerAddress               string `env:"RUN_ADDRESS"`
-	GinReleaseMode              bool   `env:"GIN_MODE"`
-	LogLevel                    string `env:"LOG_LEVEL"`
-	DataBaseURI                 string `env:"DATABASE_URI"`
-	SecretKey                   string `env:"SECRET_KEY"`
-	AccrualSystemAddress        string `env:"ACCRUAL_SYSTEM_ADDRESS"`
-	WorkerPoolSize              int    `env:"WORKER_POOL_SIZE"`
-	ProcessingChannelBufferSize int    `env:"PROCESSING_CHANNEL_BUFFER_SIZE"`
+	ServerAddress               string        `env:"RUN_ADDRESS"`
+	TrustedProxies              string        `env:"TRUSTED_PROXIES"`
+	GinReleaseMode              bool          `env:"GIN_MODE"`
+	LogLevel                    string        `env:"LOG_LEVEL"`
+	DataBaseURI                 string        `env:"DATABASE_URI"`
+	SecretKey                   string        `env:"SECRET_KEY"`
+	JWTKeys                     string        `env:"JWT_KEYS"`
+	JWTSigningKeyID             string        `env:"JWT_SIGNING_KEY_ID"`
+	AccessTokenTTL              time.Duration `env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL             time.Duration `env:"REFRESH_TOKEN_TTL"`
+	AccrualSystemAddress        string        `env:"ACCRUAL_SYSTEM_ADDRESS"`
+	WorkerPoolSize              int           `env:"WORKER_POOL_SIZE"`
+	ProcessingChannelBufferSize int           `env:"PROCESSING_CHANNEL_BUFFER_SIZE"`
+	OrderBatchMaxSize           int           `env:"ORDER_BATCH_MAX_SIZE"`
+	LoginFreeAttempts           int           `env:"LOGIN_FREE_ATTEMPTS"`
+	LoginMaxFailures            int           `env:"LOGIN_MAX_FAILURES"`
+	LoginIPFreeAttempts         int           `env:"LOGIN_IP_FREE_ATTEMPTS"`
+	LoginIPMaxFailures          int           `env:"LOGIN_IP_MAX_FAILURES"`
+	LoginBaseDelay              time.Duration `env:"LOGIN_BASE_DELAY"`
+	LoginLockout                time.Duration `env:"LOGIN_LOCKOUT"`
+	LoginMinLength              int           `env:"LOGIN_MIN_LENGTH"`
+	LoginMaxLength              int           `env:"LOGIN_MAX_LENGTH"`
+	LoginPattern                string        `env:"LOGIN_PATTERN"`
+	PasswordMinLength           int           `env:"PASSWORD_MIN_LENGTH"`
+	PasswordMinClasses          int           `env:"PASSWORD_MIN_CLASSES"`
+	PasswordResetTTL            time.Duration `env:"PASSWORD_RESET_TTL"`
+	Notifier                    string        `env:"NOTIFIER"`
+	NotifierFile                string        `env:"NOTIFIER_FILE"`
+	SMTPAddress                 string        `env:"SMTP_ADDRESS"`
+	SMTPUsername                string        `env:"SMTP_USERNAME"`
+	SMTPPassword                string        `env:"SMTP_PASSWORD"`
+	SMTPFrom                    string        `env:"SMTP_FROM"`
+	TOTPIssuer                  string        `env:"TOTP_ISSUER"`
+	LoginChallengeTTL           time.Duration `env:"LOGIN_CHALLENGE_TTL"`
+	WithdrawTOTPThreshold       float64       `env:"WITHDRAW_TOTP_THRESHOLD"`
+	PartnerKeyRateLimit         int           `env:"PARTNER_KEY_RATE_LIMIT"`
+	AuthCookie                  bool          `env:"AUTH_COOKIE"`
+	AuthCookieSecure            bool          `env:"AUTH_COOKIE_SECURE"`
+	AuthCookieDomain            string        `env:"AUTH_COOKIE_DOMAIN"`
+	OIDCIssuer                  string        `env:"OIDC_ISSUER"`
+	OIDCClientID                string        `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret            string        `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL             string        `env:"OIDC_REDIRECT_URL"`
+	OIDCStateTTL                time.Duration `env:"OIDC_STATE_TTL"`
+	WebhookMaxAttempts          int           `env:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBase            time.Duration `env:"WEBHOOK_RETRY_BASE"`
+	WebhookRetryMax             time.Duration `env:"WEBHOOK_RETRY_MAX"`
+	WebhookTimeout              time.Duration `env:"WEBHOOK_TIMEOUT"`
+	WebhookPollInterval         time.Duration `env:"WEBHOOK_POLL_INTERVAL"`
+	WebhookAllowPrivateNetworks bool          `env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	GRPCAddress                 string        `env:"GRPC_ADDRESS"`
+	AdminAddress                string        `env:"ADMIN_ADDRESS"`
+	TracingExporter             string        `env:"TRACING_EXPORTER"`
+	TracingEndpoint             string        `env:"TRACING_ENDPOINT"`
+	TracingInsecure             bool          `env:"TRACING_INSECURE"`
+	TracingFile                 string        `env:"TRACING_FILE"`
+	TracingSampleRatio          float64       `env:"TRACING_SAMPLE_RATIO"`
+	DBConnectTimeout            time.Duration `env:"DB_CONNECT_TIMEOUT"`
+	ReadyTimeout                time.Duration `env:"READY_TIMEOUT"`
+	ReadyQueueSaturation        float64       `env:"READY_QUEUE_SATURATION"`
+	MinMigrationVersion         uint          `env:"MIN_MIGRATION_VERSION"`
+	ShutdownDelay               time.Duration `env:"SHUTDOWN_DELAY"`
+	AdminLogin                  string        `env:"ADMIN_LOGIN"`
+	AdminPassword               string        `env:"ADMIN_PASSWORD"`
 }
 
 func NewConfig() *Config {
@@ -25,15 +82,71 @@ func NewConfig() *Config {
 // и сохраняет их значения в соответствующих переменных
 func (config *Config) InitConfig() {
 	flag.StringVar(&config.ServerAddress, "a", "localhost:8081", "address and port to run server")
+	flag.StringVar(&config.TrustedProxies, "tp", "", "Comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted, empty trusts none")
 	flag.BoolVar(&config.GinReleaseMode, "grm", false, "gin release mode")
 	flag.StringVar(&config.LogLevel, "ll", "info", "log level")
-	flag.StringVar(&config.SecretKey, "sk", "abcdefghijklmnopqrstuvwxyz123456", "secret key for cryptographic")
+	flag.StringVar(&config.SecretKey, "sk", "", "HMAC secret key for tokens, at least 32 bytes")
+	flag.StringVar(&config.JWTKeys, "jk", "", "JWT keys in PEM files: kid1=/path/key1.pem,kid2=/path/key2.pem")
+	flag.StringVar(&config.JWTSigningKeyID, "jsk", "", "kid of the key used to sign new tokens")
+	flag.DurationVar(&config.AccessTokenTTL, "att", 15*time.Minute, "access token lifetime")
+	flag.DurationVar(&config.RefreshTokenTTL, "rtt", 30*24*time.Hour, "refresh token lifetime")
 	flag.StringVar(&config.AccrualSystemAddress, "r", "http://localhost:8080", "accrual system address")
 	//flag.StringVar(&config.DataBaseURI, "d", "", "database dsn")
 	// Оставил для локальных тестов
 	flag.StringVar(&config.DataBaseURI, "d", "host=localhost user=pgadmin password=postgres dbname=loyaltydb port=5432 sslmode=disable", "database URI")
 	flag.IntVar(&config.WorkerPoolSize, "wps", 10, "Worker pool size")
 	flag.IntVar(&config.ProcessingChannelBufferSize, "pcbs", 10, "Processing channel buffer size")
+	flag.IntVar(&config.OrderBatchMaxSize, "obms", 100, "Max order numbers in one batch upload")
+	flag.IntVar(&config.LoginFreeAttempts, "lfa", 3, "Failed logins per login before progressive delay")
+	flag.IntVar(&config.LoginMaxFailures, "lmf", 10, "Failed logins per login before lockout")
+	flag.IntVar(&config.LoginIPFreeAttempts, "lifa", 20, "Failed logins per IP before progressive delay")
+	flag.IntVar(&config.LoginIPMaxFailures, "limf", 100, "Failed logins per IP before lockout")
+	flag.DurationVar(&config.LoginBaseDelay, "lbd", time.Second, "First delay after free login attempts, doubles each failure")
+	flag.DurationVar(&config.LoginLockout, "llo", 15*time.Minute, "Login lockout duration")
+	flag.IntVar(&config.LoginMinLength, "lminl", 3, "Min login length")
+	flag.IntVar(&config.LoginMaxLength, "lmaxl", 64, "Max login length")
+	flag.StringVar(&config.LoginPattern, "lp", `^[A-Za-z0-9._@-]+$`, "Regular expression for allowed logins")
+	flag.IntVar(&config.PasswordMinLength, "pml", 8, "Min password length")
+	flag.IntVar(&config.PasswordMinClasses, "pmc", 2, "Min character classes in password: lowercase, uppercase, digits, other")
+	flag.DurationVar(&config.PasswordResetTTL, "prt", 30*time.Minute, "Password reset token lifetime")
+	flag.StringVar(&config.Notifier, "n", "log", "How to deliver notifications: log (recipient and subject only), file or smtp")
+	flag.StringVar(&config.NotifierFile, "nf", "notifications.log", "File for the file notifier")
+	flag.StringVar(&config.SMTPAddress, "smtp", "", "SMTP server host:port")
+	flag.StringVar(&config.SMTPUsername, "smtpu", "", "SMTP username")
+	flag.StringVar(&config.SMTPPassword, "smtpp", "", "SMTP password")
+	flag.StringVar(&config.SMTPFrom, "smtpf", "", "Sender address for notifications")
+	flag.StringVar(&config.TOTPIssuer, "ti", "Gophermart", "Issuer shown in authenticator apps")
+	flag.DurationVar(&config.LoginChallengeTTL, "lct", 5*time.Minute, "Time to enter the two-factor code after the password")
+	flag.Float64Var(&config.WithdrawTOTPThreshold, "wtt", 1000, "Withdrawals above this sum require a TOTP code from users with 2FA enabled, 0 disables the check")
+	flag.IntVar(&config.PartnerKeyRateLimit, "pkrl", 60, "Default requests per minute for a partner API key")
+	flag.BoolVar(&config.AuthCookie, "ac", false, "Also issue the access token in an HttpOnly cookie with CSRF protection")
+	flag.BoolVar(&config.AuthCookieSecure, "acs", true, "Send session cookies over HTTPS only")
+	flag.StringVar(&config.AuthCookieDomain, "acd", "", "Domain of session cookies, empty for the current host")
+	flag.StringVar(&config.OIDCIssuer, "oi", "", "OpenID Connect issuer URL, empty disables OIDC login")
+	flag.StringVar(&config.OIDCClientID, "oci", "", "OpenID Connect client ID")
+	flag.StringVar(&config.OIDCClientSecret, "ocs", "", "OpenID Connect client secret")
+	flag.StringVar(&config.OIDCRedirectURL, "oru", "", "Redirect URL registered at the provider, must point to /api/user/oidc/callback")
+	flag.DurationVar(&config.OIDCStateTTL, "ost", 10*time.Minute, "Time to return from the OpenID Connect provider")
+	flag.IntVar(&config.WebhookMaxAttempts, "wma", 8, "Webhook delivery attempts before the delivery is marked failed")
+	flag.DurationVar(&config.WebhookRetryBase, "wrb", 30*time.Second, "Delay after the first failed webhook delivery, doubles each failure")
+	flag.DurationVar(&config.WebhookRetryMax, "wrm", 6*time.Hour, "Max delay between webhook delivery attempts")
+	flag.DurationVar(&config.WebhookTimeout, "wto", 10*time.Second, "Time to wait for a partner to answer a webhook")
+	flag.DurationVar(&config.WebhookPollInterval, "wpi", 5*time.Second, "How often the webhook delivery queue is checked")
+	flag.BoolVar(&config.WebhookAllowPrivateNetworks, "wapn", false, "Allow webhooks to loopback and private addresses, for local development only")
+	flag.StringVar(&config.GRPCAddress, "ga", "localhost:8082", "address and port of the gRPC API, empty disables it")
+	flag.StringVar(&config.AdminAddress, "ada", "localhost:8083", "address and port of the admin listener with /metrics, /healthz and /readyz, empty disables it; the probes are also served on the main address")
+	flag.StringVar(&config.TracingExporter, "te", "none", "Where to send trace spans: none, stdout, file or otlp")
+	flag.StringVar(&config.TracingEndpoint, "tep", "localhost:4317", "OTLP gRPC collector address for the otlp trace exporter")
+	flag.BoolVar(&config.TracingInsecure, "tin", false, "Send spans to the OTLP collector without TLS")
+	flag.StringVar(&config.TracingFile, "tf", "traces.json", "File for the file trace exporter")
+	flag.Float64Var(&config.TracingSampleRatio, "tsr", 1, "Share of traces started by the service that are recorded, 0..1")
+	flag.DurationVar(&config.DBConnectTimeout, "dct", 30*time.Second, "How long to retry connecting to the database at startup")
+	flag.DurationVar(&config.ReadyTimeout, "rto", 2*time.Second, "Time limit for readiness checks")
+	flag.Float64Var(&config.ReadyQueueSaturation, "rqs", 0.9, "Order queue fill ratio at which the service reports not ready")
+	flag.UintVar(&config.MinMigrationVersion, "mmv", 0, "Min schema version from database/migrations required for readiness, 0 accepts any")
+	flag.DurationVar(&config.ShutdownDelay, "sdd", 5*time.Second, "Time between failing readiness and stopping the servers on shutdown")
+	flag.StringVar(&config.AdminLogin, "adl", "", "Login granted the admin role on startup, empty grants none")
+	flag.StringVar(&config.AdminPassword, "adp", "", "Password to create the admin with if the login is not registered yet")
 	// парсим переданные серверу аргументы в зарегистрированные переменные
 	flag.Parse()
 	// Пробуем распарсить переменные окружения, если их не будет, то оставляем значения по умолчанию из флагов
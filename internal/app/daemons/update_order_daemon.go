@@ -1,39 +1,92 @@
 package daemons
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 	"github.com/keyjin88/go-loyalty-system/internal/app/services"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
 
-func WorkerProcessingOrders(ch <-chan entities.Order, host string, db *gorm.DB, maxWorkers int, mutex *sync.Mutex) {
+// accrualClient клиент системы Accrual: открывает клиентские спаны и передаёт контекст трассы в traceparent
+var accrualClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// errUserDeleted пользователь заказа удалён, начислять баллы некому
+var errUserDeleted = errors.New("user is deleted")
+
+// WorkerProcessingOrders получает расчёт по заказам из системы Accrual, начисляет баллы
+// и публикует в events смену статуса заказа и изменение баланса. Записи в лог помечаются
+// идентификатором запроса, в котором заказ загружен, а спан обработки продолжает его трассу.
+func WorkerProcessingOrders(
+	ch <-chan dto.OrderTask,
+	host string,
+	db *gorm.DB,
+	maxWorkers int,
+	mutex *sync.Mutex,
+	events *services.EventBus,
+) {
 	workerPool := make(chan struct{}, maxWorkers) // Создаем пул горутин
-	for order := range ch {
+	metrics.OrderWorkers.Set(float64(maxWorkers))
+	for task := range ch {
 		workerPool <- struct{}{} // Заполняем пул горутин
-		go func(orderID uint, accrual float64) {
+		go func(task dto.OrderTask) {
+			ctx := trace.ContextWithSpanContext(context.Background(), task.Trace)
+			if task.RequestID != "" {
+				ctx = logger.WithRequestID(ctx, task.RequestID)
+			}
+			ctx, span := tracing.Start(ctx, "WorkerProcessingOrders.process",
+				attribute.Int64("order.id", int64(task.Order.ID)),
+				attribute.String("order.number", task.Order.Number),
+			)
+			defer span.End()
+			ctx = tracing.WithLogger(ctx)
+			log := logger.FromContext(ctx)
+			orderID := task.Order.ID
+			db := db.WithContext(ctx)
+			metrics.OrderWorkersWaiting.Inc()
+			_, lockSpan := tracing.Start(ctx, "WorkerProcessingOrders.lock")
 			mutex.Lock()
+			lockSpan.End()
+			metrics.OrderWorkersWaiting.Dec()
+			metrics.OrderWorkersBusy.Inc()
 			defer mutex.Unlock()
 			defer func() {
+				metrics.OrderWorkersBusy.Dec()
 				<-workerPool // Освобождаем горутину при завершении
 			}()
 			var order entities.Order
 			if err := db.First(&order, orderID).Error; err != nil {
-				logger.Log.Errorf("Failed to retrieve order %v: %v", orderID, err)
+				log.Errorf("Failed to retrieve order %v: %v", orderID, err)
 				return
 			}
-			getOrderDetails(&order, host)
+			// Заказы удалённого пользователя помечены удалёнными вместе с ним
+			if order.IsDeleted {
+				log.Infof("Order %v belongs to a deleted user, skipping", orderID)
+				return
+			}
+			previousStatus := order.Status
+			getOrderDetails(ctx, db, &order, host)
 			var savedUser entities.User
 			if err := db.First(&savedUser, "id = ?", order.UserID).Error; err != nil {
-				logger.Log.Errorf("Failed to retrieve user %v: %v", order.UserID, err)
+				log.Errorf("Failed to retrieve user %v: %v", order.UserID, err)
 				return
 			}
+			var balance models.BalanceResponse
 			err := db.Set("gorm:query_option", "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").
 				Transaction(func(tx *gorm.DB) error {
 					if err := tx.Model(order).Updates(order).Error; err != nil {
@@ -43,61 +96,136 @@ func WorkerProcessingOrders(ch <-chan entities.Order, host string, db *gorm.DB,
 					if err := tx.First(&savedUser, "id = ?", order.UserID).Error; err != nil {
 						return err
 					}
+					// Пользователь мог быть удалён, пока шёл запрос в систему Accrual
+					if savedUser.IsDeleted {
+						return errUserDeleted
+					}
 					savedUser.Balance += order.Accrual
+					balance = models.BalanceResponse{Current: savedUser.Balance, Withdrawn: savedUser.Withdrawn}
 					return tx.Updates(&savedUser).Error
 				})
+			if errors.Is(err, errUserDeleted) {
+				log.Infof("User %v of order %v is deleted, accrual is not credited", order.UserID, order.ID)
+				return
+			}
 			if err != nil {
-				logger.Log.Error("Failed to process order %v: %v", order.ID, err)
+				log.Errorf("Failed to process order %v: %v", order.ID, err)
+				return
+			}
+			metrics.PointsAccrued.Add(order.Accrual)
+			if order.Status != previousStatus {
+				events.PublishLogged(ctx, order.UserID, entities.EventOrderStatusChanged, models.OrderStatusEvent{
+					Number:  order.Number,
+					Status:  order.Status,
+					Accrual: order.Accrual,
+				})
+			}
+			if order.Accrual != 0 {
+				events.PublishLogged(ctx, order.UserID, entities.EventBalanceChanged, balance)
 			}
-		}(order.ID, order.Accrual)
+		}(task)
 	}
 }
 
-func getOrderDetails(order *entities.Order, host string) {
+func getOrderDetails(ctx context.Context, db *gorm.DB, order *entities.Order, host string) {
 	url := fmt.Sprintf(host+"/api/orders/%s", order.Number)
 	maxRetries := 5
 	retryInterval := 1 * time.Second
 	for i := 0; i < maxRetries; i++ {
-		resp, err := http.Get(url)
-		if err != nil {
-			logger.Log.Infof("Error getting order info from: %s", url)
+		if !requestOrderDetails(ctx, db, order, url, i == maxRetries-1) {
 			return
 		}
-		switch resp.StatusCode {
-		case http.StatusOK:
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				logger.Log.Infof("Error reading response")
-				return
-			}
-			var details services.AccrualDetails
-			err = json.Unmarshal(body, &details)
-			if err != nil {
-				logger.Log.Infof("Error unmarshalling response")
-				return
-			}
-			order.Status = details.Status
-			order.Accrual = details.Accrual
-		case http.StatusNoContent:
-			logger.Log.Infof("заказ %s не зарегистрирован в системе расчета", order.Number)
-			return
-		case http.StatusTooManyRequests:
-			if i == maxRetries-1 {
-				logger.Log.Infof("превышено количество запросов по заказу: %s", order.Number)
-				return
-			}
-			err := resp.Body.Close()
-			if err != nil {
-				logger.Log.Infof("error while closing response body")
-				return
-			}
-			time.Sleep(retryInterval)
-		case http.StatusInternalServerError:
-			logger.Log.Infof("внутренняя ошибка сервера")
-			return
-		default:
-			logger.Log.Infof("непредвиденный статус ответа: %s", resp.Status)
-			return
+		time.Sleep(retryInterval)
+	}
+}
+
+// requestOrderDetails одно обращение к системе Accrual. Возвращает true, если запрос нужно повторить.
+// Тело ответа закрывается при любом исходе, иначе соединение не вернётся в пул.
+func requestOrderDetails(ctx context.Context, db *gorm.DB, order *entities.Order, url string, last bool) bool {
+	log := logger.FromContext(ctx)
+	start := time.Now()
+	resp, err := getAccrual(ctx, url)
+	observeAccrualRequest(start, resp, err)
+	if err != nil {
+		log.Infof("Error getting order info from: %s", url)
+		recordAttempt(ctx, db, order.ID, 0, "", err.Error())
+		return false
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Infof("error while closing response body")
+		}
+	}()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Infof("Error reading response")
+			recordAttempt(ctx, db, order.ID, resp.StatusCode, "", err.Error())
+			return false
+		}
+		var details services.AccrualDetails
+		err = json.Unmarshal(body, &details)
+		if err != nil {
+			log.Infof("Error unmarshalling response")
+			recordAttempt(ctx, db, order.ID, resp.StatusCode, "", err.Error())
+			return false
+		}
+		recordAttempt(ctx, db, order.ID, resp.StatusCode, details.Status, "")
+		order.Status = details.Status
+		order.Accrual = details.Accrual
+		return false
+	case http.StatusNoContent:
+		recordAttempt(ctx, db, order.ID, resp.StatusCode, "", "order is not registered in accrual system")
+		log.Infof("заказ %s не зарегистрирован в системе расчета", order.Number)
+		return false
+	case http.StatusTooManyRequests:
+		recordAttempt(ctx, db, order.ID, resp.StatusCode, "", "too many requests")
+		if last {
+			log.Infof("превышено количество запросов по заказу: %s", order.Number)
+			return false
+		}
+		return true
+	case http.StatusInternalServerError:
+		recordAttempt(ctx, db, order.ID, resp.StatusCode, "", "accrual system internal error")
+		log.Infof("внутренняя ошибка сервера")
+		return false
+	default:
+		recordAttempt(ctx, db, order.ID, resp.StatusCode, "", "unexpected response status")
+		log.Infof("непредвиденный статус ответа: %s", resp.Status)
+		return false
+	}
+}
+
+// recordAttempt сохраняет результат обращения к системе Accrual для истории обработки заказа
+func recordAttempt(ctx context.Context, db *gorm.DB, orderID uint, httpStatus int, status string, errorMessage string) {
+	attempt := entities.OrderAttempt{
+		OrderID:    orderID,
+		HTTPStatus: httpStatus,
+		Status:     status,
+		Error:      errorMessage,
+	}
+	if err := db.Create(&attempt).Error; err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record processing attempt for order %v: %v", orderID, err)
+	}
+}
+
+func getAccrual(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return accrualClient.Do(request)
+}
+
+// observeAccrualRequest учитывает время ответа системы Accrual и ответы 429
+func observeAccrualRequest(start time.Time, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			metrics.TooManyRequests.WithLabelValues(metrics.SourceAccrual).Inc()
 		}
 	}
+	metrics.AccrualRequestDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
 }
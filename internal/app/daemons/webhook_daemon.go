@@ -0,0 +1,20 @@
+package daemons
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services"
+	"time"
+)
+
+// WorkerDeliveringWebhooks раз в interval отправляет партнёрам webhook, время доставки которых наступило
+func WorkerDeliveringWebhooks(webhooks *services.WebhookService, interval time.Duration) {
+	ctx := context.Background()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := webhooks.DeliverDue(ctx); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to deliver webhooks: %v", err)
+		}
+	}
+}
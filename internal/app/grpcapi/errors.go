@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain домен кодов ошибок в google.rpc.ErrorInfo
+const errorDomain = "gophermart"
+
+// statusCodes сопоставляет код доменной ошибки со статусом gRPC, как problemStatuses в HTTP API.
+// Повторная загрузка своего заказа ошибкой не считается и обрабатывается в UploadOrder.
+var statusCodes = map[apperrors.Code]codes.Code{
+	apperrors.CodeInvalidRequest:           codes.InvalidArgument,
+	apperrors.CodeUnauthorized:             codes.Unauthenticated,
+	apperrors.CodeInvalidCredentials:       codes.Unauthenticated,
+	apperrors.CodeInvalidRefreshToken:      codes.Unauthenticated,
+	apperrors.CodeInvalidResetToken:        codes.InvalidArgument,
+	apperrors.CodeInvalidTwoFactorCode:     codes.Unauthenticated,
+	apperrors.CodeInvalidLoginChallenge:    codes.Unauthenticated,
+	apperrors.CodeInvalidAPIKey:            codes.Unauthenticated,
+	apperrors.CodeInvalidOIDCState:         codes.InvalidArgument,
+	apperrors.CodeOIDCLoginFailed:          codes.Unauthenticated,
+	apperrors.CodeForbidden:                codes.PermissionDenied,
+	apperrors.CodeTwoFactorRequired:        codes.PermissionDenied,
+	apperrors.CodeTwoFactorAlreadyEnabled:  codes.FailedPrecondition,
+	apperrors.CodeTooManyAttempts:          codes.ResourceExhausted,
+	apperrors.CodeRateLimitExceeded:        codes.ResourceExhausted,
+	apperrors.CodeUserAlreadyExists:        codes.AlreadyExists,
+	apperrors.CodePartnerAlreadyExists:     codes.AlreadyExists,
+	apperrors.CodeIdentityAlreadyLinked:    codes.AlreadyExists,
+	apperrors.CodeOrderUploadedByOtherUser: codes.AlreadyExists,
+	apperrors.CodeInvalidOrderNumber:       codes.InvalidArgument,
+	apperrors.CodeInsufficientFunds:        codes.FailedPrecondition,
+	apperrors.CodeNotFound:                 codes.NotFound,
+}
+
+// toStatus единая точка преобразования ошибок сервисов в статус gRPC. Код доменной ошибки
+// передаётся в ErrorInfo, время ожидания - в RetryInfo, нарушения валидации - в BadRequest.
+// Неизвестные ошибки превращаются во внутреннюю ошибку без подробностей.
+func toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		if code, ok := statusCodes[appErr.Code]; ok {
+			logger.FromContext(ctx).Infof("grpc call failed with %s: %v", appErr.Code, err)
+			details := []protoiface.MessageV1{
+				&errdetails.ErrorInfo{Reason: string(appErr.Code), Domain: errorDomain},
+			}
+			if appErr.RetryAfter > 0 {
+				details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(appErr.RetryAfter)})
+			}
+			if len(appErr.Violations) > 0 {
+				violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(appErr.Violations))
+				for _, violation := range appErr.Violations {
+					violations = append(violations, &errdetails.BadRequest_FieldViolation{Description: violation})
+				}
+				details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+			}
+			st, detailsErr := status.New(code, appErr.Message).WithDetails(details...)
+			if detailsErr != nil {
+				return status.Error(code, appErr.Message)
+			}
+			return st.Err()
+		}
+	}
+	logger.FromContext(ctx).Errorf("internal server error: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
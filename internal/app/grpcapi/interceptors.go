@@ -0,0 +1,266 @@
+package grpcapi
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/grpcapi/pb"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/middleware"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestIDMetadata ключ метаданных с идентификатором запроса, как заголовок X-Request-ID в HTTP API
+const requestIDMetadata = "x-request-id"
+
+type contextKey int
+
+const (
+	accessTokenKey contextKey = iota
+	auditKey
+)
+
+// publicMethods методы, доступные без access-токена
+var publicMethods = map[string]bool{
+	pb.Gophermart_Register_FullMethodName:      true,
+	pb.Gophermart_Login_FullMethodName:         true,
+	pb.Gophermart_CompleteLogin_FullMethodName: true,
+}
+
+// auditedMethods методы, которые записываются в журнал аудита, и их события
+var auditedMethods = map[string]string{
+	pb.Gophermart_Register_FullMethodName:      entities.AuditRegister,
+	pb.Gophermart_Login_FullMethodName:         entities.AuditLogin,
+	pb.Gophermart_CompleteLogin_FullMethodName: entities.AuditLoginTwoFactor,
+	pb.Gophermart_Withdraw_FullMethodName:      entities.AuditWithdraw,
+}
+
+// UnaryErrorInterceptor переводит ошибки сервисов в статусы gRPC. Ставится первым в цепочке,
+// чтобы остальные перехватчики видели доменные ошибки.
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, toStatus(ctx, err)
+	}
+}
+
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return toStatus(ss.Context(), handler(srv, ss))
+	}
+}
+
+// UnaryLoggingInterceptor аналог RequestID и AccessLog: берёт идентификатор запроса из метаданных
+// x-request-id или создаёт новый, возвращает его в заголовке ответа, кладёт в контекст логер
+// с этим идентификатором и пишет запись о каждом вызове. Ставится первым в цепочке.
+func UnaryLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = withRequestID(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func StreamLoggingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context())
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	requestID := middleware.EnsureRequestID(metadataValue(ctx, requestIDMetadata))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID)); err != nil {
+		logger.FromContext(ctx).Errorf("failed to set request id header: %v", err)
+	}
+	return tracing.WithLogger(logger.WithRequestID(ctx, requestID))
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []any{
+		"method", method,
+		"code", code.String(),
+		"latency", time.Since(start),
+		"client_ip", clientIP(ctx),
+		"user_agent", metadataValue(ctx, "user-agent"),
+	}
+	log := logger.FromContext(ctx)
+	if code == codes.Internal || code == codes.Unknown {
+		log.Errorw("grpc call", fields...)
+		return
+	}
+	log.Infow("grpc call", fields...)
+}
+
+// UnaryAuthInterceptor аналог AuthMiddleware: проверяет access-токен из метаданных authorization
+// и кладёт его в контекст вызова. Методы входа и регистрации пропускаются без токена.
+func UnaryAuthInterceptor(keyfunc jwt.Keyfunc, revocations middleware.TokenRevocationChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, keyfunc, revocations)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuthInterceptor(keyfunc jwt.Keyfunc, revocations middleware.TokenRevocationChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), keyfunc, revocations)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, keyfunc jwt.Keyfunc, revocations middleware.TokenRevocationChecker) (context.Context, error) {
+	// Значение без схемы принимается как есть, как и в HTTP API
+	tokenString := strings.TrimPrefix(metadataValue(ctx, "authorization"), "Bearer ")
+	if tokenString == "" {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "authorization token is missing")
+	}
+	token, err := middleware.VerifyAccessToken(ctx, tokenString, keyfunc, revocations)
+	if err != nil {
+		return nil, err
+	}
+	setAudit(ctx, func(entry *auditEntry) { entry.actorID = token.UserID })
+	return context.WithValue(ctx, accessTokenKey, token), nil
+}
+
+// contextStream поток с контекстом, дополненным перехватчиком
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// auditEntry участник, объект и результат события: участника из токена заполняет проверка токена,
+// остальное уточняет метод сервера
+type auditEntry struct {
+	actorID uint
+	target  string
+	outcome string
+}
+
+// UnaryAuditInterceptor записывает в журнал аудита вызовы auditedMethods, а для остальных методов
+// только отказы в доступе. Ставится перед проверкой токена, чтобы попадали и отказы самой проверки.
+func UnaryAuditInterceptor(recorder middleware.AuditRecorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		entry := &auditEntry{}
+		resp, err := handler(context.WithValue(ctx, auditKey, entry), req)
+		if action, ok := auditedMethods[info.FullMethod]; ok {
+			recordAuditEvent(ctx, recorder, action, entry, err)
+		} else if rejected(err) {
+			entry.target = info.FullMethod
+			recordAuditEvent(ctx, recorder, entities.AuditTokenRejected, entry, err)
+		}
+		return resp, err
+	}
+}
+
+func StreamAuditInterceptor(recorder middleware.AuditRecorder) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if rejected(err) {
+			recordAuditEvent(ss.Context(), recorder, entities.AuditTokenRejected, &auditEntry{target: info.FullMethod}, err)
+		}
+		return err
+	}
+}
+
+func rejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	status := handlers.ErrorStatus(err)
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+func recordAuditEvent(ctx context.Context, recorder middleware.AuditRecorder, action string, entry *auditEntry, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status = handlers.ErrorStatus(err)
+	}
+	outcome := entry.outcome
+	if outcome == "" {
+		outcome = entities.AuditOutcomeSuccess
+		if status >= http.StatusBadRequest {
+			outcome = entities.AuditOutcomeFailure
+		}
+	}
+	err = recorder.Record(ctx, dto.AuditEventDTO{
+		ActorID:   entry.actorID,
+		Action:    action,
+		Target:    entry.target,
+		IP:        clientIP(ctx),
+		UserAgent: metadataValue(ctx, "user-agent"),
+		Status:    status,
+		Outcome:   outcome,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to record audit event: %v", err)
+	}
+}
+
+// setAudit даёт методу уточнить событие аудита, если вызов записывается в журнал
+func setAudit(ctx context.Context, update func(entry *auditEntry)) {
+	if entry, ok := ctx.Value(auditKey).(*auditEntry); ok {
+		update(entry)
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func clientInfo(ctx context.Context) dto.ClientInfo {
+	return dto.ClientInfo{IP: clientIP(ctx), UserAgent: metadataValue(ctx, "user-agent")}
+}
@@ -0,0 +1,277 @@
+package grpcapi
+
+//go:generate protoc -I proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative gophermart.proto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/grpcapi/pb"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/middleware"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+var orderStatuses = map[string]bool{
+	"NEW":        true,
+	"PROCESSING": true,
+	"INVALID":    true,
+	"PROCESSED":  true,
+}
+
+// Server gRPC API поверх тех же сервисов, что и HTTP-обработчики
+type Server struct {
+	pb.UnimplementedGophermartServer
+	userService      handlers.UserService
+	orderService     handlers.OrderService
+	withdrawService  handlers.WithdrawService
+	tokenService     handlers.TokenService
+	twoFactorService handlers.TwoFactorService
+	eventService     handlers.EventService
+}
+
+func NewServer(
+	userService handlers.UserService,
+	orderService handlers.OrderService,
+	withdrawService handlers.WithdrawService,
+	tokenService handlers.TokenService,
+	twoFactorService handlers.TwoFactorService,
+	eventService handlers.EventService,
+) *Server {
+	return &Server{
+		userService:      userService,
+		orderService:     orderService,
+		withdrawService:  withdrawService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		eventService:     eventService,
+	}
+}
+
+func (s *Server) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.AuthResponse, error) {
+	setAudit(ctx, func(entry *auditEntry) { entry.target = req.Login })
+	user, err := s.userService.SaveUser(ctx, dto.UserDTO{
+		UserName: req.Login,
+		Password: req.Password,
+		Email:    req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	setAudit(ctx, func(entry *auditEntry) { entry.actorID = user.ID })
+	return s.issueTokens(ctx, user.ID)
+}
+
+// Login первый шаг входа. Пользователю с подключённым вторым фактором вместо токенов
+// возвращается вызов для CompleteLogin.
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.AuthResponse, error) {
+	setAudit(ctx, func(entry *auditEntry) { entry.target = req.Login })
+	user, err := s.userService.GetUserByUserName(ctx, dto.UserDTO{
+		UserName: req.Login,
+		Password: req.Password,
+		IP:       clientIP(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	setAudit(ctx, func(entry *auditEntry) { entry.actorID = user.ID })
+	if !user.TOTPEnabled {
+		return s.issueTokens(ctx, user.ID)
+	}
+	challenge, err := s.twoFactorService.CreateChallenge(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	setAudit(ctx, func(entry *auditEntry) { entry.outcome = entities.AuditOutcomeChallenge })
+	return &pb.AuthResponse{Result: &pb.AuthResponse_Challenge{Challenge: &pb.LoginChallenge{
+		Challenge: challenge.Challenge,
+		ExpiresIn: challenge.ExpiresIn,
+	}}}, nil
+}
+
+func (s *Server) CompleteLogin(ctx context.Context, req *pb.CompleteLoginRequest) (*pb.AuthResponse, error) {
+	userID, err := s.twoFactorService.CompleteChallenge(ctx, req.Challenge, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	setAudit(ctx, func(entry *auditEntry) { entry.actorID = userID })
+	return s.issueTokens(ctx, userID)
+}
+
+func (s *Server) issueTokens(ctx context.Context, userID uint) (*pb.AuthResponse, error) {
+	tokens, err := s.tokenService.IssueTokens(ctx, userID, clientInfo(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT token: %w", err)
+	}
+	return &pb.AuthResponse{Result: &pb.AuthResponse_Tokens{Tokens: &pb.Tokens{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    tokens.TokenType,
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+	}}}, nil
+}
+
+// UploadOrder загружает заказ. Повторная загрузка своего заказа, как и в HTTP API, ошибкой не считается.
+func (s *Server) UploadOrder(ctx context.Context, req *pb.UploadOrderRequest) (*pb.UploadOrderResponse, error) {
+	result, err := s.orderService.SaveOrder(ctx, dto.OrderDTO{Number: req.Number, UserID: userID(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UploadOrderResponse{Number: result.Order.Number, AlreadyUploaded: result.AlreadyUploaded}, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	query, err := pageQuery(ctx, req.Limit, req.Cursor, req.Descending)
+	if err != nil {
+		return nil, err
+	}
+	for _, orderStatus := range req.Statuses {
+		orderStatus = strings.ToUpper(strings.TrimSpace(orderStatus))
+		if !orderStatuses[orderStatus] {
+			return nil, apperrors.InvalidRequest("unknown status %q", orderStatus)
+		}
+		query.Statuses = append(query.Statuses, orderStatus)
+	}
+	page, err := s.orderService.GetOrdersPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*pb.Order, 0, len(page.Items))
+	for _, order := range page.Items {
+		orders = append(orders, &pb.Order{
+			Number:     order.Number,
+			Status:     order.Status,
+			Accrual:    order.Accrual,
+			UploadedAt: order.UploadedAt,
+		})
+	}
+	return &pb.ListOrdersResponse{Orders: orders, NextCursor: page.NextCursor}, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, _ *pb.GetBalanceRequest) (*pb.Balance, error) {
+	balance, err := s.userService.GetUserBalance(ctx, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Balance{Current: balance.Current, Withdrawn: balance.Withdrawn}, nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *pb.WithdrawRequest) (*pb.WithdrawResponse, error) {
+	setAudit(ctx, func(entry *auditEntry) { entry.target = req.Order })
+	err := s.withdrawService.SaveWithdraw(ctx, dto.WithdrawDTO{
+		OrderNumber: req.Order,
+		Sum:         req.Sum,
+		UserID:      userID(ctx),
+		TOTPCode:    req.TotpCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.WithdrawResponse{}, nil
+}
+
+func (s *Server) ListWithdrawals(ctx context.Context, req *pb.ListWithdrawalsRequest) (*pb.ListWithdrawalsResponse, error) {
+	query, err := pageQuery(ctx, req.Limit, req.Cursor, req.Descending)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.withdrawService.GetWithdrawalsPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	withdrawals := make([]*pb.Withdrawal, 0, len(page.Items))
+	for _, withdrawal := range page.Items {
+		withdrawals = append(withdrawals, &pb.Withdrawal{
+			Order:       withdrawal.Order,
+			Sum:         withdrawal.Sum,
+			ProcessedAt: withdrawal.ProcessedAt,
+		})
+	}
+	return &pb.ListWithdrawalsResponse{Withdrawals: withdrawals, NextCursor: page.NextCursor}, nil
+}
+
+// WatchOrders отдаёт смену статусов заказов пользователя, пока клиент не отключится.
+// Подписчика, не успевающего читать события, шина отключает, и поток завершается со статусом
+// Unavailable: клиенту нужно переподключиться с last_event_id.
+func (s *Server) WatchOrders(req *pb.WatchOrdersRequest, stream pb.Gophermart_WatchOrdersServer) error {
+	ctx := stream.Context()
+	lastEventID := uint(req.LastEventId)
+	subscription, err := s.eventService.Subscribe(ctx, userID(ctx), lastEventID)
+	if err != nil {
+		return err
+	}
+	defer subscription.Cancel()
+
+	send := func(event entities.UserEvent) error {
+		// Событие могло уже уйти среди пропущенных
+		if event.ID <= lastEventID {
+			return nil
+		}
+		lastEventID = event.ID
+		if event.Type != entities.EventOrderStatusChanged {
+			return nil
+		}
+		var payload models.OrderStatusEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return fmt.Errorf("failed to decode event %d: %w", event.ID, err)
+		}
+		return stream.Send(&pb.OrderStatusEvent{
+			Id:      uint64(event.ID),
+			Number:  payload.Number,
+			Status:  payload.Status,
+			Accrual: payload.Accrual,
+		})
+	}
+	for _, event := range subscription.Missed {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream closed, resubscribe with last_event_id")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pageQuery параметры страницы списка. Нулевой limit означает размер страницы по умолчанию.
+func pageQuery(ctx context.Context, limit int32, cursor string, descending bool) (dto.PageQuery, error) {
+	query := dto.PageQuery{
+		UserID:     userID(ctx),
+		Limit:      defaultPageLimit,
+		Cursor:     cursor,
+		Descending: descending,
+	}
+	if limit != 0 {
+		if limit < 1 || limit > maxPageLimit {
+			return dto.PageQuery{}, apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit)
+		}
+		query.Limit = int(limit)
+	}
+	return query, nil
+}
+
+// userID пользователь из проверенного перехватчиком токена
+func userID(ctx context.Context) uint {
+	token, _ := ctx.Value(accessTokenKey).(middleware.AccessToken)
+	return token.UserID
+}
@@ -0,0 +1,406 @@
+package grpcapi
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/grpcapi/pb"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	appservices "github.com/keyjin88/go-loyalty-system/internal/app/services"
+	servicemocks "github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test secret key of at least 32 bytes")
+
+type noRevocations struct{}
+
+func (noRevocations) IsRevoked(context.Context, string, string) (bool, error) {
+	return false, nil
+}
+
+type auditLog struct {
+	mu     sync.Mutex
+	events []dto.AuditEventDTO
+}
+
+func (l *auditLog) Record(_ context.Context, event dto.AuditEventDTO) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+	return nil
+}
+
+type testServices struct {
+	users     *mocks.MockUserService
+	orders    *mocks.MockOrderService
+	withdraws *mocks.MockWithdrawService
+	tokens    *mocks.MockTokenService
+	twoFactor *mocks.MockTwoFactorService
+	events    *mocks.MockEventService
+}
+
+// startServer поднимает сервер с перехватчиками как в setup.go поверх bufconn и возвращает клиента
+func startServer(t *testing.T, ctrl *gomock.Controller, audit *auditLog) (pb.GophermartClient, testServices) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	services := testServices{
+		users:     mocks.NewMockUserService(ctrl),
+		orders:    mocks.NewMockOrderService(ctrl),
+		withdraws: mocks.NewMockWithdrawService(ctrl),
+		tokens:    mocks.NewMockTokenService(ctrl),
+		twoFactor: mocks.NewMockTwoFactorService(ctrl),
+		events:    mocks.NewMockEventService(ctrl),
+	}
+	keyfunc := func(*jwt.Token) (any, error) { return testSecret, nil }
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			UnaryLoggingInterceptor(),
+			UnaryErrorInterceptor(),
+			UnaryAuditInterceptor(audit),
+			UnaryAuthInterceptor(keyfunc, noRevocations{}),
+		),
+		grpc.ChainStreamInterceptor(
+			StreamLoggingInterceptor(),
+			StreamErrorInterceptor(),
+			StreamAuditInterceptor(audit),
+			StreamAuthInterceptor(keyfunc, noRevocations{}),
+		),
+	)
+	pb.RegisterGophermartServer(server, NewServer(
+		services.users, services.orders, services.withdraws, services.tokens, services.twoFactor, services.events,
+	))
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	return pb.NewGophermartClient(conn), services
+}
+
+func authorized(t *testing.T, userID uint) context.Context {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userID": userID,
+		"jti":    "jti-1",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	}).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestServer_Login(t *testing.T) {
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+	tests := []struct {
+		name        string
+		user        entities.User
+		loginError  error
+		code        codes.Code
+		tokens      bool
+		challenge   bool
+		auditStatus int
+		outcome     string
+	}{
+		{
+			name:        "Tokens",
+			user:        entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}},
+			code:        codes.OK,
+			tokens:      true,
+			auditStatus: http.StatusOK,
+			outcome:     entities.AuditOutcomeSuccess,
+		},
+		{
+			name:        "Two-factor challenge",
+			user:        entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true},
+			code:        codes.OK,
+			challenge:   true,
+			auditStatus: http.StatusOK,
+			outcome:     entities.AuditOutcomeChallenge,
+		},
+		{
+			name:        "Invalid credentials",
+			loginError:  apperrors.New(apperrors.CodeInvalidCredentials, "invalid login or password"),
+			code:        codes.Unauthenticated,
+			auditStatus: http.StatusUnauthorized,
+			outcome:     entities.AuditOutcomeFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			audit := &auditLog{}
+			client, services := startServer(t, ctrl, audit)
+			services.users.EXPECT().GetUserByUserName(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, userDTO dto.UserDTO) (entities.User, error) {
+				if userDTO.UserName != "user" || userDTO.Password != "password" || userDTO.IP == "" {
+					t.Errorf("unexpected login %+v", userDTO)
+				}
+				return tt.user, tt.loginError
+			})
+			if tt.tokens {
+				services.tokens.EXPECT().IssueTokens(gomock.Any(), uint(7), gomock.Any()).Return(tokens, nil)
+			}
+			if tt.challenge {
+				services.twoFactor.EXPECT().CreateChallenge(gomock.Any(), uint(7)).
+					Return(models.LoginChallengeResponse{TwoFactorRequired: true, Challenge: "c", ExpiresIn: 300}, nil)
+			}
+
+			resp, err := client.Login(context.Background(), &pb.LoginRequest{Login: "user", Password: "password"})
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+			if tt.tokens && resp.GetTokens().GetAccessToken() != "access" {
+				t.Errorf("expected tokens, got %v", resp)
+			}
+			if tt.challenge && resp.GetChallenge().GetChallenge() != "c" {
+				t.Errorf("expected challenge, got %v", resp)
+			}
+			if len(audit.events) != 1 {
+				t.Fatalf("expected one audit event, got %+v", audit.events)
+			}
+			event := audit.events[0]
+			if event.Action != entities.AuditLogin || event.Target != "user" ||
+				event.Status != tt.auditStatus || event.Outcome != tt.outcome {
+				t.Errorf("unexpected audit event %+v", event)
+			}
+		})
+	}
+}
+
+func TestServer_Authorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	audit := &auditLog{}
+	client, services := startServer(t, ctrl, audit)
+
+	_, err := client.GetBalance(context.Background(), &pb.GetBalanceRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without token, got %v", err)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != entities.AuditTokenRejected ||
+		audit.events[0].Target != pb.Gophermart_GetBalance_FullMethodName {
+		t.Errorf("expected rejected token in audit log, got %+v", audit.events)
+	}
+
+	services.users.EXPECT().GetUserBalance(gomock.Any(), uint(7)).Return(models.BalanceResponse{Current: 500, Withdrawn: 42}, nil)
+	balance, err := client.GetBalance(authorized(t, 7), &pb.GetBalanceRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Current != 500 || balance.Withdrawn != 42 {
+		t.Errorf("unexpected balance %v", balance)
+	}
+}
+
+func TestServer_UploadOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		saveError       error
+		code            codes.Code
+		alreadyUploaded bool
+		reason          string
+	}{
+		{name: "New order", code: codes.OK},
+		{name: "Already uploaded", code: codes.OK, alreadyUploaded: true},
+		{
+			name:      "Uploaded by another user",
+			saveError: apperrors.ErrOrderUploadedByOtherUser,
+			code:      codes.AlreadyExists,
+			reason:    string(apperrors.CodeOrderUploadedByOtherUser),
+		},
+		{
+			name:      "Invalid number",
+			saveError: apperrors.ErrInvalidOrderNumber,
+			code:      codes.InvalidArgument,
+			reason:    string(apperrors.CodeInvalidOrderNumber),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client, services := startServer(t, ctrl, &auditLog{})
+			services.orders.EXPECT().SaveOrder(gomock.Any(), dto.OrderDTO{Number: "12345678903", UserID: 7}).
+				Return(dto.OrderUploadResult{Order: entities.Order{Number: "12345678903"}, AlreadyUploaded: tt.alreadyUploaded}, tt.saveError)
+
+			resp, err := client.UploadOrder(authorized(t, 7), &pb.UploadOrderRequest{Number: "12345678903"})
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+			if err == nil && (resp.Number != "12345678903" || resp.AlreadyUploaded != tt.alreadyUploaded) {
+				t.Errorf("unexpected response %v", resp)
+			}
+			if tt.reason != "" {
+				var info *errdetails.ErrorInfo
+				for _, detail := range status.Convert(err).Details() {
+					if d, ok := detail.(*errdetails.ErrorInfo); ok {
+						info = d
+					}
+				}
+				if info == nil || info.Reason != tt.reason || info.Domain != errorDomain {
+					t.Errorf("expected ErrorInfo with reason %s, got %v", tt.reason, info)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ListOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client, services := startServer(t, ctrl, &auditLog{})
+	services.orders.EXPECT().GetOrdersPage(gomock.Any(), dto.PageQuery{
+		UserID:     7,
+		Limit:      defaultPageLimit,
+		Cursor:     "c",
+		Descending: true,
+		Statuses:   []string{"PROCESSED"},
+	}).Return(models.OrderPage{
+		Items:      []models.AllOrderResponse{{Number: "12345678903", Status: "PROCESSED", Accrual: 500}},
+		NextCursor: "next",
+	}, nil)
+
+	resp, err := client.ListOrders(authorized(t, 7), &pb.ListOrdersRequest{
+		Cursor:     "c",
+		Descending: true,
+		Statuses:   []string{"processed"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Orders) != 1 || resp.Orders[0].Accrual != 500 || resp.NextCursor != "next" {
+		t.Errorf("unexpected response %v", resp)
+	}
+
+	_, err = client.ListOrders(authorized(t, 7), &pb.ListOrdersRequest{Limit: maxPageLimit + 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for too large limit, got %v", err)
+	}
+}
+
+func TestServer_Withdraw(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	audit := &auditLog{}
+	client, services := startServer(t, ctrl, audit)
+	services.withdraws.EXPECT().SaveWithdraw(gomock.Any(), dto.WithdrawDTO{OrderNumber: "2377225624", Sum: 751, UserID: 7}).
+		Return(apperrors.ErrInsufficientFunds)
+
+	_, err := client.Withdraw(authorized(t, 7), &pb.WithdrawRequest{Order: "2377225624", Sum: 751})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition, got %v", err)
+	}
+	if len(audit.events) != 1 {
+		t.Fatalf("expected one audit event, got %+v", audit.events)
+	}
+	event := audit.events[0]
+	if event.Action != entities.AuditWithdraw || event.ActorID != 7 || event.Target != "2377225624" ||
+		event.Status != http.StatusPaymentRequired || event.Outcome != entities.AuditOutcomeFailure {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+}
+
+func TestServer_WithdrawInvalidSum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client, services := startServer(t, ctrl, &auditLog{})
+	// настоящий сервис списаний: сумма должна проверяться им, а не транспортом
+	withdrawService := appservices.NewWithdrawService(servicemocks.NewMockWithdrawRepository(ctrl),
+		servicemocks.NewMockUserRepository(ctrl), &sync.Mutex{}, nil, nil, 0)
+	services.withdraws.EXPECT().SaveWithdraw(gomock.Any(), gomock.Any()).
+		DoAndReturn(withdrawService.SaveWithdraw).Times(2)
+
+	for _, sum := range []float64{-1000, 0} {
+		_, err := client.Withdraw(authorized(t, 7), &pb.WithdrawRequest{Order: "2377225624", Sum: sum})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for sum %v, got %v", sum, err)
+		}
+	}
+}
+
+func TestServer_WatchOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client, services := startServer(t, ctrl, &auditLog{})
+	event := func(id uint, eventType string, payload string) entities.UserEvent {
+		return entities.UserEvent{Entity: entities.Entity{Model: gorm.Model{ID: id}}, UserID: 7, Type: eventType, Payload: payload}
+	}
+	events := make(chan entities.UserEvent, 3)
+	cancelled := make(chan struct{})
+	services.events.EXPECT().Subscribe(gomock.Any(), uint(7), uint(4)).Return(dto.EventSubscription{
+		Missed: []entities.UserEvent{
+			event(5, entities.EventOrderStatusChanged, `{"number":"12345678903","status":"PROCESSING"}`),
+			event(6, entities.EventBalanceChanged, `{"current":500}`),
+		},
+		Events: events,
+		Cancel: func() { close(cancelled) },
+	}, nil)
+	// Событие 5 уже ушло среди пропущенных и не должно повториться
+	events <- event(5, entities.EventOrderStatusChanged, `{"number":"12345678903","status":"PROCESSING"}`)
+	events <- event(7, entities.EventOrderStatusChanged, `{"number":"12345678903","status":"PROCESSED","accrual":500}`)
+
+	ctx, cancel := context.WithCancel(authorized(t, 7))
+	stream, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{LastEventId: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []*pb.OrderStatusEvent
+	for len(received) < 2 {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, event)
+	}
+	if received[0].Id != 5 || received[0].Status != "PROCESSING" ||
+		received[1].Id != 7 || received[1].Status != "PROCESSED" || received[1].Accrual != 500 {
+		t.Errorf("unexpected events %v", received)
+	}
+
+	close(events)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected stream to end when the subscription is closed, got %v", err)
+	}
+	cancel()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("subscription was not cancelled")
+	}
+}
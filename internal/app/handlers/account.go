@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const exportFileName = "gophermart-export.json"
+
+// DeleteAccount удаляет учётную запись текущего пользователя и завершает все его сессии
+func (h *Handler) DeleteAccount(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	if err := h.accountService.DeleteAccount(requestCtx(c), userID); err != nil {
+		RespondError(c, err)
+		return
+	}
+	h.clearSessionCookies(c)
+	c.JSON(http.StatusOK, gin.H{"info": "account deleted"})
+}
+
+// ExportUserData отдаёт все данные пользователя одним JSON-файлом
+func (h *Handler) ExportUserData(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	export, err := h.accountService.ExportData(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFileName))
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_DeleteAccount(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := apperrors.Newf(apperrors.CodeNotFound, "user %d not found", 7)
+	tests := []struct {
+		name        string
+		deleteError error
+		status      int
+		response    any
+	}{
+		{
+			name:     "Success",
+			status:   http.StatusOK,
+			response: gin.H{"info": "account deleted"},
+		},
+		{
+			name:        "Already deleted",
+			deleteError: notFound,
+			status:      http.StatusNotFound,
+			response:    newProblem(notFound),
+		},
+		{
+			name:        "Internal Server Error",
+			deleteError: errors.New("internal Server Error"),
+			status:      http.StatusInternalServerError,
+			response:    newProblem(errors.New("internal Server Error")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			accountService := mocks.NewMockAccountService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().MustGet("userID").Return(uint(7))
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			accountService.EXPECT().DeleteAccount(gomock.Any(), uint(7)).Return(tt.deleteError)
+
+			h := &Handler{
+				accountService: accountService,
+			}
+			h.DeleteAccount(requestContext)
+		})
+	}
+}
+
+func TestHandler_ExportUserData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	export := models.UserDataExport{User: models.UserProfileExport{ID: 7, Login: "alice"}}
+	accountService := mocks.NewMockAccountService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	requestContext.EXPECT().MustGet("userID").Return(uint(7))
+	requestContext.EXPECT().Header("Content-Disposition", `attachment; filename="gophermart-export.json"`)
+	requestContext.EXPECT().JSON(http.StatusOK, export)
+	accountService.EXPECT().ExportData(gomock.Any(), uint(7)).Return(export, nil)
+
+	h := &Handler{
+		accountService: accountService,
+	}
+	h.ExportUserData(requestContext)
+}
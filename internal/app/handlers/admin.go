@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"net/http"
+	"strconv"
+)
+
+// SearchUsers поиск пользователей по подстроке логина (параметр q) с постраничной выдачей
+func (h *Handler) SearchUsers(c RequestContext) {
+	query := dto.UserSearchQuery{
+		Query:  c.Query("q"),
+		Limit:  defaultPageLimit,
+		Cursor: c.Query("cursor"),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			RespondError(c, apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit))
+			return
+		}
+		query.Limit = limit
+	}
+	page, err := h.adminService.SearchUsers(requestCtx(c), query)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(page.Items) == 0 {
+		c.JSON(http.StatusNoContent, gin.H{"error": "users not found"})
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+func (h *Handler) GetUser(c RequestContext) {
+	userID, err := parseUserID(c)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	user, err := h.adminService.GetUser(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) GetUserBalance(c RequestContext) {
+	userID, err := parseUserID(c)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	balance, err := h.adminService.GetUserBalance(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+func parseUserID(c RequestContext) (uint, error) {
+	return parseIDParam(c, "id", "user id")
+}
+
+// parseIDParam читает из пути положительный идентификатор. name имя параметра пути,
+// what название идентификатора для сообщения об ошибке.
+func parseIDParam(c RequestContext, name string, what string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil || id == 0 {
+		return 0, apperrors.InvalidRequest("%s must be a positive integer", what)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_SearchUsers(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := []models.AdminUserResponse{{ID: 1, Login: "alice", Role: "user", CreatedAt: "2023-01-01T00:00:00Z"}}
+	tests := []struct {
+		name            string
+		limit           string
+		searchCallCount int
+		searchReturn    models.AdminUserPage
+		searchError     error
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success with next page",
+			limit:           "1",
+			searchCallCount: 1,
+			searchReturn:    models.AdminUserPage{Items: users, NextCursor: "next"},
+			status:          http.StatusOK,
+			response:        users,
+		},
+		{
+			name:            "No users",
+			searchCallCount: 1,
+			status:          http.StatusNoContent,
+			response:        gin.H{"error": "users not found"},
+		},
+		{
+			name:     "Invalid limit",
+			limit:    "0",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			adminService := mocks.NewMockAdminService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().Query("q").Return("ali")
+			requestContext.EXPECT().Query("cursor").Return("")
+			requestContext.EXPECT().Query("limit").Return(tt.limit)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			if tt.searchReturn.NextCursor != "" {
+				requestContext.EXPECT().Header(nextCursorHeader, tt.searchReturn.NextCursor)
+			}
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			adminService.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, query dto.UserSearchQuery) (models.AdminUserPage, error) {
+				if query.Query != "ali" {
+					t.Errorf("unexpected query: %+v", query)
+				}
+				return tt.searchReturn, tt.searchError
+			}).Times(tt.searchCallCount)
+
+			h := &Handler{
+				adminService: adminService,
+			}
+			h.SearchUsers(requestContext)
+		})
+	}
+}
+
+func TestHandler_GetUserBalance(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := models.AdminBalanceResponse{UserID: 5, Current: 100, Withdrawn: 50, Accrued: 150, WithdrawalsTotal: 50}
+	notFound := apperrors.Newf(apperrors.CodeNotFound, "user %d not found", 5)
+	tests := []struct {
+		name             string
+		id               string
+		balanceCallCount int
+		balanceReturn    models.AdminBalanceResponse
+		balanceError     error
+		status           int
+		response         any
+	}{
+		{
+			name:             "Success",
+			id:               "5",
+			balanceCallCount: 1,
+			balanceReturn:    balance,
+			status:           http.StatusOK,
+			response:         balance,
+		},
+		{
+			name:             "Not found",
+			id:               "5",
+			balanceCallCount: 1,
+			balanceError:     notFound,
+			status:           http.StatusNotFound,
+			response:         newProblem(notFound),
+		},
+		{
+			name:     "Invalid id",
+			id:       "abc",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("user id must be a positive integer")),
+		},
+		{
+			name:             "Internal Server Error",
+			id:               "5",
+			balanceCallCount: 1,
+			balanceError:     errors.New("internal Server Error"),
+			status:           http.StatusInternalServerError,
+			response:         newProblem(errors.New("internal Server Error")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			adminService := mocks.NewMockAdminService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().Param("id").Return(tt.id)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			adminService.EXPECT().GetUserBalance(gomock.Any(), uint(5)).
+				Return(tt.balanceReturn, tt.balanceError).Times(tt.balanceCallCount)
+
+			h := &Handler{
+				adminService: adminService,
+			}
+			h.GetUserBalance(requestContext)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"net/http"
+	"strconv"
+)
+
+// Ключи контекста, через которые обработчик уточняет событие журнала аудита
+const (
+	// AuditActorKey пользователь, если он стал известен только в обработчике, например при входе
+	AuditActorKey = "auditActorID"
+	// AuditTargetKey объект события: логин, номер заказа
+	AuditTargetKey = "auditTarget"
+	// AuditOutcomeKey результат события, если его нельзя вывести из статуса ответа
+	AuditOutcomeKey = "auditOutcome"
+)
+
+// SearchAuditEvents поиск по журналу аудита с фильтрами actor_id, action, target, outcome, ip, from и to
+func (h *Handler) SearchAuditEvents(c RequestContext) {
+	query := dto.AuditEventQuery{
+		Action:  c.Query("action"),
+		Target:  c.Query("target"),
+		Outcome: c.Query("outcome"),
+		IP:      c.Query("ip"),
+		Limit:   defaultPageLimit,
+		Cursor:  c.Query("cursor"),
+	}
+	if raw := c.Query("actor_id"); raw != "" {
+		actorID, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil || actorID == 0 {
+			RespondError(c, apperrors.InvalidRequest("actor_id must be a positive integer"))
+			return
+		}
+		query.ActorID = uint(actorID)
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			RespondError(c, apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit))
+			return
+		}
+		query.Limit = limit
+	}
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		RespondError(c, err)
+		return
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		RespondError(c, err)
+		return
+	}
+	page, err := h.auditService.SearchEvents(requestCtx(c), query)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(page.Items) == 0 {
+		c.JSON(http.StatusNoContent, gin.H{"error": "audit events not found"})
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
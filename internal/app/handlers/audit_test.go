@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_SearchAuditEvents(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []models.AuditEventResponse{{ID: 3, ActorID: 7, Action: "auth.login", Outcome: "failure", IP: "10.0.0.1"}}
+	tests := []struct {
+		name            string
+		actorID         string
+		from            string
+		searchCallCount int
+		searchReturn    models.AuditEventPage
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			actorID:         "7",
+			from:            "2023-01-01T00:00:00Z",
+			searchCallCount: 1,
+			searchReturn:    models.AuditEventPage{Items: events, NextCursor: "next"},
+			status:          http.StatusOK,
+			response:        events,
+		},
+		{
+			name:            "No events",
+			searchCallCount: 1,
+			status:          http.StatusNoContent,
+			response:        gin.H{"error": "audit events not found"},
+		},
+		{
+			name:     "Invalid actor",
+			actorID:  "abc",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("actor_id must be a positive integer")),
+		},
+		{
+			name:     "Invalid from",
+			from:     "yesterday",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("from must be an RFC 3339 timestamp")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auditService := mocks.NewMockAuditService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			params := map[string]string{"action": "auth.login", "outcome": "failure", "actor_id": tt.actorID, "from": tt.from}
+			requestContext.EXPECT().Query(gomock.Any()).DoAndReturn(func(key string) string {
+				return params[key]
+			}).AnyTimes()
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			if tt.searchReturn.NextCursor != "" {
+				requestContext.EXPECT().Header(nextCursorHeader, tt.searchReturn.NextCursor)
+			}
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			auditService.EXPECT().SearchEvents(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, query dto.AuditEventQuery) (models.AuditEventPage, error) {
+				if query.Action != "auth.login" || query.Outcome != "failure" || query.Limit != defaultPageLimit {
+					t.Errorf("unexpected query: %+v", query)
+				}
+				return tt.searchReturn, nil
+			}).Times(tt.searchCallCount)
+
+			h := &Handler{
+				auditService: auditService,
+			}
+			h.SearchAuditEvents(requestContext)
+		})
+	}
+}
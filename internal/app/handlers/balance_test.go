@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"errors"
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
@@ -48,16 +47,17 @@ func TestHandler_GetBalance(t *testing.T) {
 			userServiceReturn: models.BalanceResponse{},
 			userServiceError:  errors.New("error"),
 			status:            http.StatusInternalServerError,
-			response:          gin.H{"error": "Internal Server Error"},
+			response:          newProblem(errors.New("error")),
 		},
 	}
 	userService := mocks.NewMockUserService(ctrl)
 	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
-		userService.EXPECT().GetUserBalance(tt.mustGetReturn).
+		userService.EXPECT().GetUserBalance(gomock.Any(), tt.mustGetReturn).
 			Return(tt.userServiceReturn, tt.userServiceError)
 		requestContext.EXPECT().MustGet(gomock.Any()).
 			Return(tt.mustGetReturn)
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
 		requestContext.EXPECT().JSON(tt.status, tt.response)
 		t.Run(tt.name, func(t *testing.T) {
 			h := &Handler{
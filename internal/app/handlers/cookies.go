@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+)
+
+const (
+	// AccessTokenCookie HttpOnly-cookie с access-токеном для браузерных клиентов
+	AccessTokenCookie = "access_token"
+	// CSRFTokenCookie cookie с CSRF-токеном. Доступна скриптам страницы, которые копируют её
+	// значение в заголовок CSRFHeader.
+	CSRFTokenCookie = "csrf_token"
+	CSRFHeader      = "X-CSRF-Token"
+)
+
+// SessionCookie настройки cookie-сессии. Если Enabled не выставлен, токены выдаются только в теле ответа.
+type SessionCookie struct {
+	Enabled bool
+	Secure  bool
+	Domain  string
+}
+
+// setSessionCookies кладёт access-токен в HttpOnly-cookie и выдаёт к нему новый CSRF-токен
+func (h *Handler) setSessionCookies(c RequestContext, accessToken string, maxAge int) error {
+	if !h.sessionCookie.Enabled {
+		return nil
+	}
+	csrfToken := make([]byte, 32)
+	if _, err := rand.Read(csrfToken); err != nil {
+		return err
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(AccessTokenCookie, accessToken, maxAge, "/", h.sessionCookie.Domain, h.sessionCookie.Secure, true)
+	c.SetCookie(CSRFTokenCookie, base64.RawURLEncoding.EncodeToString(csrfToken), maxAge, "/",
+		h.sessionCookie.Domain, h.sessionCookie.Secure, false)
+	return nil
+}
+
+func (h *Handler) clearSessionCookies(c RequestContext) {
+	if !h.sessionCookie.Enabled {
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(AccessTokenCookie, "", -1, "/", h.sessionCookie.Domain, h.sessionCookie.Secure, true)
+	c.SetCookie(CSRFTokenCookie, "", -1, "/", h.sessionCookie.Domain, h.sessionCookie.Secure, false)
+}
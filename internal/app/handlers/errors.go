@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// problemStatuses сопоставляет код доменной ошибки с HTTP-статусом ответа.
+// Повторная загрузка своего заказа по спецификации отвечает 200.
+var problemStatuses = map[apperrors.Code]int{
+	apperrors.CodeInvalidRequest:           http.StatusBadRequest,
+	apperrors.CodeUnauthorized:             http.StatusUnauthorized,
+	apperrors.CodeInvalidCredentials:       http.StatusUnauthorized,
+	apperrors.CodeInvalidRefreshToken:      http.StatusUnauthorized,
+	apperrors.CodeInvalidResetToken:        http.StatusBadRequest,
+	apperrors.CodeInvalidTwoFactorCode:     http.StatusUnauthorized,
+	apperrors.CodeInvalidLoginChallenge:    http.StatusUnauthorized,
+	apperrors.CodeInvalidAPIKey:            http.StatusUnauthorized,
+	apperrors.CodeInvalidOIDCState:         http.StatusBadRequest,
+	apperrors.CodeOIDCLoginFailed:          http.StatusUnauthorized,
+	apperrors.CodeForbidden:                http.StatusForbidden,
+	apperrors.CodeTwoFactorRequired:        http.StatusForbidden,
+	apperrors.CodeTwoFactorAlreadyEnabled:  http.StatusConflict,
+	apperrors.CodeTooManyAttempts:          http.StatusTooManyRequests,
+	apperrors.CodeRateLimitExceeded:        http.StatusTooManyRequests,
+	apperrors.CodeUserAlreadyExists:        http.StatusConflict,
+	apperrors.CodePartnerAlreadyExists:     http.StatusConflict,
+	apperrors.CodeIdentityAlreadyLinked:    http.StatusConflict,
+	apperrors.CodeOrderUploadedByOtherUser: http.StatusConflict,
+	apperrors.CodeInvalidOrderNumber:       http.StatusUnprocessableEntity,
+	apperrors.CodeInsufficientFunds:        http.StatusPaymentRequired,
+	apperrors.CodeNotFound:                 http.StatusNotFound,
+}
+
+// newProblem строит тело ответа для ошибки. Неизвестные ошибки превращаются во внутреннюю ошибку
+// без подробностей, чтобы не раскрывать клиенту детали реализации.
+func newProblem(err error) models.Problem {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		if status, ok := problemStatuses[appErr.Code]; ok {
+			return models.Problem{
+				Type:       problemType(appErr.Code),
+				Title:      http.StatusText(status),
+				Status:     status,
+				Detail:     appErr.Message,
+				Code:       string(appErr.Code),
+				Violations: appErr.Violations,
+			}
+		}
+	}
+	return models.Problem{
+		Type:   problemType(apperrors.CodeInternal),
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+		Code:   string(apperrors.CodeInternal),
+	}
+}
+
+// ErrorStatus HTTP-статус, которым обработчики ответили бы на ошибку
+func ErrorStatus(err error) int {
+	return newProblem(err).Status
+}
+
+// RespondError единая точка преобразования ошибок сервисов в HTTP-ответ.
+func RespondError(c RequestContext, err error) {
+	problem := newProblem(err)
+	log := logger.FromContext(requestCtx(c))
+	if problem.Status == http.StatusInternalServerError {
+		log.Errorf("internal server error: %v", err)
+	} else {
+		log.Infof("request failed with %s: %v", problem.Code, err)
+	}
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+	c.Header("Content-Type", problemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+func problemType(code apperrors.Code) string {
+	return "/problems/" + strings.ReplaceAll(strings.ToLower(string(code)), "_", "-")
+}
+
+// readJSON читает тело запроса и разбирает его в dst, возвращая доменную ошибку валидации.
+func readJSON(c RequestContext, dst any) error {
+	requestBytes, err := c.GetRawData()
+	if err != nil {
+		return apperrors.InvalidRequest("error while reading request")
+	}
+	return readJSONBytes(requestBytes, dst)
+}
+
+func readJSONBytes(requestBytes []byte, dst any) error {
+	if err := json.Unmarshal(requestBytes, dst); err != nil {
+		return apperrors.InvalidRequest("malformed JSON")
+	}
+	return nil
+}
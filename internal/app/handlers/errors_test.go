@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want models.Problem
+	}{
+		{
+			name: "Domain error",
+			err:  apperrors.ErrInsufficientFunds,
+			want: models.Problem{
+				Type:   "/problems/insufficient-funds",
+				Title:  "Payment Required",
+				Status: http.StatusPaymentRequired,
+				Detail: "not enough funds",
+				Code:   "INSUFFICIENT_FUNDS",
+			},
+		},
+		{
+			name: "Wrapped domain error",
+			err:  fmt.Errorf("save order: %w", apperrors.ErrOrderUploadedByOtherUser),
+			want: models.Problem{
+				Type:   "/problems/order-uploaded-by-other-user",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: "order already uploaded by another user",
+				Code:   "ORDER_UPLOADED_BY_OTHER_USER",
+			},
+		},
+		{
+			name: "Domain error with custom message",
+			err:  apperrors.InvalidRequest("limit must be positive"),
+			want: models.Problem{
+				Type:   "/problems/invalid-request",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "limit must be positive",
+				Code:   "INVALID_REQUEST",
+			},
+		},
+		{
+			name: "Validation error",
+			err:  apperrors.ValidationFailed([]string{"login is too short", "password is too short"}),
+			want: models.Problem{
+				Type:       "/problems/invalid-request",
+				Title:      "Bad Request",
+				Status:     http.StatusBadRequest,
+				Detail:     "request validation failed",
+				Code:       "INVALID_REQUEST",
+				Violations: []string{"login is too short", "password is too short"},
+			},
+		},
+		{
+			name: "Unknown error",
+			err:  errors.New("pq: connection refused"),
+			want: models.Problem{
+				Type:   "/problems/internal-error",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Code:   "INTERNAL_ERROR",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newProblem(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newProblem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorIsMatchesByCode(t *testing.T) {
+	if !errors.Is(apperrors.InvalidRequest("bad cursor"), apperrors.ErrInvalidRequest) {
+		t.Error("errors with the same code must match")
+	}
+	if errors.Is(apperrors.ErrInvalidOrderNumber, apperrors.ErrOrderUploadedByOtherUser) {
+		t.Error("errors with different codes must not match")
+	}
+}
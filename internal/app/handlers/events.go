@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-contrib/sse"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// eventHeartbeatInterval как часто в тихий поток пишется комментарий, чтобы прокси не закрывали
+	// соединение, а обработчик замечал отключившихся клиентов
+	eventHeartbeatInterval = 15 * time.Second
+	// eventRetry через сколько миллисекунд клиенту переподключаться после обрыва
+	eventRetry = 3000
+)
+
+// StreamEvents отдаёт поток Server-Sent Events со сменой статусов заказов и изменениями баланса
+// пользователя. Клиент, передавший заголовок Last-Event-ID, сначала получает пропущенные события.
+func (h *Handler) StreamEvents(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	var lastEventID uint
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil {
+			RespondError(c, apperrors.InvalidRequest("Last-Event-ID must be an event id"))
+			return
+		}
+		lastEventID = uint(id)
+	}
+	subscription, err := h.eventService.Subscribe(requestCtx(c), userID, lastEventID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	defer subscription.Cancel()
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	opened := false
+	c.Stream(func(w io.Writer) bool {
+		if !opened {
+			// Первая запись сразу отправляет клиенту заголовки ответа
+			opened = true
+			if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetry); err != nil {
+				return false
+			}
+			for _, event := range subscription.Missed {
+				if err := writeEvent(w, event); err != nil {
+					return false
+				}
+				lastEventID = event.ID
+			}
+			return true
+		}
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return false
+			}
+			// Событие могло уже уйти среди пропущенных
+			if event.ID <= lastEventID {
+				return true
+			}
+			lastEventID = event.ID
+			return writeEvent(w, event) == nil
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
+
+func writeEvent(w io.Writer, event entities.UserEvent) error {
+	return sse.Encode(w, sse.Event{
+		Id:    strconv.FormatUint(uint64(event.ID), 10),
+		Event: event.Type,
+		Data:  event.Payload,
+	})
+}
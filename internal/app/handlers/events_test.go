@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_StreamEvents(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := func(id uint, eventType string, payload string) entities.UserEvent {
+		return entities.UserEvent{Entity: entities.Entity{Model: gorm.Model{ID: id}}, UserID: 7, Type: eventType, Payload: payload}
+	}
+	tests := []struct {
+		name           string
+		lastEventID    string
+		subscribeCount int
+		subscribeID    uint
+		missed         []entities.UserEvent
+		live           []entities.UserEvent
+		status         int
+		response       any
+		stream         string
+	}{
+		{
+			name:           "Live events",
+			subscribeCount: 1,
+			live:           []entities.UserEvent{event(3, entities.EventBalanceChanged, `{"current":500,"withdrawn":0}`)},
+			stream:         "retry: 3000\n\nid:3\nevent:balance.changed\ndata:{\"current\":500,\"withdrawn\":0}\n\n",
+		},
+		{
+			name:           "Resume after Last-Event-ID",
+			lastEventID:    "4",
+			subscribeCount: 1,
+			subscribeID:    4,
+			missed:         []entities.UserEvent{event(5, entities.EventOrderStatusChanged, `{"number":"1","status":"PROCESSED"}`)},
+			live: []entities.UserEvent{
+				event(5, entities.EventOrderStatusChanged, `{"number":"1","status":"PROCESSED"}`),
+				event(6, entities.EventBalanceChanged, `{"current":1,"withdrawn":0}`),
+			},
+			stream: "retry: 3000\n\n" +
+				"id:5\nevent:order.status_changed\ndata:{\"number\":\"1\",\"status\":\"PROCESSED\"}\n\n" +
+				"id:6\nevent:balance.changed\ndata:{\"current\":1,\"withdrawn\":0}\n\n",
+		},
+		{
+			name:        "Invalid Last-Event-ID",
+			lastEventID: "abc",
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("Last-Event-ID must be an event id")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			events := make(chan entities.UserEvent, len(tt.live))
+			for _, live := range tt.live {
+				events <- live
+			}
+			close(events)
+			cancelled := false
+			eventService := mocks.NewMockEventService(ctrl)
+			eventService.EXPECT().Subscribe(gomock.Any(), uint(7), tt.subscribeID).
+				Return(dto.EventSubscription{Missed: tt.missed, Events: events, Cancel: func() { cancelled = true }}, nil).
+				Times(tt.subscribeCount)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().MustGet("userID").Return(uint(7))
+			requestContext.EXPECT().GetHeader("Last-Event-ID").Return(tt.lastEventID)
+			requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).AnyTimes()
+			var stream bytes.Buffer
+			requestContext.EXPECT().Stream(gomock.Any()).DoAndReturn(func(step func(w io.Writer) bool) bool {
+				for step(&stream) {
+				}
+				return false
+			}).Times(tt.subscribeCount)
+			if tt.response != nil {
+				requestContext.EXPECT().JSON(tt.status, tt.response)
+			}
+
+			h := &Handler{
+				eventService: eventService,
+			}
+			h.StreamEvents(requestContext)
+			if stream.String() != tt.stream {
+				t.Errorf("unexpected stream:\n%q\nwant:\n%q", stream.String(), tt.stream)
+			}
+			if cancelled != (tt.subscribeCount == 1) {
+				t.Errorf("subscription cancelled: %v", cancelled)
+			}
+		})
+	}
+}
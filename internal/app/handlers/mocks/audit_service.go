@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/handlers (interfaces: AuditService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	models "github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+)
+
+// MockAuditService is a mock of AuditService interface.
+type MockAuditService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditServiceMockRecorder
+}
+
+// MockAuditServiceMockRecorder is the mock recorder for MockAuditService.
+type MockAuditServiceMockRecorder struct {
+	mock *MockAuditService
+}
+
+// NewMockAuditService creates a new mock instance.
+func NewMockAuditService(ctrl *gomock.Controller) *MockAuditService {
+	mock := &MockAuditService{ctrl: ctrl}
+	mock.recorder = &MockAuditServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditService) EXPECT() *MockAuditServiceMockRecorder {
+	return m.recorder
+}
+
+// SearchEvents mocks base method.
+func (m *MockAuditService) SearchEvents(arg0 context.Context, arg1 dto.AuditEventQuery) (models.AuditEventPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchEvents", arg0, arg1)
+	ret0, _ := ret[0].(models.AuditEventPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchEvents indicates an expected call of SearchEvents.
+func (mr *MockAuditServiceMockRecorder) SearchEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchEvents", reflect.TypeOf((*MockAuditService)(nil).SearchEvents), arg0, arg1)
+}
@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/handlers (interfaces: OIDCService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	models "github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+)
+
+// MockOIDCService is a mock of OIDCService interface.
+type MockOIDCService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCServiceMockRecorder
+}
+
+// MockOIDCServiceMockRecorder is the mock recorder for MockOIDCService.
+type MockOIDCServiceMockRecorder struct {
+	mock *MockOIDCService
+}
+
+// NewMockOIDCService creates a new mock instance.
+func NewMockOIDCService(ctrl *gomock.Controller) *MockOIDCService {
+	mock := &MockOIDCService{ctrl: ctrl}
+	mock.recorder = &MockOIDCServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCService) EXPECT() *MockOIDCServiceMockRecorder {
+	return m.recorder
+}
+
+// CompleteAuthorization mocks base method.
+func (m *MockOIDCService) CompleteAuthorization(arg0 context.Context, arg1, arg2 string) (dto.OIDCLoginResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteAuthorization", arg0, arg1, arg2)
+	ret0, _ := ret[0].(dto.OIDCLoginResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteAuthorization indicates an expected call of CompleteAuthorization.
+func (mr *MockOIDCServiceMockRecorder) CompleteAuthorization(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteAuthorization", reflect.TypeOf((*MockOIDCService)(nil).CompleteAuthorization), arg0, arg1, arg2)
+}
+
+// StartAuthorization mocks base method.
+func (m *MockOIDCService) StartAuthorization(arg0 context.Context, arg1 uint) (models.OIDCAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(models.OIDCAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartAuthorization indicates an expected call of StartAuthorization.
+func (mr *MockOIDCServiceMockRecorder) StartAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartAuthorization", reflect.TypeOf((*MockOIDCService)(nil).StartAuthorization), arg0, arg1)
+}
@@ -5,11 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
-	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	models "github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 )
 
@@ -36,32 +36,62 @@ func (m *MockOrderService) EXPECT() *MockOrderServiceMockRecorder {
 	return m.recorder
 }
 
-// GetAllOrders mocks base method.
-func (m *MockOrderService) GetAllOrders(arg0 uint) ([]models.AllOrderResponse, error) {
+// GetOrder mocks base method.
+func (m *MockOrderService) GetOrder(arg0 context.Context, arg1 uint, arg2 string) (models.OrderResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllOrders", arg0)
-	ret0, _ := ret[0].([]models.AllOrderResponse)
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(models.OrderResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllOrders indicates an expected call of GetAllOrders.
-func (mr *MockOrderServiceMockRecorder) GetAllOrders(arg0 interface{}) *gomock.Call {
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockOrderServiceMockRecorder) GetOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockOrderService)(nil).GetAllOrders), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrderService)(nil).GetOrder), arg0, arg1, arg2)
+}
+
+// GetOrdersPage mocks base method.
+func (m *MockOrderService) GetOrdersPage(arg0 context.Context, arg1 dto.PageQuery) (models.OrderPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersPage", arg0, arg1)
+	ret0, _ := ret[0].(models.OrderPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersPage indicates an expected call of GetOrdersPage.
+func (mr *MockOrderServiceMockRecorder) GetOrdersPage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersPage", reflect.TypeOf((*MockOrderService)(nil).GetOrdersPage), arg0, arg1)
 }
 
 // SaveOrder mocks base method.
-func (m *MockOrderService) SaveOrder(arg0 dto.OrderDTO) (entities.Order, error) {
+func (m *MockOrderService) SaveOrder(arg0 context.Context, arg1 dto.OrderDTO) (dto.OrderUploadResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveOrder", arg0)
-	ret0, _ := ret[0].(entities.Order)
+	ret := m.ctrl.Call(m, "SaveOrder", arg0, arg1)
+	ret0, _ := ret[0].(dto.OrderUploadResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveOrder indicates an expected call of SaveOrder.
-func (mr *MockOrderServiceMockRecorder) SaveOrder(arg0 interface{}) *gomock.Call {
+func (mr *MockOrderServiceMockRecorder) SaveOrder(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockOrderService)(nil).SaveOrder), arg0, arg1)
+}
+
+// SaveOrders mocks base method.
+func (m *MockOrderService) SaveOrders(arg0 context.Context, arg1 uint, arg2 []string) ([]models.BatchOrderResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.BatchOrderResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveOrders indicates an expected call of SaveOrders.
+func (mr *MockOrderServiceMockRecorder) SaveOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockOrderService)(nil).SaveOrder), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrders", reflect.TypeOf((*MockOrderService)(nil).SaveOrders), arg0, arg1, arg2)
 }
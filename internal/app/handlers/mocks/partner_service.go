@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/handlers (interfaces: PartnerService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	models "github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+)
+
+// MockPartnerService is a mock of PartnerService interface.
+type MockPartnerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPartnerServiceMockRecorder
+}
+
+// MockPartnerServiceMockRecorder is the mock recorder for MockPartnerService.
+type MockPartnerServiceMockRecorder struct {
+	mock *MockPartnerService
+}
+
+// NewMockPartnerService creates a new mock instance.
+func NewMockPartnerService(ctrl *gomock.Controller) *MockPartnerService {
+	mock := &MockPartnerService{ctrl: ctrl}
+	mock.recorder = &MockPartnerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPartnerService) EXPECT() *MockPartnerServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockPartnerService) CreateAPIKey(arg0 context.Context, arg1 dto.APIKeyDTO) (models.APIKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(models.APIKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockPartnerServiceMockRecorder) CreateAPIKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockPartnerService)(nil).CreateAPIKey), arg0, arg1)
+}
+
+// CreatePartner mocks base method.
+func (m *MockPartnerService) CreatePartner(arg0 context.Context, arg1 string) (models.PartnerResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePartner", arg0, arg1)
+	ret0, _ := ret[0].(models.PartnerResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePartner indicates an expected call of CreatePartner.
+func (mr *MockPartnerServiceMockRecorder) CreatePartner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePartner", reflect.TypeOf((*MockPartnerService)(nil).CreatePartner), arg0, arg1)
+}
+
+// LinkUser mocks base method.
+func (m *MockPartnerService) LinkUser(arg0 context.Context, arg1 uint, arg2 string, arg3 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkUser indicates an expected call of LinkUser.
+func (mr *MockPartnerServiceMockRecorder) LinkUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkUser", reflect.TypeOf((*MockPartnerService)(nil).LinkUser), arg0, arg1, arg2, arg3)
+}
+
+// ResolveUser mocks base method.
+func (m *MockPartnerService) ResolveUser(arg0 context.Context, arg1 uint, arg2 string) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveUser indicates an expected call of ResolveUser.
+func (mr *MockPartnerServiceMockRecorder) ResolveUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveUser", reflect.TypeOf((*MockPartnerService)(nil).ResolveUser), arg0, arg1, arg2)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockPartnerService) RevokeAPIKey(arg0 context.Context, arg1, arg2 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockPartnerServiceMockRecorder) RevokeAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockPartnerService)(nil).RevokeAPIKey), arg0, arg1, arg2)
+}
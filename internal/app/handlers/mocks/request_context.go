@@ -5,6 +5,8 @@
 package mocks
 
 import (
+	io "io"
+	http "net/http"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -33,6 +35,48 @@ func (m *MockRequestContext) EXPECT() *MockRequestContextMockRecorder {
 	return m.recorder
 }
 
+// ClientIP mocks base method.
+func (m *MockRequestContext) ClientIP() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientIP")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ClientIP indicates an expected call of ClientIP.
+func (mr *MockRequestContextMockRecorder) ClientIP() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientIP", reflect.TypeOf((*MockRequestContext)(nil).ClientIP))
+}
+
+// ContentType mocks base method.
+func (m *MockRequestContext) ContentType() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContentType")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ContentType indicates an expected call of ContentType.
+func (mr *MockRequestContextMockRecorder) ContentType() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContentType", reflect.TypeOf((*MockRequestContext)(nil).ContentType))
+}
+
+// GetHeader mocks base method.
+func (m *MockRequestContext) GetHeader(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeader", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetHeader indicates an expected call of GetHeader.
+func (mr *MockRequestContextMockRecorder) GetHeader(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeader", reflect.TypeOf((*MockRequestContext)(nil).GetHeader), arg0)
+}
+
 // GetRawData mocks base method.
 func (m *MockRequestContext) GetRawData() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -85,3 +129,81 @@ func (mr *MockRequestContextMockRecorder) MustGet(arg0 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MustGet", reflect.TypeOf((*MockRequestContext)(nil).MustGet), arg0)
 }
+
+// Param mocks base method.
+func (m *MockRequestContext) Param(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Param", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Param indicates an expected call of Param.
+func (mr *MockRequestContextMockRecorder) Param(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Param", reflect.TypeOf((*MockRequestContext)(nil).Param), arg0)
+}
+
+// Query mocks base method.
+func (m *MockRequestContext) Query(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockRequestContextMockRecorder) Query(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockRequestContext)(nil).Query), arg0)
+}
+
+// Set mocks base method.
+func (m *MockRequestContext) Set(arg0 string, arg1 interface{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Set", arg0, arg1)
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockRequestContextMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRequestContext)(nil).Set), arg0, arg1)
+}
+
+// SetCookie mocks base method.
+func (m *MockRequestContext) SetCookie(arg0, arg1 string, arg2 int, arg3, arg4 string, arg5, arg6 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCookie", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// SetCookie indicates an expected call of SetCookie.
+func (mr *MockRequestContextMockRecorder) SetCookie(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCookie", reflect.TypeOf((*MockRequestContext)(nil).SetCookie), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// SetSameSite mocks base method.
+func (m *MockRequestContext) SetSameSite(arg0 http.SameSite) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSameSite", arg0)
+}
+
+// SetSameSite indicates an expected call of SetSameSite.
+func (mr *MockRequestContextMockRecorder) SetSameSite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSameSite", reflect.TypeOf((*MockRequestContext)(nil).SetSameSite), arg0)
+}
+
+// Stream mocks base method.
+func (m *MockRequestContext) Stream(arg0 func(io.Writer) bool) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockRequestContextMockRecorder) Stream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockRequestContext)(nil).Stream), arg0)
+}
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,47 +37,75 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(arg0 context.Context, arg1 dto.PasswordChangeDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), arg0, arg1)
+}
+
 // GetUserBalance mocks base method.
-func (m *MockUserService) GetUserBalance(arg0 uint) (models.BalanceResponse, error) {
+func (m *MockUserService) GetUserBalance(arg0 context.Context, arg1 uint) (models.BalanceResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserBalance", arg0)
+	ret := m.ctrl.Call(m, "GetUserBalance", arg0, arg1)
 	ret0, _ := ret[0].(models.BalanceResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserBalance indicates an expected call of GetUserBalance.
-func (mr *MockUserServiceMockRecorder) GetUserBalance(arg0 interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) GetUserBalance(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBalance", reflect.TypeOf((*MockUserService)(nil).GetUserBalance), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBalance", reflect.TypeOf((*MockUserService)(nil).GetUserBalance), arg0, arg1)
 }
 
 // GetUserByUserName mocks base method.
-func (m *MockUserService) GetUserByUserName(arg0 dto.UserDTO) (entities.User, error) {
+func (m *MockUserService) GetUserByUserName(arg0 context.Context, arg1 dto.UserDTO) (entities.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByUserName", arg0)
+	ret := m.ctrl.Call(m, "GetUserByUserName", arg0, arg1)
 	ret0, _ := ret[0].(entities.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserByUserName indicates an expected call of GetUserByUserName.
-func (mr *MockUserServiceMockRecorder) GetUserByUserName(arg0 interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) GetUserByUserName(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserName", reflect.TypeOf((*MockUserService)(nil).GetUserByUserName), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserName", reflect.TypeOf((*MockUserService)(nil).GetUserByUserName), arg0, arg1)
 }
 
 // SaveUser mocks base method.
-func (m *MockUserService) SaveUser(arg0 dto.UserDTO) (entities.User, error) {
+func (m *MockUserService) SaveUser(arg0 context.Context, arg1 dto.UserDTO) (entities.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveUser", arg0)
+	ret := m.ctrl.Call(m, "SaveUser", arg0, arg1)
 	ret0, _ := ret[0].(entities.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveUser indicates an expected call of SaveUser.
-func (mr *MockUserServiceMockRecorder) SaveUser(arg0 interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) SaveUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUserService)(nil).SaveUser), arg0, arg1)
+}
+
+// UpdateEmail mocks base method.
+func (m *MockUserService) UpdateEmail(arg0 context.Context, arg1 uint, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmail", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmail indicates an expected call of UpdateEmail.
+func (mr *MockUserServiceMockRecorder) UpdateEmail(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUserService)(nil).SaveUser), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmail", reflect.TypeOf((*MockUserService)(nil).UpdateEmail), arg0, arg1, arg2)
 }
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,31 +36,31 @@ func (m *MockWithdrawService) EXPECT() *MockWithdrawServiceMockRecorder {
 	return m.recorder
 }
 
-// GetAllWithdrawals mocks base method.
-func (m *MockWithdrawService) GetAllWithdrawals(arg0 uint) ([]models.WithdrawResponse, error) {
+// GetWithdrawalsPage mocks base method.
+func (m *MockWithdrawService) GetWithdrawalsPage(arg0 context.Context, arg1 dto.PageQuery) (models.WithdrawPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllWithdrawals", arg0)
-	ret0, _ := ret[0].([]models.WithdrawResponse)
+	ret := m.ctrl.Call(m, "GetWithdrawalsPage", arg0, arg1)
+	ret0, _ := ret[0].(models.WithdrawPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllWithdrawals indicates an expected call of GetAllWithdrawals.
-func (mr *MockWithdrawServiceMockRecorder) GetAllWithdrawals(arg0 interface{}) *gomock.Call {
+// GetWithdrawalsPage indicates an expected call of GetWithdrawalsPage.
+func (mr *MockWithdrawServiceMockRecorder) GetWithdrawalsPage(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllWithdrawals", reflect.TypeOf((*MockWithdrawService)(nil).GetAllWithdrawals), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawalsPage", reflect.TypeOf((*MockWithdrawService)(nil).GetWithdrawalsPage), arg0, arg1)
 }
 
 // SaveWithdraw mocks base method.
-func (m *MockWithdrawService) SaveWithdraw(arg0 dto.WithdrawDTO) error {
+func (m *MockWithdrawService) SaveWithdraw(arg0 context.Context, arg1 dto.WithdrawDTO) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveWithdraw", arg0)
+	ret := m.ctrl.Call(m, "SaveWithdraw", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveWithdraw indicates an expected call of SaveWithdraw.
-func (mr *MockWithdrawServiceMockRecorder) SaveWithdraw(arg0 interface{}) *gomock.Call {
+func (mr *MockWithdrawServiceMockRecorder) SaveWithdraw(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWithdraw", reflect.TypeOf((*MockWithdrawService)(nil).SaveWithdraw), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWithdraw", reflect.TypeOf((*MockWithdrawService)(nil).SaveWithdraw), arg0, arg1)
 }
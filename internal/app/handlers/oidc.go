@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"net/http"
+)
+
+// StartOIDCLogin начинает вход через внешний провайдер OpenID Connect
+func (h *Handler) StartOIDCLogin(c RequestContext) {
+	response, err := h.oidcService.StartAuthorization(requestCtx(c), 0)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// LinkOIDCIdentity начинает привязку внешней учётной записи к текущему пользователю
+func (h *Handler) LinkOIDCIdentity(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	response, err := h.oidcService.StartAuthorization(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteOIDCLogin принимает пользователя, вернувшегося от провайдера. После входа выдаёт
+// токены так же, как вход по паролю, после привязки только подтверждает её.
+func (h *Handler) CompleteOIDCLogin(c RequestContext) {
+	if providerError := c.Query("error"); providerError != "" {
+		RespondError(c, apperrors.Newf(apperrors.CodeOIDCLoginFailed, "identity provider returned %s", providerError))
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		RespondError(c, apperrors.InvalidRequest("code and state are required"))
+		return
+	}
+	result, err := h.oidcService.CompleteAuthorization(requestCtx(c), code, state)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditActorKey, result.UserID)
+	if result.Linked {
+		c.JSON(http.StatusOK, gin.H{"info": "external identity linked"})
+		return
+	}
+	h.signIn(c, result.UserID, result.TOTPEnabled)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_StartOIDCLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := models.OIDCAuthorizationResponse{AuthorizationURL: "https://idp.example.com/authorize?state=s"}
+	oidcService := mocks.NewMockOIDCService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	oidcService.EXPECT().StartAuthorization(gomock.Any(), uint(0)).Return(response, nil)
+	requestContext.EXPECT().JSON(http.StatusOK, response)
+
+	h := &Handler{
+		oidcService: oidcService,
+	}
+	h.StartOIDCLogin(requestContext)
+}
+
+func TestHandler_LinkOIDCIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := models.OIDCAuthorizationResponse{AuthorizationURL: "https://idp.example.com/authorize?state=s"}
+	oidcService := mocks.NewMockOIDCService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	requestContext.EXPECT().MustGet("userID").Return(uint(7))
+	oidcService.EXPECT().StartAuthorization(gomock.Any(), uint(7)).Return(response, nil)
+	requestContext.EXPECT().JSON(http.StatusOK, response)
+
+	h := &Handler{
+		oidcService: oidcService,
+	}
+	h.LinkOIDCIdentity(requestContext)
+}
+
+func TestHandler_CompleteOIDCLogin(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+	challenge := models.LoginChallengeResponse{TwoFactorRequired: true, Challenge: "challenge", ExpiresIn: 300}
+	tests := []struct {
+		name              string
+		providerError     string
+		code              string
+		completeCallCount int
+		completeResult    dto.OIDCLoginResult
+		completeError     error
+		actorCount        int
+		issueCallCount    int
+		challengeCount    int
+		status            int
+		response          any
+	}{
+		{
+			name:              "Login",
+			code:              "code",
+			completeCallCount: 1,
+			completeResult:    dto.OIDCLoginResult{UserID: 7},
+			actorCount:        1,
+			issueCallCount:    1,
+			status:            http.StatusOK,
+			response:          tokens,
+		},
+		{
+			name:              "Login with two-factor",
+			code:              "code",
+			completeCallCount: 1,
+			completeResult:    dto.OIDCLoginResult{UserID: 7, TOTPEnabled: true},
+			actorCount:        1,
+			challengeCount:    1,
+			status:            http.StatusOK,
+			response:          challenge,
+		},
+		{
+			name:              "Link",
+			code:              "code",
+			completeCallCount: 1,
+			completeResult:    dto.OIDCLoginResult{UserID: 7, Linked: true},
+			actorCount:        1,
+			status:            http.StatusOK,
+			response:          gin.H{"info": "external identity linked"},
+		},
+		{
+			name:              "Invalid state",
+			code:              "code",
+			completeCallCount: 1,
+			completeError:     apperrors.ErrInvalidOIDCState,
+			status:            http.StatusBadRequest,
+			response:          newProblem(apperrors.ErrInvalidOIDCState),
+		},
+		{
+			name:          "Provider error",
+			providerError: "access_denied",
+			status:        http.StatusUnauthorized,
+			response:      newProblem(apperrors.New(apperrors.CodeOIDCLoginFailed, "identity provider returned access_denied")),
+		},
+		{
+			name:     "Missing code",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("code and state are required")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oidcService := mocks.NewMockOIDCService(ctrl)
+			tokenService := mocks.NewMockTokenService(ctrl)
+			twoFactorService := mocks.NewMockTwoFactorService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().Query("error").Return(tt.providerError)
+			requestContext.EXPECT().Query("code").Return(tt.code).AnyTimes()
+			requestContext.EXPECT().Query("state").Return("state").AnyTimes()
+			requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).AnyTimes()
+			requestContext.EXPECT().Set(AuditActorKey, uint(7)).Times(tt.actorCount)
+			requestContext.EXPECT().Set(AuditOutcomeKey, entities.AuditOutcomeChallenge).Times(tt.challengeCount)
+			requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.issueCallCount)
+			requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.issueCallCount)
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			oidcService.EXPECT().CompleteAuthorization(gomock.Any(), "code", "state").
+				Return(tt.completeResult, tt.completeError).
+				Times(tt.completeCallCount)
+			tokenService.EXPECT().IssueTokens(gomock.Any(), uint(7), dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).
+				Return(tokens, nil).
+				Times(tt.issueCallCount)
+			twoFactorService.EXPECT().CreateChallenge(gomock.Any(), uint(7)).Return(challenge, nil).Times(tt.challengeCount)
+
+			h := &Handler{
+				oidcService:      oidcService,
+				tokenService:     tokenService,
+				twoFactorService: twoFactorService,
+			}
+			h.CompleteOIDCLogin(requestContext)
+		})
+	}
+}
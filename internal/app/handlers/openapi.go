@@ -0,0 +1,8 @@
+package handlers
+
+import "net/http"
+
+// GetOpenAPI отдаёт описание API в формате OpenAPI 3
+func (h *Handler) GetOpenAPI(c RequestContext) {
+	c.JSON(http.StatusOK, h.apiDocument)
+}
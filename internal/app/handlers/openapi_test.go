@@ -0,0 +1,795 @@
+package handlers
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/keyring"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/openapi"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type serviceMocks struct {
+	user      *mocks.MockUserService
+	order     *mocks.MockOrderService
+	withdraw  *mocks.MockWithdrawService
+	token     *mocks.MockTokenService
+	admin     *mocks.MockAdminService
+	reset     *mocks.MockPasswordResetService
+	twoFactor *mocks.MockTwoFactorService
+	partner   *mocks.MockPartnerService
+	account   *mocks.MockAccountService
+	audit     *mocks.MockAuditService
+	oidc      *mocks.MockOIDCService
+	events    *mocks.MockEventService
+	webhooks  *mocks.MockWebhookService
+}
+
+// TestHandler_ResponsesMatchOpenAPI проверяет, что ответы обработчиков соответствуют документу OpenAPI
+func TestHandler_ResponsesMatchOpenAPI(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	document, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiRouter, err := gorillamux.NewRouter(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := map[string]any{
+		"userID":         uint(7),
+		"tokenID":        "jti",
+		"sessionID":      "session",
+		"tokenExpiresAt": time.Now().Add(time.Minute),
+	}
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+	challenge := models.LoginChallengeResponse{TwoFactorRequired: true, Challenge: "challenge", ExpiresIn: 300}
+	order := models.AllOrderResponse{Number: "12345678903", Status: "PROCESSED", Accrual: 500, UploadedAt: "2023-01-02T15:04:05Z"}
+	withdrawal := models.WithdrawResponse{Order: "2377225624", Sum: 100, ProcessedAt: "2023-01-02T15:04:05Z"}
+	adminUser := models.AdminUserResponse{ID: 7, Login: "user", Role: entities.RoleUser, CreatedAt: "2023-01-02T15:04:05Z"}
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		header      map[string]string
+		keys        map[string]any
+		params      gin.Params
+		prepare     func(m serviceMocks)
+		handle      func(h *Handler, c *gin.Context)
+		status      int
+	}{
+		{
+			name:   "JWKS",
+			method: http.MethodGet,
+			target: "/.well-known/jwks.json",
+			handle: func(h *Handler, c *gin.Context) { h.GetJWKS(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "OpenAPI document",
+			method: http.MethodGet,
+			target: "/openapi.json",
+			handle: func(h *Handler, c *gin.Context) { h.GetOpenAPI(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Register",
+			method:      http.MethodPost,
+			target:      "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"user","password":"password"}`,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().SaveUser(gomock.Any(), gomock.Any()).Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}}, nil)
+				m.token.EXPECT().IssueTokens(gomock.Any(), uint(7), gomock.Any()).Return(tokens, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RegisterUser(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Register existing user",
+			method:      http.MethodPost,
+			target:      "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":"user","password":"password"}`,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().SaveUser(gomock.Any(), gomock.Any()).Return(entities.User{}, apperrors.ErrUserAlreadyExists)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RegisterUser(c) },
+			status: http.StatusConflict,
+		},
+		{
+			name:        "Login with two-factor",
+			method:      http.MethodPost,
+			target:      "/api/user/login",
+			contentType: "application/json",
+			body:        `{"login":"user","password":"password"}`,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().GetUserByUserName(gomock.Any(), gomock.Any()).
+					Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true}, nil)
+				m.twoFactor.EXPECT().CreateChallenge(gomock.Any(), uint(7)).Return(challenge, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.LoginUser(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Login throttled",
+			method:      http.MethodPost,
+			target:      "/api/user/login",
+			contentType: "application/json",
+			body:        `{"login":"user","password":"password"}`,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().GetUserByUserName(gomock.Any(), gomock.Any()).Return(entities.User{}, apperrors.TooManyAttempts(time.Minute))
+			},
+			handle: func(h *Handler, c *gin.Context) { h.LoginUser(c) },
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:        "Complete login",
+			method:      http.MethodPost,
+			target:      "/api/user/login/2fa",
+			contentType: "application/json",
+			body:        `{"challenge":"challenge","code":"123456"}`,
+			prepare: func(m serviceMocks) {
+				m.twoFactor.EXPECT().CompleteChallenge(gomock.Any(), "challenge", "123456").Return(uint(7), nil)
+				m.token.EXPECT().IssueTokens(gomock.Any(), uint(7), gomock.Any()).Return(tokens, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.CompleteLogin(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Refresh token",
+			method:      http.MethodPost,
+			target:      "/api/user/token/refresh",
+			contentType: "application/json",
+			body:        `{"refresh_token":"refresh"}`,
+			prepare: func(m serviceMocks) {
+				m.token.EXPECT().Refresh(gomock.Any(), "refresh", gomock.Any()).Return(models.TokenResponse{}, apperrors.ErrInvalidRefreshToken)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RefreshToken(c) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:        "Request password reset",
+			method:      http.MethodPost,
+			target:      "/api/user/password/reset",
+			contentType: "application/json",
+			body:        `{"login":"user"}`,
+			prepare: func(m serviceMocks) {
+				m.reset.EXPECT().RequestReset(gomock.Any(), "user", gomock.Any()).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RequestPasswordReset(c) },
+			status: http.StatusAccepted,
+		},
+		{
+			name:        "Confirm password reset",
+			method:      http.MethodPost,
+			target:      "/api/user/password/reset/confirm",
+			contentType: "application/json",
+			body:        `{"token":"token","new_password":"password"}`,
+			prepare: func(m serviceMocks) {
+				m.reset.EXPECT().ConfirmReset(gomock.Any(), "token", "password").Return(uint(7), nil)
+				m.token.EXPECT().RevokeAllForUser(gomock.Any(), uint(7)).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ConfirmPasswordReset(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Start OIDC login",
+			method: http.MethodGet,
+			target: "/api/user/oidc/login",
+			prepare: func(m serviceMocks) {
+				m.oidc.EXPECT().StartAuthorization(gomock.Any(), uint(0)).
+					Return(models.OIDCAuthorizationResponse{AuthorizationURL: "https://idp.example.com/authorize"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.StartOIDCLogin(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Complete OIDC link",
+			method: http.MethodGet,
+			target: "/api/user/oidc/callback?code=code&state=state",
+			prepare: func(m serviceMocks) {
+				m.oidc.EXPECT().CompleteAuthorization(gomock.Any(), "code", "state").Return(dto.OIDCLoginResult{UserID: 7, Linked: true}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.CompleteOIDCLogin(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "OIDC provider error",
+			method: http.MethodGet,
+			target: "/api/user/oidc/callback?error=access_denied",
+			handle: func(h *Handler, c *gin.Context) { h.CompleteOIDCLogin(c) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Link OIDC identity",
+			method: http.MethodPost,
+			target: "/api/user/oidc/link",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.oidc.EXPECT().StartAuthorization(gomock.Any(), uint(7)).
+					Return(models.OIDCAuthorizationResponse{AuthorizationURL: "https://idp.example.com/authorize"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.LinkOIDCIdentity(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Logout",
+			method: http.MethodPost,
+			target: "/api/user/logout",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.token.EXPECT().Logout(gomock.Any(), uint(7), "jti", gomock.Any(), "session", "").Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.Logout(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "List sessions",
+			method: http.MethodGet,
+			target: "/api/user/sessions",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.token.EXPECT().ListSessions(gomock.Any(), uint(7), "session").Return([]models.SessionResponse{{
+					ID:         1,
+					Device:     "test-agent",
+					IP:         "10.0.0.1",
+					CreatedAt:  "2023-01-02T15:04:05Z",
+					LastSeenAt: "2023-01-02T15:04:05Z",
+					Current:    true,
+				}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ListSessions(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Revoke missing session",
+			method: http.MethodDelete,
+			target: "/api/user/sessions/3",
+			keys:   user,
+			params: gin.Params{{Key: "id", Value: "3"}},
+			prepare: func(m serviceMocks) {
+				m.token.EXPECT().RevokeSession(gomock.Any(), uint(7), uint(3)).Return(apperrors.ErrNotFound)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RevokeSession(c) },
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Delete account",
+			method: http.MethodDelete,
+			target: "/api/user",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.account.EXPECT().DeleteAccount(gomock.Any(), uint(7)).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.DeleteAccount(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Export user data",
+			method: http.MethodGet,
+			target: "/api/user/export",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.account.EXPECT().ExportData(gomock.Any(), uint(7)).Return(models.UserDataExport{
+					ExportedAt:  "2023-01-02T15:04:05Z",
+					User:        models.UserProfileExport{ID: 7, Login: "user", Role: entities.RoleUser, CreatedAt: "2023-01-02T15:04:05Z"},
+					Orders:      []models.AllOrderResponse{order},
+					Withdrawals: []models.WithdrawResponse{withdrawal},
+					Ledger: []models.LedgerEntry{
+						{Type: models.LedgerEntryAccrual, Order: order.Number, Amount: 500, Balance: 500, At: order.UploadedAt},
+					},
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ExportUserData(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Change password",
+			method:      http.MethodPost,
+			target:      "/api/user/password",
+			contentType: "application/json",
+			body:        `{"old_password":"old","new_password":"new"}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Return(nil)
+				m.token.EXPECT().RevokeAllForUser(gomock.Any(), uint(7)).Return(nil)
+				m.token.EXPECT().IssueTokens(gomock.Any(), uint(7), gomock.Any()).Return(tokens, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ChangePassword(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Update email",
+			method:      http.MethodPut,
+			target:      "/api/user/email",
+			contentType: "application/json",
+			body:        `{"email":"user@example.com"}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().UpdateEmail(gomock.Any(), uint(7), "user@example.com").Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.UpdateEmail(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Enroll two-factor",
+			method: http.MethodPost,
+			target: "/api/user/2fa/enroll",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.twoFactor.EXPECT().Enroll(gomock.Any(), uint(7)).
+					Return(models.TOTPEnrollmentResponse{Secret: "SECRET", URI: "otpauth://totp/gophermart:user?secret=SECRET"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.EnrollTwoFactor(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Confirm two-factor",
+			method:      http.MethodPost,
+			target:      "/api/user/2fa/confirm",
+			contentType: "application/json",
+			body:        `{"code":"123456"}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.twoFactor.EXPECT().Confirm(gomock.Any(), uint(7), "123456").Return([]string{"recovery"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ConfirmTwoFactor(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Disable two-factor",
+			method:      http.MethodPost,
+			target:      "/api/user/2fa/disable",
+			contentType: "application/json",
+			body:        `{"code":"123456"}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.twoFactor.EXPECT().Disable(gomock.Any(), uint(7), "123456").Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.DisableTwoFactor(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Upload order",
+			method:      http.MethodPost,
+			target:      "/api/user/orders",
+			contentType: "text/plain",
+			body:        "12345678903",
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dto.OrderUploadResult{Order: entities.Order{Number: "12345678903"}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ProcessUserOrder(c) },
+			status: http.StatusAccepted,
+		},
+		{
+			name:        "Upload own order again",
+			method:      http.MethodPost,
+			target:      "/api/user/orders",
+			contentType: "text/plain",
+			body:        "12345678903",
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).
+					Return(dto.OrderUploadResult{Order: entities.Order{Number: "12345678903"}, AlreadyUploaded: true}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ProcessUserOrder(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Upload invalid order",
+			method:      http.MethodPost,
+			target:      "/api/user/orders",
+			contentType: "text/plain",
+			body:        "12345678900",
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dto.OrderUploadResult{}, apperrors.ErrInvalidOrderNumber)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ProcessUserOrder(c) },
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "Upload order batch",
+			method:      http.MethodPost,
+			target:      "/api/user/orders/batch",
+			contentType: "application/json",
+			body:        `["12345678903", 2377225624]`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().SaveOrders(gomock.Any(), uint(7), []string{"12345678903", "2377225624"}).Return([]models.BatchOrderResult{
+					{Number: "12345678903", Result: models.BatchResultAccepted},
+					{Number: "2377225624", Result: models.BatchResultDuplicateOwn},
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ProcessUserOrdersBatch(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "List orders",
+			method: http.MethodGet,
+			target: "/api/user/orders?limit=1",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().GetOrdersPage(gomock.Any(), gomock.Any()).
+					Return(models.OrderPage{Items: []models.AllOrderResponse{order}, NextCursor: "cursor"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetAllOrders(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "List no orders",
+			method: http.MethodGet,
+			target: "/api/user/orders",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().GetOrdersPage(gomock.Any(), gomock.Any()).Return(models.OrderPage{}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetAllOrders(c) },
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Get order",
+			method: http.MethodGet,
+			target: "/api/user/orders/12345678903",
+			keys:   user,
+			params: gin.Params{{Key: "number", Value: "12345678903"}},
+			prepare: func(m serviceMocks) {
+				m.order.EXPECT().GetOrder(gomock.Any(), uint(7), "12345678903").Return(models.OrderResponse{
+					Number:     "12345678903",
+					Status:     "PROCESSING",
+					UploadedAt: "2023-01-02T15:04:05Z",
+					Attempts:   []models.OrderAttemptResponse{{AttemptedAt: "2023-01-02T15:04:05Z", HTTPStatus: 200, Status: "PROCESSING"}},
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetOrder(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Get balance",
+			method: http.MethodGet,
+			target: "/api/user/balance",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.user.EXPECT().GetUserBalance(gomock.Any(), uint(7)).Return(models.BalanceResponse{Current: 500.5, Withdrawn: 42}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetBalance(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Withdraw",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":100}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.withdraw.EXPECT().SaveWithdraw(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.SaveWithdraw(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Withdraw without funds",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":100}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.withdraw.EXPECT().SaveWithdraw(gomock.Any(), gomock.Any()).Return(apperrors.ErrInsufficientFunds)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.SaveWithdraw(c) },
+			status: http.StatusPaymentRequired,
+		},
+		{
+			name:        "Withdraw after too many wrong codes",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":100,"totp_code":"123456"}`,
+			keys:        user,
+			prepare: func(m serviceMocks) {
+				m.withdraw.EXPECT().SaveWithdraw(gomock.Any(), gomock.Any()).Return(apperrors.TooManyAttempts(time.Minute))
+			},
+			handle: func(h *Handler, c *gin.Context) { h.SaveWithdraw(c) },
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "List withdrawals",
+			method: http.MethodGet,
+			target: "/api/user/withdrawals",
+			keys:   user,
+			prepare: func(m serviceMocks) {
+				m.withdraw.EXPECT().GetWithdrawalsPage(gomock.Any(), gomock.Any()).
+					Return(models.WithdrawPage{Items: []models.WithdrawResponse{withdrawal}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetAllWithdrawals(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Events with invalid Last-Event-ID",
+			method: http.MethodGet,
+			target: "/api/user/events",
+			header: map[string]string{"Last-Event-ID": "abc"},
+			keys:   user,
+			handle: func(h *Handler, c *gin.Context) { h.StreamEvents(c) },
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Search users",
+			method: http.MethodGet,
+			target: "/api/admin/users?q=us",
+			prepare: func(m serviceMocks) {
+				m.admin.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).Return(models.AdminUserPage{Items: []models.AdminUserResponse{adminUser}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.SearchUsers(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Get user",
+			method: http.MethodGet,
+			target: "/api/admin/users/7",
+			params: gin.Params{{Key: "id", Value: "7"}},
+			prepare: func(m serviceMocks) {
+				m.admin.EXPECT().GetUser(gomock.Any(), uint(7)).Return(models.AdminUserDetailsResponse{
+					AdminUserResponse: adminUser,
+					Balance:           500,
+					Withdrawn:         100,
+					OrdersCount:       2,
+					WithdrawalsCount:  1,
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetUser(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Get user balance",
+			method: http.MethodGet,
+			target: "/api/admin/users/7/balance",
+			params: gin.Params{{Key: "id", Value: "7"}},
+			prepare: func(m serviceMocks) {
+				m.admin.EXPECT().GetUserBalance(gomock.Any(), uint(7)).Return(models.AdminBalanceResponse{
+					UserID:           7,
+					Current:          400,
+					Withdrawn:        100,
+					Accrued:          500,
+					WithdrawalsTotal: 100,
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetUserBalance(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Search audit events",
+			method: http.MethodGet,
+			target: "/api/admin/audit-events?actor_id=7",
+			prepare: func(m serviceMocks) {
+				m.audit.EXPECT().SearchEvents(gomock.Any(), gomock.Any()).Return(models.AuditEventPage{Items: []models.AuditEventResponse{{
+					ID:        1,
+					ActorID:   7,
+					Action:    entities.AuditLogin,
+					IP:        "10.0.0.1",
+					Status:    http.StatusOK,
+					Outcome:   entities.AuditOutcomeSuccess,
+					CreatedAt: "2023-01-02T15:04:05Z",
+				}}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.SearchAuditEvents(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Create partner",
+			method:      http.MethodPost,
+			target:      "/api/admin/partners",
+			contentType: "application/json",
+			body:        `{"name":"shop"}`,
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().CreatePartner(gomock.Any(), "shop").
+					Return(models.PartnerResponse{ID: 1, Name: "shop", CreatedAt: "2023-01-02T15:04:05Z"}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.CreatePartner(c) },
+			status: http.StatusCreated,
+		},
+		{
+			name:        "Create API key",
+			method:      http.MethodPost,
+			target:      "/api/admin/partners/1/keys",
+			contentType: "application/json",
+			body:        `{"scopes":["orders:write"]}`,
+			params:      gin.Params{{Key: "id", Value: "1"}},
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(models.APIKeyResponse{
+					ID:        1,
+					Key:       "key",
+					Prefix:    "prefix",
+					Scopes:    []string{entities.ScopeOrdersWrite},
+					RateLimit: 60,
+					CreatedAt: "2023-01-02T15:04:05Z",
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.CreateAPIKey(c) },
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Revoke API key",
+			method: http.MethodDelete,
+			target: "/api/admin/partners/1/keys/2",
+			params: gin.Params{{Key: "id", Value: "1"}, {Key: "keyID", Value: "2"}},
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().RevokeAPIKey(gomock.Any(), uint(1), uint(2)).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.RevokeAPIKey(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Link partner user",
+			method:      http.MethodPut,
+			target:      "/api/admin/partners/1/users/customer",
+			contentType: "application/json",
+			body:        `{"user_id":7}`,
+			params:      gin.Params{{Key: "id", Value: "1"}, {Key: "externalID", Value: "customer"}},
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().LinkUser(gomock.Any(), uint(1), "customer", uint(7)).Return(nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.LinkPartnerUser(c) },
+			status: http.StatusOK,
+		},
+		{
+			name:        "Upload partner order",
+			method:      http.MethodPost,
+			target:      "/api/partner/users/customer/orders",
+			contentType: "text/plain",
+			body:        "12345678903",
+			keys:        map[string]any{"partnerID": uint(1)},
+			params:      gin.Params{{Key: "externalID", Value: "customer"}},
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().ResolveUser(gomock.Any(), uint(1), "customer").Return(uint(7), nil)
+				m.order.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dto.OrderUploadResult{Order: entities.Order{Number: "12345678903"}}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.ProcessPartnerOrder(c) },
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "Get partner user balance",
+			method: http.MethodGet,
+			target: "/api/partner/users/customer/balance",
+			keys:   map[string]any{"partnerID": uint(1)},
+			params: gin.Params{{Key: "externalID", Value: "customer"}},
+			prepare: func(m serviceMocks) {
+				m.partner.EXPECT().ResolveUser(gomock.Any(), uint(1), "customer").Return(uint(0), apperrors.ErrNotFound)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetPartnerUserBalance(c) },
+			status: http.StatusNotFound,
+		},
+		{
+			name:        "Create webhook subscription",
+			method:      http.MethodPost,
+			target:      "/api/partner/webhooks",
+			contentType: "application/json",
+			body:        `{"url":"https://shop.example/hooks","event_types":["order.processed"]}`,
+			keys:        map[string]any{"partnerID": uint(1)},
+			prepare: func(m serviceMocks) {
+				m.webhooks.EXPECT().CreateSubscription(gomock.Any(), gomock.Any()).Return(models.WebhookSubscriptionResponse{
+					ID:         4,
+					URL:        "https://shop.example/hooks",
+					EventTypes: []string{"order.processed"},
+					Secret:     "secret",
+					CreatedAt:  "2023-01-02T15:04:05Z",
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.CreateWebhookSubscription(c) },
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Get webhook deliveries",
+			method: http.MethodGet,
+			target: "/api/partner/webhooks/4/deliveries",
+			keys:   map[string]any{"partnerID": uint(1)},
+			params: gin.Params{{Key: "id", Value: "4"}},
+			prepare: func(m serviceMocks) {
+				m.webhooks.EXPECT().GetDeliveries(gomock.Any(), gomock.Any()).Return(models.WebhookDeliveryPage{
+					Items: []models.WebhookDeliveryResponse{{
+						ID:            9,
+						EventType:     "order.processed",
+						Status:        "pending",
+						Attempts:      1,
+						Error:         "unexpected response status 500",
+						CreatedAt:     "2023-01-02T15:04:05Z",
+						LastAttemptAt: "2023-01-02T15:04:05Z",
+						NextAttemptAt: "2023-01-02T15:05:05Z",
+					}},
+					NextCursor: "next",
+				}, nil)
+			},
+			handle: func(h *Handler, c *gin.Context) { h.GetWebhookDeliveries(c) },
+			status: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := serviceMocks{
+				user:      mocks.NewMockUserService(ctrl),
+				order:     mocks.NewMockOrderService(ctrl),
+				withdraw:  mocks.NewMockWithdrawService(ctrl),
+				token:     mocks.NewMockTokenService(ctrl),
+				admin:     mocks.NewMockAdminService(ctrl),
+				reset:     mocks.NewMockPasswordResetService(ctrl),
+				twoFactor: mocks.NewMockTwoFactorService(ctrl),
+				partner:   mocks.NewMockPartnerService(ctrl),
+				account:   mocks.NewMockAccountService(ctrl),
+				audit:     mocks.NewMockAuditService(ctrl),
+				oidc:      mocks.NewMockOIDCService(ctrl),
+				events:    mocks.NewMockEventService(ctrl),
+				webhooks:  mocks.NewMockWebhookService(ctrl),
+			}
+			if tt.prepare != nil {
+				tt.prepare(m)
+			}
+			h := NewHandler(m.user, m.order, m.withdraw, m.token, m.admin, m.reset, m.twoFactor, m.partner, m.account,
+				m.audit, m.oidc, m.events, m.webhooks, keyring.New(), document, SessionCookie{}, 100)
+
+			request := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.header {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = request
+			c.Params = tt.params
+			for key, value := range tt.keys {
+				c.Set(key, value)
+			}
+			tt.handle(h, c)
+			if recorder.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+
+			route, pathParams, err := apiRouter.FindRoute(request)
+			if err != nil {
+				t.Fatalf("route is not documented: %v", err)
+			}
+			err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    request,
+					PathParams: pathParams,
+					Route:      route,
+				},
+				Status:  recorder.Code,
+				Header:  recorder.Header(),
+				Body:    io.NopCloser(recorder.Body),
+				Options: &openapi3filter.Options{IncludeResponseStatus: true},
+			})
+			if err != nil {
+				t.Errorf("response does not match the OpenAPI document: %v", err)
+			}
+		})
+	}
+}
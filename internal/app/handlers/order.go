@@ -1,60 +1,127 @@
 package handlers
 
 import (
-	"errors"
+	"bytes"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
-	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 	"net/http"
+	"strings"
 )
 
-var ErrOrderAlreadyUploaded = errors.New("order already uploaded by another user")
-var ErrOrderAlreadyUploadedByUser = errors.New("order already uploaded by this user")
-var ErrOrderHasWrongFormat = errors.New("order already uploaded by this user")
-
 func (h *Handler) ProcessUserOrder(c RequestContext) {
 	requestBytes, err := c.GetRawData()
 	if err != nil {
-		logger.Log.Infof("error while reading request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while reading request"})
+		RespondError(c, apperrors.InvalidRequest("error while reading request"))
 		return
 	}
 	orderNumber := string(requestBytes)
 	userID := c.MustGet("userID").(uint)
-	order, err := h.orderService.SaveOrder(dto.OrderDTO{Number: orderNumber, UserID: userID})
+	result, err := h.orderService.SaveOrder(requestCtx(c), dto.OrderDTO{Number: orderNumber, UserID: userID})
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	respondOrderUploaded(c, result)
+}
+
+// respondOrderUploaded ответ на принятый заказ. На повторную загрузку своего заказа ответ тот же, но с 200.
+func respondOrderUploaded(c RequestContext, result dto.OrderUploadResult) {
+	status := http.StatusAccepted
+	if result.AlreadyUploaded {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{"processed": result.Order.Number})
+}
+
+// ProcessUserOrdersBatch принимает пакет номеров заказов: JSON-массив или текст, по номеру на строку
+func (h *Handler) ProcessUserOrdersBatch(c RequestContext) {
+	requestBytes, err := c.GetRawData()
+	if err != nil {
+		RespondError(c, apperrors.InvalidRequest("error while reading request"))
+		return
+	}
+	numbers, err := parseOrderNumbers(c.ContentType(), requestBytes)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(numbers) == 0 {
+		RespondError(c, apperrors.InvalidRequest("batch is empty"))
+		return
+	}
+	if len(numbers) > h.orderBatchMaxSize {
+		RespondError(c, apperrors.InvalidRequest("batch must contain at most %d order numbers", h.orderBatchMaxSize))
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	results, err := h.orderService.SaveOrders(requestCtx(c), userID, numbers)
 	if err != nil {
-		if errors.Is(err, ErrOrderAlreadyUploadedByUser) {
-			logger.Log.Infof("Order already uploaded by user %v", userID)
-			c.JSON(http.StatusOK, gin.H{"error": "order already uploaded by this user"})
-			return
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.BatchOrderResponse{Results: results})
+}
+
+func parseOrderNumbers(contentType string, body []byte) ([]string, error) {
+	if contentType == "application/json" {
+		var items []any
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&items); err != nil {
+			return nil, apperrors.InvalidRequest("malformed JSON")
 		}
-		if errors.Is(err, ErrOrderAlreadyUploaded) {
-			logger.Log.Infof("Order already uploaded by another user")
-			c.JSON(http.StatusConflict, gin.H{"error": "order already uploaded by another user"})
-			return
+		numbers := make([]string, 0, len(items))
+		for _, item := range items {
+			switch number := item.(type) {
+			case string:
+				numbers = append(numbers, strings.TrimSpace(number))
+			case json.Number:
+				numbers = append(numbers, number.String())
+			default:
+				return nil, apperrors.InvalidRequest("order numbers must be strings or numbers")
+			}
 		}
-		if errors.Is(err, ErrOrderHasWrongFormat) {
-			logger.Log.Infof("Wrong order number format: %s", order.Number)
-			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "wrong order number format"})
-			return
+		return numbers, nil
+	}
+	var numbers []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if number := strings.TrimSpace(line); number != "" {
+			numbers = append(numbers, number)
 		}
-		logger.Log.Infof("Internal Server Error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
 	}
-	c.JSON(http.StatusAccepted, gin.H{"processed": order.Number})
+	return numbers, nil
 }
 
 func (h *Handler) GetAllOrders(c RequestContext) {
-	userID := c.MustGet("userID").(uint)
-	orders, err := h.orderService.GetAllOrders(userID)
+	query, err := parsePageQuery(c, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		RespondError(c, err)
 		return
 	}
-	if len(orders) == 0 {
+	page, err := h.orderService.GetOrdersPage(requestCtx(c), query)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(page.Items) == 0 {
 		c.JSON(http.StatusNoContent, gin.H{"error": "orders not found"})
 		return
 	}
-	c.JSON(http.StatusOK, orders)
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+func (h *Handler) GetOrder(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	order, err := h.orderService.GetOrder(requestCtx(c), userID, c.Param("number"))
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
 	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
@@ -30,11 +31,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 		mustGetReturn       uint
 		mustGetCallCount    int
 		saveOrderParameters dto.OrderDTO
-		saveOrderResponse   entities.Order
+		saveOrderResponse   dto.OrderUploadResult
 		saveOrderError      error
 		saveOrderCallCount  int
 		status              int
-		response            gin.H
+		response            any
 	}{
 		{
 			name:             "Success",
@@ -46,8 +47,8 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 				Number: "1234567890",
 				UserID: 101,
 			},
-			saveOrderResponse: entities.Order{
-				Number: "1234567890",
+			saveOrderResponse: dto.OrderUploadResult{
+				Order: entities.Order{Number: "1234567890"},
 			},
 			saveOrderError:     nil,
 			saveOrderCallCount: 1,
@@ -60,11 +61,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 			getRowDataError:     errors.New("error while reading request"),
 			mustGetCallCount:    0,
 			saveOrderParameters: dto.OrderDTO{},
-			saveOrderResponse:   entities.Order{},
+			saveOrderResponse:   dto.OrderUploadResult{},
 			saveOrderError:      nil,
 			saveOrderCallCount:  0,
 			status:              http.StatusBadRequest,
-			response:            gin.H{"error": "Error while reading request"},
+			response:            newProblem(apperrors.InvalidRequest("error while reading request")),
 		},
 		{
 			name:             "Order already uploaded by this user",
@@ -76,11 +77,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 				Number: "1234567890",
 				UserID: 101,
 			},
-			saveOrderResponse:  entities.Order{},
-			saveOrderError:     ErrOrderAlreadyUploadedByUser,
+			saveOrderResponse:  dto.OrderUploadResult{Order: entities.Order{Number: "1234567890"}, AlreadyUploaded: true},
+			saveOrderError:     nil,
 			saveOrderCallCount: 1,
 			status:             http.StatusOK,
-			response:           gin.H{"error": "order already uploaded by this user"},
+			response:           gin.H{"processed": "1234567890"},
 		},
 		{
 			name:             "Order already uploaded by another user",
@@ -92,11 +93,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 				Number: "1234567890",
 				UserID: 101,
 			},
-			saveOrderResponse:  entities.Order{},
-			saveOrderError:     ErrOrderAlreadyUploaded,
+			saveOrderResponse:  dto.OrderUploadResult{},
+			saveOrderError:     apperrors.ErrOrderUploadedByOtherUser,
 			saveOrderCallCount: 1,
 			status:             http.StatusConflict,
-			response:           gin.H{"error": "order already uploaded by another user"},
+			response:           newProblem(apperrors.ErrOrderUploadedByOtherUser),
 		},
 		{
 			name:             "Order has wrong format",
@@ -108,11 +109,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 				Number: "1234567890",
 				UserID: 101,
 			},
-			saveOrderResponse:  entities.Order{},
-			saveOrderError:     ErrOrderHasWrongFormat,
+			saveOrderResponse:  dto.OrderUploadResult{},
+			saveOrderError:     apperrors.ErrInvalidOrderNumber,
 			saveOrderCallCount: 1,
 			status:             http.StatusUnprocessableEntity,
-			response:           gin.H{"error": "wrong order number format"},
+			response:           newProblem(apperrors.ErrInvalidOrderNumber),
 		},
 		{
 			name:             "Internal Server Error",
@@ -124,11 +125,11 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 				Number: "1234567890",
 				UserID: 101,
 			},
-			saveOrderResponse:  entities.Order{},
+			saveOrderResponse:  dto.OrderUploadResult{},
 			saveOrderError:     errors.New("internal Server Error"),
 			saveOrderCallCount: 1,
 			status:             http.StatusInternalServerError,
-			response:           gin.H{"error": "Internal Server Error"},
+			response:           newProblem(errors.New("internal Server Error")),
 		},
 	}
 	orderService := mocks.NewMockOrderService(ctrl)
@@ -139,9 +140,10 @@ func TestHandler_ProcessUserOrder(t *testing.T) {
 		requestContext.EXPECT().MustGet("userID").
 			Return(tt.mustGetReturn).
 			Times(tt.mustGetCallCount)
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
 		requestContext.EXPECT().JSON(tt.status, tt.response)
 
-		orderService.EXPECT().SaveOrder(tt.saveOrderParameters).
+		orderService.EXPECT().SaveOrder(gomock.Any(), tt.saveOrderParameters).
 			Return(tt.saveOrderResponse, tt.saveOrderError).
 			Times(tt.saveOrderCallCount)
 
@@ -160,9 +162,6 @@ func TestHandler_GetAllOrders(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
 	now := time.Now()
 	orders := []models.AllOrderResponse{
 		{
@@ -173,54 +172,102 @@ func TestHandler_GetAllOrders(t *testing.T) {
 			UploadedAt:   now.Format(time.RFC3339),
 		},
 	}
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	minAmount := 10.5
 
 	tests := []struct {
-		name               string
-		mustGetReturn      uint
-		mustGetCallCount   int
-		getAllOrdersReturn []models.AllOrderResponse
-		getAllOrdersError  error
-		status             int
-		response           any
+		name             string
+		queryParams      map[string]string
+		pageQuery        dto.PageQuery
+		getPageCallCount int
+		getPageReturn    models.OrderPage
+		getPageError     error
+		nextCursorHeader string
+		status           int
+		response         any
 	}{
 		{
-			name:               "Success",
-			mustGetReturn:      101,
-			mustGetCallCount:   1,
-			getAllOrdersReturn: orders,
-			getAllOrdersError:  nil,
-			status:             http.StatusOK,
-			response:           orders,
+			name:             "Success",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageReturn:    models.OrderPage{Items: orders},
+			status:           http.StatusOK,
+			response:         orders,
 		},
 		{
-			name:               "Internal Server Error",
-			mustGetReturn:      101,
-			mustGetCallCount:   1,
-			getAllOrdersReturn: []models.AllOrderResponse{},
-			getAllOrdersError:  errors.New("internal Server Error"),
-			status:             http.StatusInternalServerError,
-			response:           gin.H{"error": "Internal Server Error"},
+			name: "Success with filters and next page",
+			queryParams: map[string]string{
+				"limit":      "1",
+				"order":      "desc",
+				"status":     "new, processed",
+				"from":       "2023-07-01T00:00:00Z",
+				"min_amount": "10.5",
+				"cursor":     "abc",
+			},
+			pageQuery: dto.PageQuery{
+				UserID:     101,
+				Limit:      1,
+				Cursor:     "abc",
+				Descending: true,
+				Statuses:   []string{"NEW", "PROCESSED"},
+				From:       &from,
+				MinAmount:  &minAmount,
+			},
+			getPageCallCount: 1,
+			getPageReturn:    models.OrderPage{Items: orders, NextCursor: "next"},
+			nextCursorHeader: "next",
+			status:           http.StatusOK,
+			response:         orders,
 		},
 		{
-			name:               "Orders not found",
-			mustGetReturn:      101,
-			mustGetCallCount:   1,
-			getAllOrdersReturn: []models.AllOrderResponse{},
-			getAllOrdersError:  nil,
-			status:             http.StatusNoContent,
-			response:           gin.H{"error": "orders not found"},
+			name:             "Internal Server Error",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageError:     errors.New("internal Server Error"),
+			status:           http.StatusInternalServerError,
+			response:         newProblem(errors.New("internal Server Error")),
+		},
+		{
+			name:             "Orders not found",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageReturn:    models.OrderPage{Items: []models.AllOrderResponse{}},
+			status:           http.StatusNoContent,
+			response:         gin.H{"error": "orders not found"},
+		},
+		{
+			name:        "Invalid limit",
+			queryParams: map[string]string{"limit": "0"},
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit)),
+		},
+		{
+			name:        "Unknown status",
+			queryParams: map[string]string{"status": "DONE"},
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("unknown status %q", "DONE")),
 		},
 	}
-	orderService := mocks.NewMockOrderService(ctrl)
-	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			requestContext.EXPECT().MustGet(gomock.Any()).
-				Return(tt.mustGetReturn).
-				Times(tt.mustGetCallCount)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			orderService := mocks.NewMockOrderService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+
+			requestContext.EXPECT().MustGet("userID").Return(uint(101))
+			requestContext.EXPECT().Query(gomock.Any()).DoAndReturn(func(key string) string {
+				return tt.queryParams[key]
+			}).AnyTimes()
+			if tt.nextCursorHeader != "" {
+				requestContext.EXPECT().Header(nextCursorHeader, tt.nextCursorHeader)
+			}
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
 			requestContext.EXPECT().JSON(tt.status, tt.response)
 
-			orderService.EXPECT().GetAllOrders(gomock.Any()).Return(tt.getAllOrdersReturn, tt.getAllOrdersError)
+			orderService.EXPECT().GetOrdersPage(gomock.Any(), tt.pageQuery).
+				Return(tt.getPageReturn, tt.getPageError).
+				Times(tt.getPageCallCount)
 
 			h := &Handler{
 				orderService: orderService,
@@ -229,3 +276,165 @@ func TestHandler_GetAllOrders(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_GetOrder(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	order := models.OrderResponse{
+		Number:     "12345678903",
+		Status:     "PROCESSED",
+		Accrual:    500,
+		UploadedAt: "2023-07-01T12:00:00Z",
+		Attempts: []models.OrderAttemptResponse{
+			{AttemptedAt: "2023-07-01T12:00:01Z", HTTPStatus: http.StatusOK, Status: "PROCESSED"},
+		},
+	}
+	notFound := apperrors.Newf(apperrors.CodeNotFound, "order %s not found", "12345678903")
+
+	tests := []struct {
+		name           string
+		getOrderReturn models.OrderResponse
+		getOrderError  error
+		status         int
+		response       any
+	}{
+		{
+			name:           "Success",
+			getOrderReturn: order,
+			status:         http.StatusOK,
+			response:       order,
+		},
+		{
+			name:          "Not found",
+			getOrderError: notFound,
+			status:        http.StatusNotFound,
+			response:      newProblem(notFound),
+		},
+		{
+			name:          "Internal Server Error",
+			getOrderError: errors.New("internal Server Error"),
+			status:        http.StatusInternalServerError,
+			response:      newProblem(errors.New("internal Server Error")),
+		},
+	}
+	orderService := mocks.NewMockOrderService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	for _, tt := range tests {
+		requestContext.EXPECT().MustGet("userID").Return(uint(101))
+		requestContext.EXPECT().Param("number").Return("12345678903")
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+		requestContext.EXPECT().JSON(tt.status, tt.response)
+
+		orderService.EXPECT().GetOrder(gomock.Any(), uint(101), "12345678903").
+			Return(tt.getOrderReturn, tt.getOrderError)
+
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				orderService: orderService,
+			}
+			h.GetOrder(requestContext)
+		})
+	}
+}
+
+func TestHandler_ProcessUserOrdersBatch(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []models.BatchOrderResult{
+		{Number: "12345678903", Result: models.BatchResultAccepted},
+		{Number: "1234", Result: models.BatchResultInvalidLuhn},
+	}
+	tests := []struct {
+		name                string
+		contentType         string
+		body                []byte
+		saveOrdersNumbers   []string
+		saveOrdersCallCount int
+		saveOrdersError     error
+		status              int
+		response            any
+	}{
+		{
+			name:                "JSON array",
+			contentType:         "application/json",
+			body:                []byte(`["12345678903", 1234]`),
+			saveOrdersNumbers:   []string{"12345678903", "1234"},
+			saveOrdersCallCount: 1,
+			status:              http.StatusOK,
+			response:            models.BatchOrderResponse{Results: results},
+		},
+		{
+			name:                "Newline separated text",
+			contentType:         "text/plain",
+			body:                []byte("12345678903\r\n\n 1234 \n"),
+			saveOrdersNumbers:   []string{"12345678903", "1234"},
+			saveOrdersCallCount: 1,
+			status:              http.StatusOK,
+			response:            models.BatchOrderResponse{Results: results},
+		},
+		{
+			name:        "Malformed JSON",
+			contentType: "application/json",
+			body:        []byte(`{"numbers": []}`),
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("malformed JSON")),
+		},
+		{
+			name:        "Empty batch",
+			contentType: "text/plain",
+			body:        []byte("\n\n"),
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("batch is empty")),
+		},
+		{
+			name:        "Batch too large",
+			contentType: "text/plain",
+			body:        []byte("1\n2\n3\n4"),
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("batch must contain at most %d order numbers", 3)),
+		},
+		{
+			name:                "Internal Server Error",
+			contentType:         "text/plain",
+			body:                []byte("12345678903"),
+			saveOrdersNumbers:   []string{"12345678903"},
+			saveOrdersCallCount: 1,
+			saveOrdersError:     errors.New("internal Server Error"),
+			status:              http.StatusInternalServerError,
+			response:            newProblem(errors.New("internal Server Error")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			orderService := mocks.NewMockOrderService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+
+			requestContext.EXPECT().GetRawData().Return(tt.body, nil)
+			requestContext.EXPECT().ContentType().Return(tt.contentType)
+			requestContext.EXPECT().MustGet("userID").Return(uint(101)).Times(tt.saveOrdersCallCount)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+
+			orderService.EXPECT().SaveOrders(gomock.Any(), uint(101), tt.saveOrdersNumbers).
+				Return(results, tt.saveOrdersError).
+				Times(tt.saveOrdersCallCount)
+
+			h := &Handler{
+				orderService:      orderService,
+				orderBatchMaxSize: 3,
+			}
+			h.ProcessUserOrdersBatch(requestContext)
+		})
+	}
+}
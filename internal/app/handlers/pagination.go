@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	// nextCursorHeader заголовок с курсором следующей страницы. Тело ответа остаётся массивом,
+	// как того требует спецификация, поэтому курсор передаётся отдельно.
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+var orderStatuses = map[string]bool{
+	"NEW":        true,
+	"PROCESSING": true,
+	"INVALID":    true,
+	"PROCESSED":  true,
+}
+
+// parsePageQuery разбирает параметры limit, cursor, order, from, to, min_amount, max_amount
+// и, если withStatus, status - список статусов через запятую.
+func parsePageQuery(c RequestContext, withStatus bool) (dto.PageQuery, error) {
+	query := dto.PageQuery{
+		UserID: c.MustGet("userID").(uint),
+		Limit:  defaultPageLimit,
+		Cursor: c.Query("cursor"),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return dto.PageQuery{}, apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit)
+		}
+		query.Limit = limit
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return dto.PageQuery{}, apperrors.InvalidRequest("order must be asc or desc")
+	}
+	if raw := c.Query("status"); withStatus && raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !orderStatuses[status] {
+				return dto.PageQuery{}, apperrors.InvalidRequest("unknown status %q", status)
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		return dto.PageQuery{}, err
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		return dto.PageQuery{}, err
+	}
+	if query.MinAmount, err = parseAmountParam(c, "min_amount"); err != nil {
+		return dto.PageQuery{}, err
+	}
+	if query.MaxAmount, err = parseAmountParam(c, "max_amount"); err != nil {
+		return dto.PageQuery{}, err
+	}
+	return query, nil
+}
+
+func parseTimeParam(c RequestContext, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, apperrors.InvalidRequest("%s must be an RFC 3339 timestamp", key)
+	}
+	return &value, nil
+}
+
+func parseAmountParam(c RequestContext, key string) (*float64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, apperrors.InvalidRequest("%s must be a number", key)
+	}
+	return &value, nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+)
+
+// ProcessPartnerOrder загружает заказ от имени пользователя, связанного с клиентом партнёра
+func (h *Handler) ProcessPartnerOrder(c RequestContext) {
+	userID, err := h.resolvePartnerUser(c)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	requestBytes, err := c.GetRawData()
+	if err != nil {
+		RespondError(c, apperrors.InvalidRequest("error while reading request"))
+		return
+	}
+	result, err := h.orderService.SaveOrder(requestCtx(c), dto.OrderDTO{Number: string(requestBytes), UserID: userID})
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	respondOrderUploaded(c, result)
+}
+
+func (h *Handler) GetPartnerUserBalance(c RequestContext) {
+	userID, err := h.resolvePartnerUser(c)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	balance, err := h.userService.GetUserBalance(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+func (h *Handler) resolvePartnerUser(c RequestContext) (uint, error) {
+	partnerID := c.MustGet("partnerID").(uint)
+	return h.partnerService.ResolveUser(requestCtx(c), partnerID, c.Param("externalID"))
+}
+
+func (h *Handler) CreatePartner(c RequestContext) {
+	var req models.PartnerRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	partner, err := h.partnerService.CreatePartner(requestCtx(c), req.Name)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, partner)
+}
+
+// CreateAPIKey выпускает ключ партнёру. Ключ показывается один раз, восстановить его нельзя.
+func (h *Handler) CreateAPIKey(c RequestContext) {
+	partnerID, err := parseIDParam(c, "id", "partner id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	var req models.APIKeyRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	key, err := h.partnerService.CreateAPIKey(requestCtx(c), dto.APIKeyDTO{
+		PartnerID: partnerID,
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+	})
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *Handler) RevokeAPIKey(c RequestContext) {
+	partnerID, err := parseIDParam(c, "id", "partner id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	keyID, err := parseIDParam(c, "keyID", "key id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if err := h.partnerService.RevokeAPIKey(requestCtx(c), partnerID, keyID); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "API key revoked"})
+}
+
+// LinkPartnerUser связывает клиента партнёра с пользователем системы
+func (h *Handler) LinkPartnerUser(c RequestContext) {
+	partnerID, err := parseIDParam(c, "id", "partner id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	var req models.PartnerUserLinkRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	if err := h.partnerService.LinkUser(requestCtx(c), partnerID, c.Param("externalID"), req.UserID); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "customer linked"})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_ProcessPartnerOrder(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notLinked := apperrors.Newf(apperrors.CodeNotFound, "customer %s is not linked to a user", "c-1")
+	tests := []struct {
+		name            string
+		resolveError    error
+		saveCallCount   int
+		saveError       error
+		alreadyUploaded bool
+		status          int
+		response        any
+	}{
+		{
+			name:          "Success",
+			saveCallCount: 1,
+			status:        http.StatusAccepted,
+			response:      gin.H{"processed": "12345678903"},
+		},
+		{
+			name:         "Customer not linked",
+			resolveError: notLinked,
+			status:       http.StatusNotFound,
+			response:     newProblem(notLinked),
+		},
+		{
+			name:            "Order already uploaded",
+			saveCallCount:   1,
+			alreadyUploaded: true,
+			status:          http.StatusOK,
+			response:        gin.H{"processed": "12345678903"},
+		},
+		{
+			name:          "Order of another user",
+			saveCallCount: 1,
+			saveError:     apperrors.ErrOrderUploadedByOtherUser,
+			status:        http.StatusConflict,
+			response:      newProblem(apperrors.ErrOrderUploadedByOtherUser),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			partnerService := mocks.NewMockPartnerService(ctrl)
+			orderService := mocks.NewMockOrderService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().MustGet("partnerID").Return(uint(3))
+			requestContext.EXPECT().Param("externalID").Return("c-1")
+			requestContext.EXPECT().GetRawData().Return([]byte("12345678903"), nil).Times(tt.saveCallCount)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			partnerService.EXPECT().ResolveUser(gomock.Any(), uint(3), "c-1").Return(uint(9), tt.resolveError)
+			orderService.EXPECT().SaveOrder(gomock.Any(), dto.OrderDTO{Number: "12345678903", UserID: 9}).
+				Return(dto.OrderUploadResult{Order: entities.Order{Number: "12345678903"}, AlreadyUploaded: tt.alreadyUploaded}, tt.saveError).
+				Times(tt.saveCallCount)
+
+			h := &Handler{
+				partnerService: partnerService,
+				orderService:   orderService,
+			}
+			h.ProcessPartnerOrder(requestContext)
+		})
+	}
+}
+
+func TestHandler_CreateAPIKey(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := models.APIKeyResponse{ID: 1, Key: "gm_abcd_secret", Prefix: "abcd", Scopes: []string{"orders:write"}, RateLimit: 60}
+	invalid := apperrors.ValidationFailed([]string{"unknown scope orders:delete"})
+	tests := []struct {
+		name            string
+		id              string
+		body            string
+		createCallCount int
+		createError     error
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			id:              "2",
+			body:            `{"scopes": ["orders:write"]}`,
+			createCallCount: 1,
+			status:          http.StatusCreated,
+			response:        key,
+		},
+		{
+			name:            "Unknown scope",
+			id:              "2",
+			body:            `{"scopes": ["orders:write"]}`,
+			createCallCount: 1,
+			createError:     invalid,
+			status:          http.StatusBadRequest,
+			response:        newProblem(invalid),
+		},
+		{
+			name:     "Invalid partner id",
+			id:       "x",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("partner id must be a positive integer")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			partnerService := mocks.NewMockPartnerService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().Param("id").Return(tt.id)
+			requestContext.EXPECT().GetRawData().Return([]byte(tt.body), nil).Times(tt.createCallCount)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			partnerService.EXPECT().CreateAPIKey(gomock.Any(), dto.APIKeyDTO{PartnerID: 2, Scopes: []string{"orders:write"}}).
+				Return(key, tt.createError).Times(tt.createCallCount)
+
+			h := &Handler{
+				partnerService: partnerService,
+			}
+			h.CreateAPIKey(requestContext)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+)
+
+// RequestPasswordReset отвечает одинаково независимо от того, существует ли логин
+func (h *Handler) RequestPasswordReset(c RequestContext) {
+	var req models.PasswordResetRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditTargetKey, req.Login)
+	if err := h.resetService.RequestReset(requestCtx(c), req.Login, c.ClientIP()); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"info": "if the account has an email, reset instructions have been sent"})
+}
+
+// ConfirmPasswordReset устанавливает новый пароль и завершает все сессии пользователя
+func (h *Handler) ConfirmPasswordReset(c RequestContext) {
+	var req models.PasswordResetConfirmRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	userID, err := h.resetService.ConfirmReset(requestCtx(c), req.Token, req.NewPassword)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditActorKey, userID)
+	if err := h.tokenService.RevokeAllForUser(requestCtx(c), userID); err != nil {
+		RespondError(c, fmt.Errorf("failed to revoke sessions: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "password has been reset"})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_ConfirmPasswordReset(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		confirmUserID   uint
+		confirmError    error
+		revokeCallCount int
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			confirmUserID:   7,
+			revokeCallCount: 1,
+			status:          http.StatusOK,
+			response:        gin.H{"info": "password has been reset"},
+		},
+		{
+			name:         "Invalid token",
+			confirmError: apperrors.ErrInvalidResetToken,
+			status:       http.StatusBadRequest,
+			response:     newProblem(apperrors.ErrInvalidResetToken),
+		},
+		{
+			name:         "Internal Server Error",
+			confirmError: errors.New("internal Server Error"),
+			status:       http.StatusInternalServerError,
+			response:     newProblem(errors.New("internal Server Error")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			resetService := mocks.NewMockPasswordResetService(ctrl)
+			tokenService := mocks.NewMockTokenService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().GetRawData().Return([]byte(`{"token": "token", "new_password": "New password 1"}`), nil)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().Set(AuditActorKey, uint(7)).Times(tt.revokeCallCount)
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			resetService.EXPECT().ConfirmReset(gomock.Any(), "token", "New password 1").Return(tt.confirmUserID, tt.confirmError)
+			tokenService.EXPECT().RevokeAllForUser(gomock.Any(), uint(7)).Return(nil).Times(tt.revokeCallCount)
+
+			h := &Handler{
+				resetService: resetService,
+				tokenService: tokenService,
+			}
+			h.ConfirmPasswordReset(requestContext)
+		})
+	}
+}
+
+func TestHandler_RequestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resetService := mocks.NewMockPasswordResetService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	requestContext.EXPECT().GetRawData().Return([]byte(`{"login": "alice"}`), nil)
+	requestContext.EXPECT().Set(AuditTargetKey, "alice")
+	requestContext.EXPECT().ClientIP().Return("10.0.0.1")
+	requestContext.EXPECT().JSON(http.StatusAccepted, gin.H{"info": "if the account has an email, reset instructions have been sent"})
+	resetService.EXPECT().RequestReset(gomock.Any(), "alice", "10.0.0.1").Return(nil)
+
+	h := &Handler{
+		resetService: resetService,
+	}
+	h.RequestPasswordReset(requestContext)
+}
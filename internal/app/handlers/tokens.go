@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// respondWithTokens выдаёт пользователю новую пару токенов.
+// Access-токен дублируется в заголовке Authorization для совместимости со старыми клиентами,
+// а при включённой cookie-сессии ещё и в cookie.
+func (h *Handler) respondWithTokens(c RequestContext, userID uint) {
+	tokens, err := h.tokenService.IssueTokens(requestCtx(c), userID, clientInfo(c))
+	if err != nil {
+		RespondError(c, fmt.Errorf("failed to create JWT token: %w", err))
+		return
+	}
+	h.writeTokens(c, tokens)
+}
+
+func (h *Handler) writeTokens(c RequestContext, tokens models.TokenResponse) {
+	if err := h.setSessionCookies(c, tokens.AccessToken, int(tokens.ExpiresIn)); err != nil {
+		RespondError(c, fmt.Errorf("failed to set session cookies: %w", err))
+		return
+	}
+	c.Header("Authorization", tokens.AccessToken)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) RefreshToken(c RequestContext) {
+	var req models.RefreshTokenRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	tokens, err := h.tokenService.Refresh(requestCtx(c), req.RefreshToken, clientInfo(c))
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	h.writeTokens(c, tokens)
+}
+
+// Logout отзывает текущий access-токен. Тело запроса необязательно: если в нём передан refresh-токен,
+// отзывается и он.
+func (h *Handler) Logout(c RequestContext) {
+	var req models.RefreshTokenRequest
+	requestBytes, err := c.GetRawData()
+	if err == nil && len(requestBytes) > 0 {
+		if err := readJSONBytes(requestBytes, &req); err != nil {
+			RespondError(c, err)
+			return
+		}
+	}
+	userID := c.MustGet("userID").(uint)
+	jti := c.MustGet("tokenID").(string)
+	expiresAt := c.MustGet("tokenExpiresAt").(time.Time)
+	sessionID := c.MustGet("sessionID").(string)
+	if err := h.tokenService.Logout(requestCtx(c), userID, jti, expiresAt, sessionID, req.RefreshToken); err != nil {
+		RespondError(c, err)
+		return
+	}
+	h.clearSessionCookies(c)
+	c.JSON(http.StatusOK, gin.H{"info": "logout successful"})
+}
+
+// ListSessions список активных сессий текущего пользователя
+func (h *Handler) ListSessions(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	sessions, err := h.tokenService.ListSessions(requestCtx(c), userID, c.MustGet("sessionID").(string))
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession завершает одну из сессий текущего пользователя, например на потерянном устройстве
+func (h *Handler) RevokeSession(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	sessionID, err := parseIDParam(c, "id", "session id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditTargetKey, strconv.FormatUint(uint64(sessionID), 10))
+	if err := h.tokenService.RevokeSession(requestCtx(c), userID, sessionID); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "session revoked"})
+}
+
+// clientInfo устройство и адрес, с которых пришёл запрос
+func clientInfo(c RequestContext) dto.ClientInfo {
+	return dto.ClientInfo{IP: c.ClientIP(), UserAgent: c.GetHeader("User-Agent")}
+}
+
+// GetJWKS публикует открытые ключи, которыми можно проверить токены gophermart
+func (h *Handler) GetJWKS(c RequestContext) {
+	c.JSON(http.StatusOK, h.jwks.JWKS())
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_RefreshToken(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh-2"}
+	tests := []struct {
+		name             string
+		getRowDataReturn []byte
+		refreshCallCount int
+		refreshError     error
+		headerCallCount  int
+		status           int
+		response         any
+	}{
+		{
+			name:             "Success",
+			getRowDataReturn: []byte(`{"refresh_token": "refresh-1"}`),
+			refreshCallCount: 1,
+			headerCallCount:  1,
+			status:           http.StatusOK,
+			response:         tokens,
+		},
+		{
+			name:             "Invalid refresh token",
+			getRowDataReturn: []byte(`{"refresh_token": "refresh-1"}`),
+			refreshCallCount: 1,
+			refreshError:     apperrors.ErrInvalidRefreshToken,
+			headerCallCount:  1,
+			status:           http.StatusUnauthorized,
+			response:         newProblem(apperrors.ErrInvalidRefreshToken),
+		},
+		{
+			name:             "Error while marshalling json",
+			getRowDataReturn: []byte("WRONG JSON STRING"),
+			headerCallCount:  1,
+			status:           http.StatusBadRequest,
+			response:         newProblem(apperrors.InvalidRequest("malformed JSON")),
+		},
+	}
+	tokenService := mocks.NewMockTokenService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	for _, tt := range tests {
+		requestContext.EXPECT().GetRawData().Return(tt.getRowDataReturn, nil)
+		requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).Times(tt.headerCallCount)
+		requestContext.EXPECT().JSON(tt.status, tt.response)
+		requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.refreshCallCount)
+		requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.refreshCallCount)
+
+		tokenService.EXPECT().Refresh(gomock.Any(), "refresh-1", dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).
+			Return(tokens, tt.refreshError).
+			Times(tt.refreshCallCount)
+
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				tokenService: tokenService,
+			}
+			h.RefreshToken(requestContext)
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Unix(1700000000, 0)
+	tests := []struct {
+		name             string
+		getRowDataReturn []byte
+		refreshToken     string
+		logoutCallCount  int
+		logoutError      error
+		status           int
+		response         any
+	}{
+		{
+			name:             "Without refresh token",
+			getRowDataReturn: nil,
+			logoutCallCount:  1,
+			status:           http.StatusOK,
+			response:         gin.H{"info": "logout successful"},
+		},
+		{
+			name:             "With refresh token",
+			getRowDataReturn: []byte(`{"refresh_token": "refresh-1"}`),
+			refreshToken:     "refresh-1",
+			logoutCallCount:  1,
+			status:           http.StatusOK,
+			response:         gin.H{"info": "logout successful"},
+		},
+		{
+			name:             "Internal Server Error",
+			getRowDataReturn: nil,
+			logoutCallCount:  1,
+			logoutError:      errors.New("internal Server Error"),
+			status:           http.StatusInternalServerError,
+			response:         newProblem(errors.New("internal Server Error")),
+		},
+	}
+	tokenService := mocks.NewMockTokenService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	for _, tt := range tests {
+		requestContext.EXPECT().GetRawData().Return(tt.getRowDataReturn, nil)
+		requestContext.EXPECT().MustGet("userID").Return(uint(101))
+		requestContext.EXPECT().MustGet("tokenID").Return("jti-1")
+		requestContext.EXPECT().MustGet("tokenExpiresAt").Return(expiresAt)
+		requestContext.EXPECT().MustGet("sessionID").Return("session-1")
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+		requestContext.EXPECT().JSON(tt.status, tt.response)
+
+		tokenService.EXPECT().Logout(gomock.Any(), uint(101), "jti-1", expiresAt, "session-1", tt.refreshToken).
+			Return(tt.logoutError).
+			Times(tt.logoutCallCount)
+
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				tokenService: tokenService,
+			}
+			h.Logout(requestContext)
+		})
+	}
+}
+
+func TestHandler_RefreshTokenWithSessionCookie(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh-2"}
+	tokenService := mocks.NewMockTokenService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	requestContext.EXPECT().GetRawData().Return([]byte(`{"refresh_token": "refresh-1"}`), nil)
+	requestContext.EXPECT().SetSameSite(http.SameSiteStrictMode)
+	requestContext.EXPECT().SetCookie(AccessTokenCookie, "access", 900, "/", "example.com", true, true)
+	requestContext.EXPECT().SetCookie(CSRFTokenCookie, gomock.Any(), 900, "/", "example.com", true, false)
+	requestContext.EXPECT().Header("Authorization", "access")
+	requestContext.EXPECT().JSON(http.StatusOK, tokens)
+	requestContext.EXPECT().ClientIP().Return("10.0.0.1")
+	requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent")
+	tokenService.EXPECT().Refresh(gomock.Any(), "refresh-1", gomock.Any()).Return(tokens, nil)
+
+	h := &Handler{
+		tokenService:  tokenService,
+		sessionCookie: SessionCookie{Enabled: true, Secure: true, Domain: "example.com"},
+	}
+	h.RefreshToken(requestContext)
+}
+
+func TestHandler_ListSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sessions := []models.SessionResponse{
+		{ID: 1, Device: "Firefox", IP: "10.0.0.1", Current: true},
+		{ID: 2, Device: "curl/8.0", IP: "10.0.0.2"},
+	}
+	tests := []struct {
+		name       string
+		listReturn []models.SessionResponse
+		listError  error
+		status     int
+		response   any
+	}{
+		{
+			name:       "Success",
+			listReturn: sessions,
+			status:     http.StatusOK,
+			response:   sessions,
+		},
+		{
+			name:      "Internal Server Error",
+			listError: errors.New("internal Server Error"),
+			status:    http.StatusInternalServerError,
+			response:  newProblem(errors.New("internal Server Error")),
+		},
+	}
+	tokenService := mocks.NewMockTokenService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	for _, tt := range tests {
+		requestContext.EXPECT().MustGet("userID").Return(uint(101))
+		requestContext.EXPECT().MustGet("sessionID").Return("session-1")
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+		requestContext.EXPECT().JSON(tt.status, tt.response)
+		tokenService.EXPECT().ListSessions(gomock.Any(), uint(101), "session-1").Return(tt.listReturn, tt.listError)
+
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				tokenService: tokenService,
+			}
+			h.ListSessions(requestContext)
+		})
+	}
+}
+
+func TestHandler_RevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notFound := apperrors.New(apperrors.CodeNotFound, "session not found")
+	tests := []struct {
+		name            string
+		param           string
+		revokeCallCount int
+		revokeError     error
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			param:           "5",
+			revokeCallCount: 1,
+			status:          http.StatusOK,
+			response:        gin.H{"info": "session revoked"},
+		},
+		{
+			name:            "Unknown session",
+			param:           "5",
+			revokeCallCount: 1,
+			revokeError:     notFound,
+			status:          http.StatusNotFound,
+			response:        newProblem(notFound),
+		},
+		{
+			name:     "Invalid id",
+			param:    "abc",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("session id must be a positive integer")),
+		},
+	}
+	tokenService := mocks.NewMockTokenService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	for _, tt := range tests {
+		requestContext.EXPECT().MustGet("userID").Return(uint(101))
+		requestContext.EXPECT().Param("id").Return(tt.param)
+		requestContext.EXPECT().Set(AuditTargetKey, "5").Times(tt.revokeCallCount)
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+		requestContext.EXPECT().JSON(tt.status, tt.response)
+		tokenService.EXPECT().RevokeSession(gomock.Any(), uint(101), uint(5)).Return(tt.revokeError).Times(tt.revokeCallCount)
+
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				tokenService: tokenService,
+			}
+			h.RevokeSession(requestContext)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+)
+
+// CompleteLogin второй шаг входа: обмен challenge и кода второго фактора на токены
+func (h *Handler) CompleteLogin(c RequestContext) {
+	var req models.LoginChallengeRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	userID, err := h.twoFactorService.CompleteChallenge(requestCtx(c), req.Challenge, req.Code)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditActorKey, userID)
+	h.respondWithTokens(c, userID)
+}
+
+func (h *Handler) EnrollTwoFactor(c RequestContext) {
+	userID := c.MustGet("userID").(uint)
+	enrollment, err := h.twoFactorService.Enroll(requestCtx(c), userID)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) ConfirmTwoFactor(c RequestContext) {
+	var req models.TOTPCodeRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	codes, err := h.twoFactorService.Confirm(requestCtx(c), userID, req.Code)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handler) DisableTwoFactor(c RequestContext) {
+	var req models.TOTPCodeRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	if err := h.twoFactorService.Disable(requestCtx(c), userID, req.Code); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "two-factor authentication disabled"})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_LoginUserWithTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userService := mocks.NewMockUserService(ctrl)
+	twoFactorService := mocks.NewMockTwoFactorService(ctrl)
+	requestContext := mocks.NewMockRequestContext(ctrl)
+	challenge := models.LoginChallengeResponse{TwoFactorRequired: true, Challenge: "challenge", ExpiresIn: 300}
+
+	requestContext.EXPECT().GetRawData().Return([]byte(`{"login": "alice", "password": "password"}`), nil)
+	requestContext.EXPECT().ClientIP().Return("10.0.0.1")
+	requestContext.EXPECT().Set(AuditTargetKey, "alice")
+	requestContext.EXPECT().Set(AuditActorKey, uint(7))
+	requestContext.EXPECT().Set(AuditOutcomeKey, entities.AuditOutcomeChallenge)
+	requestContext.EXPECT().JSON(http.StatusOK, challenge)
+	userService.EXPECT().GetUserByUserName(gomock.Any(), gomock.Any()).
+		Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true}, nil)
+	twoFactorService.EXPECT().CreateChallenge(gomock.Any(), uint(7)).Return(challenge, nil)
+
+	h := &Handler{
+		userService:      userService,
+		twoFactorService: twoFactorService,
+	}
+	h.LoginUser(requestContext)
+}
+
+func TestHandler_CompleteLogin(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+	tests := []struct {
+		name           string
+		completeUserID uint
+		completeError  error
+		issueCallCount int
+		status         int
+		response       any
+	}{
+		{
+			name:           "Success",
+			completeUserID: 7,
+			issueCallCount: 1,
+			status:         http.StatusOK,
+			response:       tokens,
+		},
+		{
+			name:          "Wrong code",
+			completeError: apperrors.ErrInvalidTwoFactorCode,
+			status:        http.StatusUnauthorized,
+			response:      newProblem(apperrors.ErrInvalidTwoFactorCode),
+		},
+		{
+			name:          "Expired challenge",
+			completeError: apperrors.ErrInvalidLoginChallenge,
+			status:        http.StatusUnauthorized,
+			response:      newProblem(apperrors.ErrInvalidLoginChallenge),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			twoFactorService := mocks.NewMockTwoFactorService(ctrl)
+			tokenService := mocks.NewMockTokenService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().GetRawData().Return([]byte(`{"challenge": "challenge", "code": "123456"}`), nil)
+			requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).AnyTimes()
+			requestContext.EXPECT().Set(AuditActorKey, uint(7)).Times(tt.issueCallCount)
+			requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.issueCallCount)
+			requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.issueCallCount)
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			twoFactorService.EXPECT().CompleteChallenge(gomock.Any(), "challenge", "123456").Return(tt.completeUserID, tt.completeError)
+			tokenService.EXPECT().IssueTokens(gomock.Any(), uint(7), dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).Return(tokens, nil).Times(tt.issueCallCount)
+
+			h := &Handler{
+				twoFactorService: twoFactorService,
+				tokenService:     tokenService,
+			}
+			h.CompleteLogin(requestContext)
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
-	"github.com/dgrijalva/jwt-go"
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/keyring"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"io"
+	"net/http"
+	"time"
 )
 
 //go:generate mockgen -destination=mocks/request_context.go -package=mocks . RequestContext
@@ -13,44 +19,179 @@ type RequestContext interface {
 	JSON(code int, obj any)
 	Header(key, value string)
 	MustGet(key string) any
+	Query(key string) string
+	Param(key string) string
+	ContentType() string
+	ClientIP() string
+	GetHeader(key string) string
+	SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool)
+	SetSameSite(sameSite http.SameSite)
+	Set(key string, value any)
+	Stream(step func(w io.Writer) bool) bool
+}
+
+// requestCtx контекст запроса с его логером. Вне gin, например с моками в тестах, - пустой контекст.
+func requestCtx(c RequestContext) context.Context {
+	if ginContext, ok := c.(*gin.Context); ok && ginContext.Request != nil {
+		return ginContext.Request.Context()
+	}
+	return context.Background()
 }
 
 //go:generate mockgen -destination=mocks/user_service.go -package=mocks . UserService
 type UserService interface {
-	SaveUser(userDTO dto.UserDTO) (entities.User, error)
-	GetUserByUserName(userDTO dto.UserDTO) (entities.User, error)
-	GetUserBalance(userID uint) (models.BalanceResponse, error)
+	SaveUser(ctx context.Context, userDTO dto.UserDTO) (entities.User, error)
+	GetUserByUserName(ctx context.Context, userDTO dto.UserDTO) (entities.User, error)
+	GetUserBalance(ctx context.Context, userID uint) (models.BalanceResponse, error)
+	ChangePassword(ctx context.Context, passwordDTO dto.PasswordChangeDTO) error
+	UpdateEmail(ctx context.Context, userID uint, email string) error
+}
+
+//go:generate mockgen -destination=mocks/account_service.go -package=mocks . AccountService
+type AccountService interface {
+	DeleteAccount(ctx context.Context, userID uint) error
+	ExportData(ctx context.Context, userID uint) (models.UserDataExport, error)
+}
+
+//go:generate mockgen -destination=mocks/password_reset_service.go -package=mocks . PasswordResetService
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, login string, ip string) error
+	ConfirmReset(ctx context.Context, token string, newPassword string) (uint, error)
 }
 
 //go:generate mockgen -destination=mocks/order_service.go -package=mocks . OrderService
 type OrderService interface {
-	SaveOrder(orderNumber dto.OrderDTO) (entities.Order, error)
-	GetAllOrders(userID uint) ([]models.AllOrderResponse, error)
+	SaveOrder(ctx context.Context, orderNumber dto.OrderDTO) (dto.OrderUploadResult, error)
+	GetOrdersPage(ctx context.Context, query dto.PageQuery) (models.OrderPage, error)
+	GetOrder(ctx context.Context, userID uint, number string) (models.OrderResponse, error)
+	SaveOrders(ctx context.Context, userID uint, numbers []string) ([]models.BatchOrderResult, error)
 }
 
 //go:generate mockgen -destination=mocks/withdraw_service.go -package=mocks . WithdrawService
 type WithdrawService interface {
-	SaveWithdraw(withdrawDTO dto.WithdrawDTO) error
-	GetAllWithdrawals(userID uint) ([]models.WithdrawResponse, error)
+	SaveWithdraw(ctx context.Context, withdrawDTO dto.WithdrawDTO) error
+	GetWithdrawalsPage(ctx context.Context, query dto.PageQuery) (models.WithdrawPage, error)
+}
+
+//go:generate mockgen -destination=mocks/token_service.go -package=mocks . TokenService
+type TokenService interface {
+	IssueTokens(ctx context.Context, userID uint, client dto.ClientInfo) (models.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string, client dto.ClientInfo) (models.TokenResponse, error)
+	Logout(ctx context.Context, userID uint, accessJTI string, accessExpiresAt time.Time, sessionID string, refreshToken string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID uint, id uint) error
+}
+
+//go:generate mockgen -destination=mocks/admin_service.go -package=mocks . AdminService
+type AdminService interface {
+	SearchUsers(ctx context.Context, query dto.UserSearchQuery) (models.AdminUserPage, error)
+	GetUser(ctx context.Context, userID uint) (models.AdminUserDetailsResponse, error)
+	GetUserBalance(ctx context.Context, userID uint) (models.AdminBalanceResponse, error)
+}
+
+//go:generate mockgen -destination=mocks/audit_service.go -package=mocks . AuditService
+type AuditService interface {
+	SearchEvents(ctx context.Context, query dto.AuditEventQuery) (models.AuditEventPage, error)
+}
+
+//go:generate mockgen -destination=mocks/two_factor_service.go -package=mocks . TwoFactorService
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userID uint) (models.TOTPEnrollmentResponse, error)
+	Confirm(ctx context.Context, userID uint, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint, code string) error
+	CreateChallenge(ctx context.Context, userID uint) (models.LoginChallengeResponse, error)
+	CompleteChallenge(ctx context.Context, challengeToken string, code string) (uint, error)
+}
+
+//go:generate mockgen -destination=mocks/partner_service.go -package=mocks . PartnerService
+type PartnerService interface {
+	CreatePartner(ctx context.Context, name string) (models.PartnerResponse, error)
+	CreateAPIKey(ctx context.Context, keyDTO dto.APIKeyDTO) (models.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, partnerID uint, keyID uint) error
+	LinkUser(ctx context.Context, partnerID uint, externalID string, userID uint) error
+	ResolveUser(ctx context.Context, partnerID uint, externalID string) (uint, error)
+}
+
+//go:generate mockgen -destination=mocks/oidc_service.go -package=mocks . OIDCService
+type OIDCService interface {
+	StartAuthorization(ctx context.Context, linkUserID uint) (models.OIDCAuthorizationResponse, error)
+	CompleteAuthorization(ctx context.Context, code string, state string) (dto.OIDCLoginResult, error)
+}
+
+//go:generate mockgen -destination=mocks/event_service.go -package=mocks . EventService
+type EventService interface {
+	Subscribe(ctx context.Context, userID uint, lastEventID uint) (dto.EventSubscription, error)
+}
+
+//go:generate mockgen -destination=mocks/webhook_service.go -package=mocks . WebhookService
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, subscriptionDTO dto.WebhookSubscriptionDTO) (models.WebhookSubscriptionResponse, error)
+	ListSubscriptions(ctx context.Context, partnerID uint) ([]models.WebhookSubscriptionResponse, error)
+	DeleteSubscription(ctx context.Context, partnerID uint, id uint) error
+	GetDeliveries(ctx context.Context, query dto.WebhookDeliveryQuery) (models.WebhookDeliveryPage, error)
 }
 
-type Claims struct {
-	UserID uint `json:"userID"`
-	jwt.StandardClaims
+type JWKSProvider interface {
+	JWKS() keyring.JSONWebKeySet
 }
 
 type Handler struct {
-	userService     UserService
-	orderService    OrderService
-	withdrawService WithdrawService
-	secret          string
+	userService       UserService
+	orderService      OrderService
+	withdrawService   WithdrawService
+	tokenService      TokenService
+	adminService      AdminService
+	resetService      PasswordResetService
+	twoFactorService  TwoFactorService
+	partnerService    PartnerService
+	accountService    AccountService
+	auditService      AuditService
+	oidcService       OIDCService
+	eventService      EventService
+	webhookService    WebhookService
+	jwks              JWKSProvider
+	apiDocument       *openapi3.T
+	sessionCookie     SessionCookie
+	orderBatchMaxSize int
 }
 
-func NewHandler(userService UserService, oderService OrderService, withdrawService WithdrawService, secret string) *Handler {
+func NewHandler(
+	userService UserService,
+	oderService OrderService,
+	withdrawService WithdrawService,
+	tokenService TokenService,
+	adminService AdminService,
+	resetService PasswordResetService,
+	twoFactorService TwoFactorService,
+	partnerService PartnerService,
+	accountService AccountService,
+	auditService AuditService,
+	oidcService OIDCService,
+	eventService EventService,
+	webhookService WebhookService,
+	jwks JWKSProvider,
+	apiDocument *openapi3.T,
+	sessionCookie SessionCookie,
+	orderBatchMaxSize int,
+) *Handler {
 	return &Handler{
-		userService:     userService,
-		orderService:    oderService,
-		withdrawService: withdrawService,
-		secret:          secret,
+		userService:       userService,
+		orderService:      oderService,
+		withdrawService:   withdrawService,
+		tokenService:      tokenService,
+		adminService:      adminService,
+		resetService:      resetService,
+		twoFactorService:  twoFactorService,
+		partnerService:    partnerService,
+		accountService:    accountService,
+		auditService:      auditService,
+		oidcService:       oidcService,
+		eventService:      eventService,
+		webhookService:    webhookService,
+		jwks:              jwks,
+		apiDocument:       apiDocument,
+		sessionCookie:     sessionCookie,
+		orderBatchMaxSize: orderBatchMaxSize,
 	}
 }
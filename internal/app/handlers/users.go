@@ -1,107 +1,105 @@
 package handlers
 
 import (
-	"encoding/json"
-	"errors"
-	"github.com/dgrijalva/jwt-go"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 	"net/http"
-	"time"
 )
 
 func (h *Handler) RegisterUser(c RequestContext) {
 	var req models.AuthRequest
-	requestBytes, err := c.GetRawData()
-	if err != nil {
-		logger.Log.Infof("error while reading request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while reading request"})
-		return
-	}
-	jsonErr := json.Unmarshal(requestBytes, &req)
-	if jsonErr != nil {
-		logger.Log.Infof("error while marshalling json data: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while marshalling json"})
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
 		return
 	}
-	savedUser, err := h.userService.SaveUser(dto.UserDTO{
+	c.Set(AuditTargetKey, req.Login)
+	savedUser, err := h.userService.SaveUser(requestCtx(c), dto.UserDTO{
 		UserName: req.Login,
 		Password: req.Password,
+		Email:    req.Email,
 	})
 	if err != nil {
-		if err.Error() == "user already exists" {
-			logger.Log.Infof("User already exists: %v", err)
-			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
-			return
-		}
-		logger.Log.Infof("Internal Server Error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		RespondError(c, err)
 		return
 	}
-	token, err := createToken(savedUser.ID, h.secret)
-	if err != nil {
-		logger.Log.Infof("Failed to create token %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create JWT token"})
-		return
-	}
-	c.Header("Authorization", token)
-	c.JSON(http.StatusOK, gin.H{"info": "New user successfully created"})
+	c.Set(AuditActorKey, savedUser.ID)
+	h.respondWithTokens(c, savedUser.ID)
 }
 
 func (h *Handler) LoginUser(c RequestContext) {
 	var req models.AuthRequest
-	requestBytes, err := c.GetRawData()
-	if err != nil {
-		logger.Log.Infof("error while reading request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while reading request"})
-		return
-	}
-	jsonErr := json.Unmarshal(requestBytes, &req)
-	if jsonErr != nil {
-		logger.Log.Infof("error while marshalling json data: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while marshalling json"})
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
 		return
 	}
-	savedUser, err := h.userService.GetUserByUserName(dto.UserDTO{
+	c.Set(AuditTargetKey, req.Login)
+	savedUser, err := h.userService.GetUserByUserName(requestCtx(c), dto.UserDTO{
 		UserName: req.Login,
 		Password: req.Password,
+		IP:       c.ClientIP(),
 	})
 	if err != nil {
-		if err.Error() == "crypto/bcrypt: hashedPassword is not the hash of the given password" {
-			logger.Log.Infof("Wrong password %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		RespondError(c, err)
+		return
+	}
+	c.Set(AuditActorKey, savedUser.ID)
+	h.signIn(c, savedUser.ID, savedUser.TOTPEnabled)
+}
+
+// signIn завершает проверенный вход: выдаёт токены или, если подключён второй фактор,
+// открывает второй шаг входа
+func (h *Handler) signIn(c RequestContext, userID uint, totpEnabled bool) {
+	if totpEnabled {
+		challenge, err := h.twoFactorService.CreateChallenge(requestCtx(c), userID)
+		if err != nil {
+			RespondError(c, err)
 			return
 		}
-		logger.Log.Infof("Failed to get user %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user by username"})
+		c.Set(AuditOutcomeKey, entities.AuditOutcomeChallenge)
+		c.JSON(http.StatusOK, challenge)
 		return
 	}
-	token, err := createToken(savedUser.ID, h.secret)
+	h.respondWithTokens(c, userID)
+}
+
+// ChangePassword меняет пароль, завершает все сессии пользователя и выдаёт новую пару токенов
+func (h *Handler) ChangePassword(c RequestContext) {
+	var req models.PasswordChangeRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	err := h.userService.ChangePassword(requestCtx(c), dto.PasswordChangeDTO{
+		UserID:      userID,
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+		IP:          c.ClientIP(),
+	})
 	if err != nil {
-		logger.Log.Infof("Failed to create token %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create JWT token"})
+		RespondError(c, err)
 		return
 	}
-	c.Header("Authorization", token)
-	c.JSON(http.StatusOK, gin.H{"info": "login successful"})
+	if err := h.tokenService.RevokeAllForUser(requestCtx(c), userID); err != nil {
+		RespondError(c, fmt.Errorf("failed to revoke sessions: %w", err))
+		return
+	}
+	h.respondWithTokens(c, userID)
 }
 
-func createToken(userID uint, secret string) (string, error) {
-	if userID == 0 || len(secret) == 0 {
-		return "", errors.New("invalid token credentials")
-	}
-	claims := Claims{
-		UserID: userID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(), // Токен действителен в течение 24 часов
-		},
+func (h *Handler) UpdateEmail(c RequestContext) {
+	var req models.EmailRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
+	userID := c.MustGet("userID").(uint)
+	if err := h.userService.UpdateEmail(requestCtx(c), userID, req.Email); err != nil {
+		RespondError(c, err)
+		return
 	}
-	return tokenString, nil
+	c.JSON(http.StatusOK, gin.H{"info": "email updated"})
 }
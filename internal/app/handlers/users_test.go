@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"errors"
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
 	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 	"gorm.io/gorm"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestHandler_RegisterUser(t *testing.T) {
@@ -21,6 +24,8 @@ func TestHandler_RegisterUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+
 	tests := []struct {
 		name              string
 		getRowDataReturn  []byte
@@ -28,10 +33,11 @@ func TestHandler_RegisterUser(t *testing.T) {
 		saveUserReturn    entities.User
 		saveUserError     error
 		saveUserCallCount int
+		issueTokensError  error
+		issueCallCount    int
 		headerCallCount   int
 		status            int
-		response          gin.H
-		secret            string
+		response          any
 	}{
 		{
 			name:             "Success",
@@ -49,8 +55,8 @@ func TestHandler_RegisterUser(t *testing.T) {
 			saveUserCallCount: 1,
 			headerCallCount:   1,
 			status:            http.StatusOK,
-			response:          gin.H{"info": "New user successfully created"},
-			secret:            "secret",
+			issueCallCount:    1,
+			response:          tokens,
 		},
 		{
 			name:              "Error while reading request",
@@ -59,10 +65,9 @@ func TestHandler_RegisterUser(t *testing.T) {
 			saveUserReturn:    entities.User{},
 			saveUserError:     nil,
 			saveUserCallCount: 0,
-			headerCallCount:   0,
+			headerCallCount:   1,
 			status:            http.StatusBadRequest,
-			response:          gin.H{"error": "Error while reading request"},
-			secret:            "secret",
+			response:          newProblem(apperrors.InvalidRequest("error while reading request")),
 		},
 		{
 			name:              "Error while marshalling json",
@@ -71,21 +76,20 @@ func TestHandler_RegisterUser(t *testing.T) {
 			saveUserReturn:    entities.User{},
 			saveUserError:     nil,
 			saveUserCallCount: 0,
-			headerCallCount:   0,
+			headerCallCount:   1,
 			status:            http.StatusBadRequest,
-			response:          gin.H{"error": "Error while marshalling json"},
-			secret:            "secret",
+			response:          newProblem(apperrors.InvalidRequest("malformed JSON")),
 		},
 		{
 			name:              "User already exists",
 			getRowDataReturn:  []byte("{\n\t\"login\": \"Admin\",\n\t\"password\": \"<password>\"\n}"),
 			getRowDataError:   nil,
 			saveUserReturn:    entities.User{},
-			saveUserError:     errors.New("user already exists"),
+			saveUserError:     apperrors.ErrUserAlreadyExists,
 			saveUserCallCount: 1,
-			headerCallCount:   0,
+			headerCallCount:   1,
 			status:            http.StatusConflict,
-			response:          gin.H{"error": "User already exists"},
+			response:          newProblem(apperrors.ErrUserAlreadyExists),
 		},
 		{
 			name:              "Internal Server Error",
@@ -94,9 +98,9 @@ func TestHandler_RegisterUser(t *testing.T) {
 			saveUserReturn:    entities.User{},
 			saveUserError:     errors.New("internal Server Error"),
 			saveUserCallCount: 1,
-			headerCallCount:   0,
+			headerCallCount:   1,
 			status:            http.StatusInternalServerError,
-			response:          gin.H{"error": "Internal Server Error"},
+			response:          newProblem(errors.New("internal Server Error")),
 		},
 		{
 			name:              "Create JWT Error",
@@ -105,26 +109,34 @@ func TestHandler_RegisterUser(t *testing.T) {
 			saveUserReturn:    entities.User{UserName: "Admin", Password: "hashed_password"},
 			saveUserError:     nil,
 			saveUserCallCount: 1,
-			headerCallCount:   0,
+			issueTokensError:  errors.New("invalid token credentials"),
+			issueCallCount:    1,
+			headerCallCount:   1,
 			status:            http.StatusInternalServerError,
-			response:          gin.H{"error": "failed to create JWT token"},
-			secret:            "",
+			response:          newProblem(errors.New("failed to create JWT token")),
 		},
 	}
 	userService := mocks.NewMockUserService(ctrl)
+	tokenService := mocks.NewMockTokenService(ctrl)
 	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
 		requestContext.EXPECT().GetRawData().Return(tt.getRowDataReturn, tt.getRowDataError)
 		requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).Times(tt.headerCallCount)
 		requestContext.EXPECT().JSON(tt.status, tt.response)
+		requestContext.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
+		requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.issueCallCount)
+		requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.issueCallCount)
 
-		userService.EXPECT().SaveUser(gomock.Any()).
+		userService.EXPECT().SaveUser(gomock.Any(), gomock.Any()).
 			Return(tt.saveUserReturn, tt.saveUserError).
 			Times(tt.saveUserCallCount)
+		tokenService.EXPECT().IssueTokens(gomock.Any(), tt.saveUserReturn.ID, dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).
+			Return(tokens, tt.issueTokensError).
+			Times(tt.issueCallCount)
 		t.Run(tt.name, func(t *testing.T) {
 			h := &Handler{
-				userService: userService,
-				secret:      tt.secret,
+				userService:  userService,
+				tokenService: tokenService,
 			}
 			h.RegisterUser(requestContext)
 		})
@@ -140,21 +152,23 @@ func TestHandler_LoginUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+
 	tests := []struct {
 		name             string
-		secret           string
 		getRowDataReturn []byte
 		getRowDataError  error
 		getUserReturn    entities.User
 		getUserError     error
 		getUserCallCount int
+		issueTokensError error
+		issueCallCount   int
 		headerCallCount  int
 		status           int
-		response         gin.H
+		response         any
 	}{
 		{
 			name:             "Success",
-			secret:           "secret",
 			getRowDataReturn: []byte("{\n\t\"login\": \"Admin\",\n\t\"password\": \"<password>\"\n}"),
 			getRowDataError:  nil,
 			getUserReturn: entities.User{
@@ -169,7 +183,8 @@ func TestHandler_LoginUser(t *testing.T) {
 			getUserCallCount: 1,
 			headerCallCount:  1,
 			status:           http.StatusOK,
-			response:         gin.H{"info": "login successful"},
+			issueCallCount:   1,
+			response:         tokens,
 		},
 		{
 			name:             "Error while reading request",
@@ -178,10 +193,9 @@ func TestHandler_LoginUser(t *testing.T) {
 			getUserReturn:    entities.User{},
 			getUserError:     nil,
 			getUserCallCount: 0,
-			headerCallCount:  0,
+			headerCallCount:  1,
 			status:           http.StatusBadRequest,
-			response:         gin.H{"error": "Error while reading request"},
-			secret:           "secret",
+			response:         newProblem(apperrors.InvalidRequest("error while reading request")),
 		},
 		{
 			name:             "Error while marshalling json",
@@ -190,21 +204,31 @@ func TestHandler_LoginUser(t *testing.T) {
 			getUserReturn:    entities.User{},
 			getUserError:     nil,
 			getUserCallCount: 0,
-			headerCallCount:  0,
+			headerCallCount:  1,
 			status:           http.StatusBadRequest,
-			response:         gin.H{"error": "Error while marshalling json"},
-			secret:           "secret",
+			response:         newProblem(apperrors.InvalidRequest("malformed JSON")),
 		},
 		{
 			name:             "Wrong password",
 			getRowDataReturn: []byte("{\n\t\"login\": \"Admin\",\n\t\"password\": \"<password>\"\n}"),
 			getRowDataError:  nil,
 			getUserReturn:    entities.User{},
-			getUserError:     errors.New("crypto/bcrypt: hashedPassword is not the hash of the given password"),
+			getUserError:     apperrors.ErrInvalidCredentials,
 			getUserCallCount: 1,
-			headerCallCount:  0,
+			headerCallCount:  1,
 			status:           http.StatusUnauthorized,
-			response:         gin.H{"error": "wrong password"},
+			response:         newProblem(apperrors.ErrInvalidCredentials),
+		},
+		{
+			name:             "Too many attempts",
+			getRowDataReturn: []byte("{\n\t\"login\": \"Admin\",\n\t\"password\": \"<password>\"\n}"),
+			getRowDataError:  nil,
+			getUserReturn:    entities.User{},
+			getUserError:     apperrors.TooManyAttempts(90 * time.Second),
+			getUserCallCount: 1,
+			headerCallCount:  2,
+			status:           http.StatusTooManyRequests,
+			response:         newProblem(apperrors.TooManyAttempts(90 * time.Second)),
 		},
 		{
 			name:             "Internal Server Error",
@@ -213,9 +237,9 @@ func TestHandler_LoginUser(t *testing.T) {
 			getUserReturn:    entities.User{},
 			getUserError:     errors.New("internal Server Error"),
 			getUserCallCount: 1,
-			headerCallCount:  0,
+			headerCallCount:  1,
 			status:           http.StatusInternalServerError,
-			response:         gin.H{"error": "failed to get user by username"},
+			response:         newProblem(errors.New("internal Server Error")),
 		},
 		{
 			name:             "Create JWT Error",
@@ -224,29 +248,132 @@ func TestHandler_LoginUser(t *testing.T) {
 			getUserReturn:    entities.User{UserName: "Admin", Password: "hashed_password"},
 			getUserError:     nil,
 			getUserCallCount: 1,
-			headerCallCount:  0,
+			issueTokensError: errors.New("invalid token credentials"),
+			issueCallCount:   1,
+			headerCallCount:  1,
 			status:           http.StatusInternalServerError,
-			response:         gin.H{"error": "failed to create JWT token"},
-			secret:           "",
+			response:         newProblem(errors.New("failed to create JWT token")),
 		},
 	}
 	userService := mocks.NewMockUserService(ctrl)
+	tokenService := mocks.NewMockTokenService(ctrl)
 	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
 		requestContext.EXPECT().GetRawData().Return(tt.getRowDataReturn, tt.getRowDataError)
 		requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).Times(tt.headerCallCount)
 		requestContext.EXPECT().JSON(tt.status, tt.response)
+		requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.getUserCallCount + tt.issueCallCount)
+		requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.issueCallCount)
+		requestContext.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
 
-		userService.EXPECT().GetUserByUserName(gomock.Any()).
+		userService.EXPECT().GetUserByUserName(gomock.Any(), dto.UserDTO{UserName: "Admin", Password: "<password>", IP: "10.0.0.1"}).
 			Return(tt.getUserReturn, tt.getUserError).
 			Times(tt.getUserCallCount)
+		tokenService.EXPECT().IssueTokens(gomock.Any(), tt.getUserReturn.ID, dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).
+			Return(tokens, tt.issueTokensError).
+			Times(tt.issueCallCount)
 
 		t.Run(tt.name, func(t *testing.T) {
 			h := &Handler{
-				userService: userService,
-				secret:      tt.secret,
+				userService:  userService,
+				tokenService: tokenService,
 			}
 			h.LoginUser(requestContext)
 		})
 	}
 }
+
+func TestHandler_ChangePassword(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := models.TokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+	weak := apperrors.ValidationFailed([]string{"password must be at least 8 characters"})
+	body := []byte(`{"old_password": "old password 1", "new_password": "new password 2"}`)
+
+	tests := []struct {
+		name            string
+		body            []byte
+		changeCallCount int
+		changeError     error
+		revokeCallCount int
+		revokeError     error
+		issueCallCount  int
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			body:            body,
+			changeCallCount: 1,
+			revokeCallCount: 1,
+			issueCallCount:  1,
+			status:          http.StatusOK,
+			response:        tokens,
+		},
+		{
+			name:            "Weak password",
+			body:            body,
+			changeCallCount: 1,
+			changeError:     weak,
+			status:          http.StatusBadRequest,
+			response:        newProblem(weak),
+		},
+		{
+			name:            "Wrong old password",
+			body:            body,
+			changeCallCount: 1,
+			changeError:     apperrors.ErrInvalidCredentials,
+			status:          http.StatusUnauthorized,
+			response:        newProblem(apperrors.ErrInvalidCredentials),
+		},
+		{
+			name:            "Revoke error",
+			body:            body,
+			changeCallCount: 1,
+			revokeCallCount: 1,
+			revokeError:     errors.New("db is down"),
+			status:          http.StatusInternalServerError,
+			response:        newProblem(errors.New("db is down")),
+		},
+		{
+			name:     "Malformed JSON",
+			body:     []byte("WRONG JSON STRING"),
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("malformed JSON")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			userService := mocks.NewMockUserService(ctrl)
+			tokenService := mocks.NewMockTokenService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().GetRawData().Return(tt.body, nil)
+			requestContext.EXPECT().MustGet("userID").Return(uint(7)).Times(tt.changeCallCount)
+			requestContext.EXPECT().ClientIP().Return("10.0.0.1").Times(tt.changeCallCount + tt.issueCallCount)
+			requestContext.EXPECT().GetHeader("User-Agent").Return("test-agent").Times(tt.issueCallCount)
+			requestContext.EXPECT().Header(gomock.Any(), gomock.Any()).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+
+			userService.EXPECT().ChangePassword(gomock.Any(), dto.PasswordChangeDTO{
+				UserID:      7,
+				OldPassword: "old password 1",
+				NewPassword: "new password 2",
+				IP:          "10.0.0.1",
+			}).Return(tt.changeError).Times(tt.changeCallCount)
+			tokenService.EXPECT().RevokeAllForUser(gomock.Any(), uint(7)).Return(tt.revokeError).Times(tt.revokeCallCount)
+			tokenService.EXPECT().IssueTokens(gomock.Any(), uint(7), dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"}).Return(tokens, nil).Times(tt.issueCallCount)
+
+			h := &Handler{
+				userService:  userService,
+				tokenService: tokenService,
+			}
+			h.ChangePassword(requestContext)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"strconv"
+)
+
+// CreateWebhookSubscription подписывает адрес партнёра на события его клиентов.
+// Секрет подписи показывается один раз.
+func (h *Handler) CreateWebhookSubscription(c RequestContext) {
+	var req models.WebhookSubscriptionRequest
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
+		return
+	}
+	subscription, err := h.webhookService.CreateSubscription(requestCtx(c), dto.WebhookSubscriptionDTO{
+		PartnerID:  c.MustGet("partnerID").(uint),
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (h *Handler) ListWebhookSubscriptions(c RequestContext) {
+	subscriptions, err := h.webhookService.ListSubscriptions(requestCtx(c), c.MustGet("partnerID").(uint))
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (h *Handler) DeleteWebhookSubscription(c RequestContext) {
+	subscriptionID, err := parseIDParam(c, "id", "subscription id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if err := h.webhookService.DeleteSubscription(requestCtx(c), c.MustGet("partnerID").(uint), subscriptionID); err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"info": "webhook subscription deleted"})
+}
+
+// GetWebhookDeliveries журнал доставок по подписке, от новых к старым, с параметрами limit и cursor
+func (h *Handler) GetWebhookDeliveries(c RequestContext) {
+	subscriptionID, err := parseIDParam(c, "id", "subscription id")
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	query := dto.WebhookDeliveryQuery{
+		PartnerID:      c.MustGet("partnerID").(uint),
+		SubscriptionID: subscriptionID,
+		Limit:          defaultPageLimit,
+		Cursor:         c.Query("cursor"),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			RespondError(c, apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit))
+			return
+		}
+		query.Limit = limit
+	}
+	page, err := h.webhookService.GetDeliveries(requestCtx(c), query)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(page.Items) == 0 {
+		c.JSON(http.StatusNoContent, gin.H{"error": "webhook deliveries not found"})
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_CreateWebhookSubscription(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := models.WebhookSubscriptionResponse{
+		ID:         4,
+		URL:        "https://shop.example/hooks",
+		EventTypes: []string{"order.processed"},
+		Secret:     "generated-secret",
+	}
+	invalid := apperrors.ValidationFailed([]string{"url must be an absolute http or https URL"})
+	tests := []struct {
+		name            string
+		body            string
+		createCallCount int
+		createError     error
+		status          int
+		response        any
+	}{
+		{
+			name:            "Success",
+			body:            `{"url": "https://shop.example/hooks", "event_types": ["order.processed"]}`,
+			createCallCount: 1,
+			status:          http.StatusCreated,
+			response:        subscription,
+		},
+		{
+			name:            "Invalid subscription",
+			body:            `{"url": "https://shop.example/hooks", "event_types": ["order.processed"]}`,
+			createCallCount: 1,
+			createError:     invalid,
+			status:          http.StatusBadRequest,
+			response:        newProblem(invalid),
+		},
+		{
+			name:     "Malformed JSON",
+			body:     `{"url":`,
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("malformed JSON")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			webhookService := mocks.NewMockWebhookService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().GetRawData().Return([]byte(tt.body), nil)
+			requestContext.EXPECT().MustGet("partnerID").Return(uint(3)).Times(tt.createCallCount)
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			webhookService.EXPECT().CreateSubscription(gomock.Any(), dto.WebhookSubscriptionDTO{
+				PartnerID:  3,
+				URL:        "https://shop.example/hooks",
+				EventTypes: []string{"order.processed"},
+			}).Return(subscription, tt.createError).Times(tt.createCallCount)
+
+			h := &Handler{
+				webhookService: webhookService,
+			}
+			h.CreateWebhookSubscription(requestContext)
+		})
+	}
+}
+
+func TestHandler_GetWebhookDeliveries(t *testing.T) {
+	err := logger.Initialize("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := []models.WebhookDeliveryResponse{
+		{ID: 9, EventType: "order.processed", Status: "delivered", Attempts: 2, ResponseStatus: 200},
+	}
+	notFound := apperrors.Newf(apperrors.CodeNotFound, "webhook subscription %d not found", 4)
+	tests := []struct {
+		name          string
+		id            string
+		limit         string
+		cursor        string
+		query         dto.WebhookDeliveryQuery
+		callCount     int
+		page          models.WebhookDeliveryPage
+		getError      error
+		status        int
+		response      any
+		nextCursorSet bool
+	}{
+		{
+			name:          "Page with next cursor",
+			id:            "4",
+			limit:         "1",
+			query:         dto.WebhookDeliveryQuery{PartnerID: 3, SubscriptionID: 4, Limit: 1},
+			callCount:     1,
+			page:          models.WebhookDeliveryPage{Items: deliveries, NextCursor: "next"},
+			status:        http.StatusOK,
+			response:      deliveries,
+			nextCursorSet: true,
+		},
+		{
+			name:      "No deliveries",
+			id:        "4",
+			cursor:    "c",
+			query:     dto.WebhookDeliveryQuery{PartnerID: 3, SubscriptionID: 4, Limit: defaultPageLimit, Cursor: "c"},
+			callCount: 1,
+			status:    http.StatusNoContent,
+			response:  gin.H{"error": "webhook deliveries not found"},
+		},
+		{
+			name:      "Subscription of another partner",
+			id:        "4",
+			query:     dto.WebhookDeliveryQuery{PartnerID: 3, SubscriptionID: 4, Limit: defaultPageLimit},
+			callCount: 1,
+			getError:  notFound,
+			status:    http.StatusNotFound,
+			response:  newProblem(notFound),
+		},
+		{
+			name:     "Invalid limit",
+			id:       "4",
+			limit:    "0",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("limit must be between 1 and %d", maxPageLimit)),
+		},
+		{
+			name:     "Invalid subscription id",
+			id:       "x",
+			status:   http.StatusBadRequest,
+			response: newProblem(apperrors.InvalidRequest("subscription id must be a positive integer")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			webhookService := mocks.NewMockWebhookService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+			requestContext.EXPECT().Param("id").Return(tt.id)
+			requestContext.EXPECT().MustGet("partnerID").Return(uint(3)).AnyTimes()
+			requestContext.EXPECT().Query("cursor").Return(tt.cursor).AnyTimes()
+			requestContext.EXPECT().Query("limit").Return(tt.limit).AnyTimes()
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			if tt.nextCursorSet {
+				requestContext.EXPECT().Header(nextCursorHeader, "next")
+			}
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+			webhookService.EXPECT().GetDeliveries(gomock.Any(), tt.query).Return(tt.page, tt.getError).Times(tt.callCount)
+
+			h := &Handler{
+				webhookService: webhookService,
+			}
+			h.GetWebhookDeliveries(requestContext)
+		})
+	}
+}
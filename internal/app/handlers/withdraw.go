@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
@@ -11,49 +10,43 @@ import (
 
 func (h *Handler) SaveWithdraw(c RequestContext) {
 	var req models.WithdrawRequest
-	requestBytes, err := c.GetRawData()
-	if err != nil {
-		logger.Log.Infof("error while reading request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while reading request"})
-		return
-	}
-	jsonErr := json.Unmarshal(requestBytes, &req)
-	if jsonErr != nil {
-		logger.Log.Infof("error while marshalling json data: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error while marshalling json"})
+	if err := readJSON(c, &req); err != nil {
+		RespondError(c, err)
 		return
 	}
 	req.UserID = c.MustGet("userID").(uint)
-	err = h.withdrawService.SaveWithdraw(dto.WithdrawDTO{
+	c.Set(AuditTargetKey, req.Order)
+	err := h.withdrawService.SaveWithdraw(requestCtx(c), dto.WithdrawDTO{
 		OrderNumber: req.Order,
 		Sum:         req.Sum,
 		UserID:      req.UserID,
+		TOTPCode:    req.TOTPCode,
 	})
 	if err != nil {
-		if err.Error() == "not enough funds" {
-			logger.Log.Infof("not enough funds: %v", err)
-			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Not enough funds"})
-			return
-		}
-		logger.Log.Infof("error while saving withdraw: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while saving withdraw"})
+		RespondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"info": "Withdrawal successfully saved"})
 }
 
 func (h *Handler) GetAllWithdrawals(c RequestContext) {
-	userID := c.MustGet("userID").(uint)
-	withdrawals, err := h.withdrawService.GetAllWithdrawals(userID)
+	query, err := parsePageQuery(c, false)
 	if err != nil {
-		logger.Log.Infof("Internal server error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		RespondError(c, err)
 		return
 	}
-	if len(withdrawals) == 0 {
-		logger.Log.Infof("Withdrawals are empty")
+	page, err := h.withdrawService.GetWithdrawalsPage(requestCtx(c), query)
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	if len(page.Items) == 0 {
+		logger.FromContext(requestCtx(c)).Infof("Withdrawals are empty")
 		c.JSON(http.StatusNoContent, gin.H{"error": "withdrawal not found"})
 		return
 	}
-	c.JSON(http.StatusOK, withdrawals)
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
 }
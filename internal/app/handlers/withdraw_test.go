@@ -4,8 +4,10 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
 	"github.com/keyjin88/go-loyalty-system/internal/app/handlers/mocks"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
 	"net/http"
 	"testing"
@@ -30,7 +32,7 @@ func TestHandler_SaveWithdraw(t *testing.T) {
 		saveWithdrawError     error
 		saveWithdrawCallCount int
 		status                int
-		response              gin.H
+		response              any
 	}{
 		{
 			name:                  "Success",
@@ -52,7 +54,7 @@ func TestHandler_SaveWithdraw(t *testing.T) {
 			saveWithdrawError:     nil,
 			saveWithdrawCallCount: 0,
 			status:                http.StatusBadRequest,
-			response:              gin.H{"error": "Error while reading request"},
+			response:              newProblem(apperrors.InvalidRequest("error while reading request")),
 		},
 		{
 			name:                  "Error while marshalling json",
@@ -63,7 +65,7 @@ func TestHandler_SaveWithdraw(t *testing.T) {
 			saveWithdrawError:     nil,
 			saveWithdrawCallCount: 0,
 			status:                http.StatusBadRequest,
-			response:              gin.H{"error": "Error while marshalling json"},
+			response:              newProblem(apperrors.InvalidRequest("malformed JSON")),
 		},
 		{
 			name:                  "Not enough funds",
@@ -71,10 +73,10 @@ func TestHandler_SaveWithdraw(t *testing.T) {
 			getRowDataError:       nil,
 			mustGetReturn:         101,
 			mustGetCallCount:      1,
-			saveWithdrawError:     errors.New("not enough funds"),
+			saveWithdrawError:     apperrors.ErrInsufficientFunds,
 			saveWithdrawCallCount: 1,
 			status:                http.StatusPaymentRequired,
-			response:              gin.H{"error": "Not enough funds"},
+			response:              newProblem(apperrors.ErrInsufficientFunds),
 		},
 		{
 			name:                  "Error while saving withdraw",
@@ -85,19 +87,21 @@ func TestHandler_SaveWithdraw(t *testing.T) {
 			saveWithdrawError:     errors.New("error while saving withdraw"),
 			saveWithdrawCallCount: 1,
 			status:                http.StatusInternalServerError,
-			response:              gin.H{"error": "Error while saving withdraw"},
+			response:              newProblem(errors.New("error while saving withdraw")),
 		},
 	}
 	withdrawService := mocks.NewMockWithdrawService(ctrl)
 	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
-		withdrawService.EXPECT().SaveWithdraw(gomock.Any()).
+		withdrawService.EXPECT().SaveWithdraw(gomock.Any(), gomock.Any()).
 			Return(tt.saveWithdrawError).
 			Times(tt.saveWithdrawCallCount)
 		requestContext.EXPECT().MustGet("userID").
 			Return(tt.mustGetReturn).
 			Times(tt.mustGetCallCount)
 		requestContext.EXPECT().GetRawData().Return(tt.getRowDataReturn, tt.getRowDataError)
+		requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+		requestContext.EXPECT().Set(AuditTargetKey, "2377225626").Times(tt.mustGetCallCount)
 		requestContext.EXPECT().JSON(tt.status, tt.response)
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -115,9 +119,6 @@ func TestHandler_GetAllWithdrawals(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
 	now := time.Now()
 	withdrawals := []models.WithdrawResponse{
 		{
@@ -127,54 +128,81 @@ func TestHandler_GetAllWithdrawals(t *testing.T) {
 			ProcessedAt:   now.Format(time.RFC3339),
 		},
 	}
+	maxAmount := 500.0
 
 	tests := []struct {
-		name                    string
-		mustGetReturn           uint
-		mustGetCallCount        int
-		getAllWithdrawalsReturn []models.WithdrawResponse
-		getAllWithdrawalsError  error
-		status                  int
-		response                any
+		name             string
+		queryParams      map[string]string
+		pageQuery        dto.PageQuery
+		getPageCallCount int
+		getPageReturn    models.WithdrawPage
+		getPageError     error
+		nextCursorHeader string
+		status           int
+		response         any
 	}{
 		{
-			name:                    "Success",
-			mustGetReturn:           101,
-			mustGetCallCount:        1,
-			getAllWithdrawalsReturn: withdrawals,
-			getAllWithdrawalsError:  nil,
-			status:                  http.StatusOK,
-			response:                withdrawals,
+			name:             "Success",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageReturn:    models.WithdrawPage{Items: withdrawals, NextCursor: "next"},
+			nextCursorHeader: "next",
+			status:           http.StatusOK,
+			response:         withdrawals,
+		},
+		{
+			name:             "Status filter is ignored",
+			queryParams:      map[string]string{"status": "NEW", "max_amount": "500"},
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit, MaxAmount: &maxAmount},
+			getPageCallCount: 1,
+			getPageReturn:    models.WithdrawPage{Items: withdrawals},
+			status:           http.StatusOK,
+			response:         withdrawals,
+		},
+		{
+			name:             "Internal server error",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageError:     errors.New("internal server error"),
+			status:           http.StatusInternalServerError,
+			response:         newProblem(errors.New("internal server error")),
 		},
 		{
-			name:                    "Internal server error",
-			mustGetReturn:           101,
-			mustGetCallCount:        1,
-			getAllWithdrawalsReturn: withdrawals,
-			getAllWithdrawalsError:  errors.New("internal server error"),
-			status:                  http.StatusInternalServerError,
-			response:                gin.H{"error": "Internal server error"},
+			name:             "Withdrawals are empty",
+			pageQuery:        dto.PageQuery{UserID: 101, Limit: defaultPageLimit},
+			getPageCallCount: 1,
+			getPageReturn:    models.WithdrawPage{Items: []models.WithdrawResponse{}},
+			status:           http.StatusNoContent,
+			response:         gin.H{"error": "withdrawal not found"},
 		},
 		{
-			name:                    "Withdrawals are empty",
-			mustGetReturn:           101,
-			mustGetCallCount:        1,
-			getAllWithdrawalsReturn: []models.WithdrawResponse{},
-			getAllWithdrawalsError:  nil,
-			status:                  http.StatusNoContent,
-			response:                gin.H{"error": "withdrawal not found"},
+			name:        "Invalid date",
+			queryParams: map[string]string{"to": "yesterday"},
+			status:      http.StatusBadRequest,
+			response:    newProblem(apperrors.InvalidRequest("to must be an RFC 3339 timestamp")),
 		},
 	}
-	withdrawService := mocks.NewMockWithdrawService(ctrl)
-	requestContext := mocks.NewMockRequestContext(ctrl)
 	for _, tt := range tests {
-		requestContext.EXPECT().MustGet(gomock.Any()).
-			Return(tt.mustGetReturn).
-			Times(tt.mustGetCallCount)
-		requestContext.EXPECT().JSON(tt.status, tt.response)
-		withdrawService.EXPECT().GetAllWithdrawals(tt.mustGetReturn).
-			Return(tt.getAllWithdrawalsReturn, tt.getAllWithdrawalsError)
 		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			withdrawService := mocks.NewMockWithdrawService(ctrl)
+			requestContext := mocks.NewMockRequestContext(ctrl)
+
+			requestContext.EXPECT().MustGet("userID").Return(uint(101))
+			requestContext.EXPECT().Query(gomock.Any()).DoAndReturn(func(key string) string {
+				return tt.queryParams[key]
+			}).AnyTimes()
+			if tt.nextCursorHeader != "" {
+				requestContext.EXPECT().Header(nextCursorHeader, tt.nextCursorHeader)
+			}
+			requestContext.EXPECT().Header("Content-Type", problemContentType).AnyTimes()
+			requestContext.EXPECT().JSON(tt.status, tt.response)
+
+			withdrawService.EXPECT().GetWithdrawalsPage(gomock.Any(), tt.pageQuery).
+				Return(tt.getPageReturn, tt.getPageError).
+				Times(tt.getPageCallCount)
+
 			h := &Handler{
 				withdrawService: withdrawService,
 			}
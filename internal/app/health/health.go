@@ -0,0 +1,224 @@
+// Package health отвечает на проверки живости и готовности: /healthz говорит, что процесс жив,
+// /readyz проверяет зависимости и снимает готовность на время запуска и остановки.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояния готовности в ответе /readyz
+const (
+	StatusReady        = "ready"
+	StatusDegraded     = "degraded"
+	StatusNotReady     = "not_ready"
+	StatusStarting     = "starting"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Результаты отдельных проверок
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+)
+
+// Check проверка зависимости. Run возвращает пояснение для ответа, например версию схемы,
+// или ошибку, если зависимость недоступна.
+type Check struct {
+	Name string
+	// Critical неуспешная критичная проверка снимает готовность, остальные только видны в ответе
+	Critical bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+type Result struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker набор проверок готовности. Проверки добавляются по мере запуска зависимостей,
+// до вызова Started приложение считается запускающимся.
+type Checker struct {
+	timeout      time.Duration
+	mutex        sync.RWMutex
+	checks       []Check
+	started      atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// NewChecker создаёт набор проверок, каждая из которых должна уложиться в timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) AddCheck(check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks = append(c.checks, check)
+}
+
+// Started отмечает, что зависимости настроены и приложение принимает запросы
+func (c *Checker) Started() {
+	c.started.Store(true)
+}
+
+// ShuttingDown снимает готовность перед остановкой, чтобы балансировщик перестал слать запросы
+func (c *Checker) ShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready выполняет проверки параллельно и сводит их результаты. Второе значение - готово ли
+// приложение принимать запросы.
+func (c *Checker) Ready(ctx context.Context) (Report, bool) {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}, false
+	}
+	if !c.started.Load() {
+		return Report{Status: StatusStarting}, false
+	}
+	c.mutex.RLock()
+	checks := append([]Check(nil), c.checks...)
+	c.mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(checks))}
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		if result.Status == CheckOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	return report, report.Status != StatusNotReady
+}
+
+func run(ctx context.Context, check Check) Result {
+	start := time.Now()
+	result := Result{Status: CheckOK, Critical: check.Critical}
+	detail, err := check.Run(ctx)
+	result.Detail = detail
+	result.Duration = time.Since(start).String()
+	if err != nil {
+		result.Status = CheckFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler отвечает 200, пока процесс способен обслуживать HTTP, зависимости не проверяются
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(r.Context(), w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// ReadinessHandler отвечает 200 с разбивкой по проверкам, если приложение готово, иначе 503
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, ready := c.Ready(r.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(r.Context(), w, status, report)
+	}
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.FromContext(ctx).Errorf("failed to write health response: %v", err)
+	}
+}
+
+// DatabaseCheck проверяет соединение с БД
+func DatabaseCheck(db *sql.DB) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			if err := db.PingContext(ctx); err != nil {
+				return "", err
+			}
+			stats := db.Stats()
+			return fmt.Sprintf("%d/%d connections in use", stats.InUse, stats.OpenConnections), nil
+		},
+	}
+}
+
+// QueueCheck снимает готовность, когда очередь заказов на обработку заполнена на maxSaturation и больше:
+// новые заказы будут ждать места в очереди вместе с запросом
+func QueueCheck(queue chan dto.OrderTask, maxSaturation float64) Check {
+	return Check{
+		Name:     "order_queue",
+		Critical: true,
+		Run: func(context.Context) (string, error) {
+			depth, capacity := len(queue), cap(queue)
+			detail := fmt.Sprintf("%d/%d", depth, capacity)
+			if capacity > 0 && float64(depth)/float64(capacity) >= maxSaturation {
+				return detail, fmt.Errorf("order queue is saturated")
+			}
+			return detail, nil
+		},
+	}
+}
+
+// ReachabilityCheck проверяет, что сервис по url отвечает. Любой ответ, кроме 5xx, считается успехом:
+// важно, что сервис доступен, а не что у него есть этот путь. Проверка не критичная - без внешнего
+// сервиса заказы принимаются и будут обработаны позже.
+func ReachabilityCheck(name string, url string, client *http.Client) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) (string, error) {
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return "", err
+			}
+			resp, err := client.Do(request)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			detail := fmt.Sprintf("status %d", resp.StatusCode)
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return detail, fmt.Errorf("unexpected response status %s", resp.Status)
+			}
+			return detail, nil
+		},
+	}
+}
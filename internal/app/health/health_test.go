@@ -0,0 +1,168 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func check(name string, critical bool, err error) Check {
+	return Check{
+		Name:     name,
+		Critical: critical,
+		Run:      func(context.Context) (string, error) { return "detail", err },
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	failure := errors.New("unavailable")
+	tests := []struct {
+		name           string
+		checks         []Check
+		started        bool
+		shuttingDown   bool
+		expectedCode   int
+		expectedStatus string
+	}{
+		{
+			name:           "starting",
+			checks:         []Check{check("database", true, nil)},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: StatusStarting,
+		},
+		{
+			name:           "ready",
+			checks:         []Check{check("database", true, nil), check("accrual", false, nil)},
+			started:        true,
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusReady,
+		},
+		{
+			name:           "non-critical failure",
+			checks:         []Check{check("database", true, nil), check("accrual", false, failure)},
+			started:        true,
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusDegraded,
+		},
+		{
+			name:           "critical failure",
+			checks:         []Check{check("database", true, failure), check("accrual", false, failure)},
+			started:        true,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: StatusNotReady,
+		},
+		{
+			name:           "shutting down",
+			checks:         []Check{check("database", true, nil)},
+			started:        true,
+			shuttingDown:   true,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: StatusShuttingDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(time.Second)
+			for _, c := range tt.checks {
+				checker.AddCheck(c)
+			}
+			if tt.started {
+				checker.Started()
+			}
+			if tt.shuttingDown {
+				checker.ShuttingDown()
+			}
+			recorder := httptest.NewRecorder()
+			checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != tt.expectedCode {
+				t.Errorf("expected status code %d, got %d", tt.expectedCode, recorder.Code)
+			}
+			var report Report
+			if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, report.Status)
+			}
+			if tt.expectedStatus == StatusNotReady {
+				database := report.Checks["database"]
+				if database.Status != CheckFail || database.Error != failure.Error() || !database.Critical {
+					t.Errorf("unexpected database result: %+v", database)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+	checker.AddCheck(Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	})
+	checker.Started()
+	report, ready := checker.Ready(context.Background())
+	if ready || report.Checks["database"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("expected the slow check to time out, got %+v", report)
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.ShuttingDown()
+	recorder := httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status 200 while shutting down, got %d", recorder.Code)
+	}
+}
+
+func TestQueueCheck(t *testing.T) {
+	queue := make(chan dto.OrderTask, 4)
+	queue <- dto.OrderTask{}
+	queue <- dto.OrderTask{}
+	queue <- dto.OrderTask{}
+	saturation := QueueCheck(queue, 0.75)
+	if detail, err := saturation.Run(context.Background()); err == nil || detail != "3/4" {
+		t.Errorf("expected saturated queue 3/4, got %q, %v", detail, err)
+	}
+	<-queue
+	if detail, err := saturation.Run(context.Background()); err != nil || detail != "2/4" {
+		t.Errorf("expected queue 2/4 to pass, got %q, %v", detail, err)
+	}
+}
+
+func TestReachabilityCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "not found still reachable", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			reachability := ReachabilityCheck("accrual", server.URL, server.Client())
+			if reachability.Critical {
+				t.Error("reachability check must not be critical")
+			}
+			if _, err := reachability.Run(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match the key")
+)
+
+// Key ключ подписи или проверки токенов. У ключа только для проверки signKey пустой.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign сообщает, есть ли у ключа закрытая часть
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring набор ключей: один активный ключ подписи и все ключи, которыми ещё можно проверять токены.
+// Это позволяет менять ключ подписи, не инвалидируя уже выданные токены.
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func New() *Keyring {
+	return &Keyring{keys: make(map[string]*Key)}
+}
+
+// AddHMAC добавляет симметричный ключ HS256. Такие ключи не публикуются в JWKS.
+func (k *Keyring) AddHMAC(id string, secret []byte) error {
+	if len(secret) < 32 {
+		return fmt.Errorf("hmac key %q must be at least 32 bytes", id)
+	}
+	return k.add(&Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret})
+}
+
+// AddPEM добавляет ключ RS256 или EdDSA из PEM. Закрытый ключ годится для подписи,
+// открытый - только для проверки.
+func (k *Keyring) AddPEM(id string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("key %q: no PEM data found", id)
+	}
+	parsed, err := parsePEMBlock(block)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", id, err)
+	}
+	key := &Key{ID: id}
+	switch parsed := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.signKey, key.verifyKey = AlgRS256, parsed, &parsed.PublicKey
+	case *rsa.PublicKey:
+		key.Algorithm, key.verifyKey = AlgRS256, parsed
+	case ed25519.PrivateKey:
+		key.Algorithm, key.signKey, key.verifyKey = AlgEdDSA, parsed, parsed.Public().(ed25519.PublicKey)
+	case ed25519.PublicKey:
+		key.Algorithm, key.verifyKey = AlgEdDSA, parsed
+	default:
+		return fmt.Errorf("key %q: unsupported key type %T", id, parsed)
+	}
+	return k.add(key)
+}
+
+// AddPEMFile читает ключ из файла
+func (k *Keyring) AddPEMFile(id string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", id, err)
+	}
+	return k.AddPEM(id, data)
+}
+
+// SetSigningKey делает ключ активным для подписи новых токенов
+func (k *Keyring) SetSigningKey(id string) error {
+	key, ok := k.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("key %q has no private part and cannot sign tokens", id)
+	}
+	k.signing = key
+	return nil
+}
+
+// SigningKey активный ключ подписи
+func (k *Keyring) SigningKey() *Key {
+	return k.signing
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.signing == nil {
+		return "", errors.New("no signing key configured")
+	}
+	token := jwt.NewWithClaims(signingMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signing.signKey)
+}
+
+// Keyfunc выбирает ключ проверки по kid из заголовка токена. Алгоритм токена обязан совпадать
+// с алгоритмом ключа, иначе открытый ключ RSA можно было бы использовать как HMAC-секрет.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("%w: %v", ErrAlgorithmMismatch, token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JSONWebKey открытый ключ в формате RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS возвращает открытые части асимметричных ключей, чтобы другие сервисы могли проверять токены
+func (k *Keyring) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.keys))}
+	for _, key := range k.keys {
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
+
+// RandomHMAC создаёт ключ HS256 со случайным секретом. Токены, подписанные им, перестают
+// проверяться после перезапуска, поэтому годится только когда ключи не настроены.
+func (k *Keyring) RandomHMAC(id string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	return k.AddHMAC(id, secret)
+}
+
+// ParseKeySpec разбирает список ключей вида "kid1=/path/key1.pem,kid2=/path/key2.pem"
+func ParseKeySpec(spec string) (map[string]string, []string, error) {
+	paths := make(map[string]string)
+	var order []string
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(item, "=")
+		if !ok || id == "" || path == "" {
+			return nil, nil, fmt.Errorf("invalid key spec %q, expected kid=path", item)
+		}
+		if _, exists := paths[id]; exists {
+			return nil, nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		paths[id] = path
+		order = append(order, id)
+	}
+	return paths, order, nil
+}
+
+func (k *Keyring) add(key *Key) error {
+	if key.ID == "" {
+		return errors.New("key id must not be empty")
+	}
+	if _, exists := k.keys[key.ID]; exists {
+		return fmt.Errorf("duplicate key id %q", key.ID)
+	}
+	k.keys[key.ID] = key
+	return nil
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func parsePEMBlock(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
@@ -0,0 +1,188 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestKeys(t *testing.T) (rsaPath string, edPath string, edPublicPath string) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPath = writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPath = writePEM(t, "PRIVATE KEY", der)
+	der, err = x509.MarshalPKIXPublicKey(edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicPath = writePEM(t, "PUBLIC KEY", der)
+	return rsaPath, edPath, edPublicPath
+}
+
+func claims() jwt.StandardClaims {
+	return jwt.StandardClaims{Subject: "7", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	rsaPath, edPath, _ := newTestKeys(t)
+	keys := New()
+	if err := keys.AddHMAC("hmac", []byte("abcdefghijklmnopqrstuvwxyz123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPEMFile("rsa-1", rsaPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPEMFile("ed-1", edPath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{"hmac", "rsa-1", "ed-1"} {
+		t.Run(kid, func(t *testing.T) {
+			if err := keys.SetSigningKey(kid); err != nil {
+				t.Fatal(err)
+			}
+			signed, err := keys.Sign(claims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := jwt.Parse(signed, keys.Keyfunc)
+			if err != nil || !token.Valid {
+				t.Fatalf("token must be valid: %v", err)
+			}
+			if token.Header["kid"] != kid {
+				t.Errorf("kid = %v, want %s", token.Header["kid"], kid)
+			}
+		})
+	}
+}
+
+func TestKeyring_RotationKeepsOldTokensValid(t *testing.T) {
+	rsaPath, edPath, _ := newTestKeys(t)
+	keys := New()
+	if err := keys.AddPEMFile("rsa-1", rsaPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPEMFile("ed-1", edPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetSigningKey("rsa-1"); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetSigningKey("ed-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(oldToken, keys.Keyfunc); err != nil {
+		t.Errorf("token signed by the previous key must stay valid: %v", err)
+	}
+}
+
+func TestKeyring_RejectsUnknownKidAndAlgorithmMismatch(t *testing.T) {
+	rsaPath, _, _ := newTestKeys(t)
+	keys := New()
+	if err := keys.AddPEMFile("rsa-1", rsaPath); err != nil {
+		t.Fatal(err)
+	}
+
+	other := New()
+	if err := other.AddHMAC("unknown", []byte("abcdefghijklmnopqrstuvwxyz123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SetSigningKey("unknown"); err != nil {
+		t.Fatal(err)
+	}
+	signed, _ := other.Sign(claims())
+	if _, err := jwt.Parse(signed, keys.Keyfunc); !errors.Is(err.(*jwt.ValidationError).Inner, ErrUnknownKey) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+
+	// HS256-токен с kid RSA-ключа не должен проверяться открытым ключом как секретом
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = "rsa-1"
+	forgedString, _ := forged.SignedString([]byte("whatever"))
+	if _, err := jwt.Parse(forgedString, keys.Keyfunc); !errors.Is(err.(*jwt.ValidationError).Inner, ErrAlgorithmMismatch) {
+		t.Errorf("expected algorithm mismatch, got %v", err)
+	}
+}
+
+func TestKeyring_PublicKeyCannotSign(t *testing.T) {
+	_, _, edPublicPath := newTestKeys(t)
+	keys := New()
+	if err := keys.AddPEMFile("ed-public", edPublicPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetSigningKey("ed-public"); err == nil {
+		t.Error("public key must not be accepted as signing key")
+	}
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	rsaPath, _, edPublicPath := newTestKeys(t)
+	keys := New()
+	if err := keys.AddHMAC("hmac", []byte("abcdefghijklmnopqrstuvwxyz123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPEMFile("rsa-1", rsaPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPEMFile("ed-1", edPublicPath); err != nil {
+		t.Fatal(err)
+	}
+	set := keys.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("HMAC keys must not be published, got %+v", set.Keys)
+	}
+	if set.Keys[0].KeyID != "ed-1" || set.Keys[0].KeyType != "OKP" || set.Keys[0].Curve != "Ed25519" || set.Keys[0].X == "" {
+		t.Errorf("unexpected Ed25519 JWK: %+v", set.Keys[0])
+	}
+	if set.Keys[1].KeyID != "rsa-1" || set.Keys[1].KeyType != "RSA" || set.Keys[1].E != "AQAB" || set.Keys[1].N == "" {
+		t.Errorf("unexpected RSA JWK: %+v", set.Keys[1])
+	}
+}
+
+func TestParseKeySpec(t *testing.T) {
+	paths, order, err := ParseKeySpec("rsa-2=/keys/rsa2.pem, rsa-1=/keys/rsa1.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "rsa-2" || paths["rsa-1"] != "/keys/rsa1.pem" {
+		t.Errorf("unexpected result: %v %v", paths, order)
+	}
+	if _, _, err := ParseKeySpec("rsa-1"); err == nil {
+		t.Error("spec without path must be rejected")
+	}
+	if _, _, err := ParseKeySpec("a=/x,a=/y"); err == nil {
+		t.Error("duplicate ids must be rejected")
+	}
+}
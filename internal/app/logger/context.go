@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// NewContext возвращает контекст, несущий логер l
+func NewContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext логер из контекста или общий логер, если в контексте его нет
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if l, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return &Log
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса и логером,
+// добавляющим этот идентификатор к каждой записи
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return NewContext(ctx, FromContext(ctx).With("request_id", requestID))
+}
+
+// RequestID идентификатор запроса из контекста, пустой вне запроса
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
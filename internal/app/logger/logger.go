@@ -7,8 +7,9 @@ import (
 
 // Log будет доступен всему коду как синглтон.
 // Никакой код навыка, кроме функции InitLogger, не должен модифицировать эту переменную.
-// По умолчанию установлен no-op-логер, который не выводит никаких сообщений.
-var Log zap.SugaredLogger
+// До инициализации пишет в stderr с настройками по умолчанию, чтобы не потерялась ошибка
+// запуска, случившаяся раньше Initialize (например, неверный уровень логирования).
+var Log = *zap.Must(zap.NewProduction()).Sugar()
 
 // Initialize инициализирует синглтон логера с необходимым уровнем логирования.
 func Initialize(level string) error {
@@ -0,0 +1,141 @@
+// Package metrics собирает метрики Prometheus: HTTP API, обращения к системе Accrual,
+// очередь и обработчики заказов, пул соединений с БД и бизнес-счётчики.
+package metrics
+
+import (
+	"database/sql"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "gophermart"
+
+// Источники ответов 429 в TooManyRequests
+const (
+	SourceHTTP    = "http"
+	SourceAccrual = "accrual"
+)
+
+// Registry реестр метрик приложения, отдаётся на /metrics вместе с метриками Go и процесса
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration время обработки запросов HTTP API по маршрутам
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	// AccrualRequestDuration время ответа системы Accrual, status "error" - запрос не выполнен
+	AccrualRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Accrual system request latency by response status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+	// TooManyRequests ответы 429: отданные клиентам HTTP API и полученные от системы Accrual
+	TooManyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "too_many_requests_total",
+		Help:      "Responses with status 429 by source.",
+	}, []string{"source"})
+	// OrderWorkers размер пула обработчиков заказов
+	OrderWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "workers",
+		Help:      "Size of the order processing worker pool.",
+	})
+	// OrderWorkersBusy обработчики, взявшие блокировку и обрабатывающие заказ, вместе с OrderWorkers дают загрузку пула
+	OrderWorkersBusy = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "workers_busy",
+		Help:      "Order processing workers currently handling an order.",
+	})
+	// OrderWorkersWaiting обработчики, ждущие общей блокировки баланса. В занятые они не входят.
+	OrderWorkersWaiting = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "workers_waiting",
+		Help:      "Order processing workers waiting for the balance lock.",
+	})
+	OrdersUploaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "uploaded_total",
+		Help:      "Orders accepted for processing.",
+	})
+	PointsAccrued = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "points",
+		Name:      "accrued_total",
+		Help:      "Loyalty points credited to users.",
+	})
+	PointsWithdrawn = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "points",
+		Name:      "withdrawn_total",
+		Help:      "Loyalty points withdrawn by users.",
+	})
+	// Withdrawals попытки списания по результату: success, код доменной ошибки или error
+	Withdrawals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "withdrawals",
+		Name:      "total",
+		Help:      "Withdrawal attempts by outcome.",
+	}, []string{"outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		AccrualRequestDuration,
+		TooManyRequests,
+		OrderWorkers,
+		OrderWorkersBusy,
+		OrderWorkersWaiting,
+		OrdersUploaded,
+		PointsAccrued,
+		PointsWithdrawn,
+		Withdrawals,
+	)
+}
+
+// RegisterOrderQueue добавляет глубину и ёмкость очереди заказов на обработку
+func RegisterOrderQueue(queue chan dto.OrderTask) error {
+	depth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "queue_depth",
+		Help:      "Orders waiting in the processing queue.",
+	}, func() float64 { return float64(len(queue)) })
+	capacity := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "queue_capacity",
+		Help:      "Capacity of the order processing queue.",
+	}, func() float64 { return float64(cap(queue)) })
+	if err := Registry.Register(depth); err != nil {
+		return err
+	}
+	return Registry.Register(capacity)
+}
+
+// RegisterDB добавляет статистику пула соединений из sqlDB.Stats()
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
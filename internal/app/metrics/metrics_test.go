@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	queue := make(chan dto.OrderTask, 5)
+	queue <- dto.OrderTask{}
+	queue <- dto.OrderTask{}
+	if err := RegisterOrderQueue(queue); err != nil {
+		t.Fatal(err)
+	}
+	OrdersUploaded.Add(3)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+	body, err := io.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"gophermart_orders_queue_depth 2",
+		"gophermart_orders_queue_capacity 5",
+		"gophermart_orders_uploaded_total 3",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics output has no %q", line)
+		}
+	}
+}
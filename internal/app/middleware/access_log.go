@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"time"
+)
+
+// AccessLog пишет структурированную запись о каждом запросе: метод, путь, статус, время обработки,
+// размер ответа и, если запрос прошёл проверку, пользователя или партнёра. Ставится после RequestID,
+// чтобы запись несла идентификатор запроса.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		fields := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency", time.Since(start),
+			"bytes", size,
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
+		}
+		if userID, ok := c.Get("userID"); ok {
+			fields = append(fields, "user_id", userID)
+		}
+		if partnerID, ok := c.Get("partnerID"); ok {
+			fields = append(fields, "partner_id", partnerID)
+		}
+		log := logger.FromContext(c.Request.Context())
+		if status >= 500 {
+			log.Errorw("request", fields...)
+			return
+		}
+		log.Infow("request", fields...)
+	}
+}
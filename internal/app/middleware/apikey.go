@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+const apiKeyHeader = "X-Api-Key"
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (dto.PartnerPrincipal, error)
+}
+
+// keyLimiters ограничители частоты запросов, по одному на ключ
+type keyLimiters struct {
+	mu       sync.Mutex
+	limiters map[uint]*rate.Limiter
+}
+
+// reserve резервирует запрос для ключа. Если лимит ключа изменился, ограничитель создаётся заново.
+// Возвращает 0, если запрос можно выполнить сразу, иначе время до следующей попытки.
+func (l *keyLimiters) reserve(principal dto.PartnerPrincipal) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit := rate.Limit(float64(principal.RateLimit) / time.Minute.Seconds())
+	limiter, ok := l.limiters[principal.KeyID]
+	if !ok || limiter.Burst() != principal.RateLimit {
+		limiter = rate.NewLimiter(limit, principal.RateLimit)
+		l.limiters[principal.KeyID] = limiter
+	}
+	now := time.Now()
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// APIKeyMiddleware проверяет ключ партнёра из заголовка X-Api-Key и ограничивает частоту запросов
+// по лимиту ключа. Кладёт в контекст партнёра и области доступа ключа.
+func APIKeyMiddleware(authenticator APIKeyAuthenticator) gin.HandlerFunc {
+	limiters := &keyLimiters{limiters: make(map[uint]*rate.Limiter)}
+	return func(c *gin.Context) {
+		key := c.GetHeader(apiKeyHeader)
+		if key == "" {
+			handlers.RespondError(c, apperrors.New(apperrors.CodeUnauthorized, "API key is missing"))
+			c.Abort()
+			return
+		}
+		principal, err := authenticator.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			handlers.RespondError(c, err)
+			c.Abort()
+			return
+		}
+		if retryAfter := limiters.reserve(principal); retryAfter > 0 {
+			handlers.RespondError(c, apperrors.RateLimitExceeded(retryAfter))
+			c.Abort()
+			return
+		}
+		c.Set("partnerID", principal.PartnerID)
+		c.Set("apiKeyID", principal.KeyID)
+		c.Set("scopes", principal.Scopes)
+		c.Next()
+	}
+}
+
+// RequireScope пропускает только запросы с ключом, которому выдана область доступа scope.
+// Должен стоять после APIKeyMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, granted := range c.GetStringSlice("scopes") {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+		handlers.RespondError(c, apperrors.Newf(apperrors.CodeForbidden, "API key has no %s scope", scope))
+		c.Abort()
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"net/http"
+)
+
+type AuditRecorder interface {
+	Record(ctx context.Context, event dto.AuditEventDTO) error
+}
+
+// AuditMiddleware записывает в журнал аудита каждый запрос группы вместе с его результатом.
+// Ошибка записи не влияет на ответ клиенту, но попадает в лог.
+func AuditMiddleware(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		recordAuditEvent(c, recorder, c.Request.Method+" "+c.FullPath(), c.Request.URL.RequestURI())
+	}
+}
+
+// AuditEvent записывает в журнал аудита запрос к маршруту как событие eventType.
+// Обработчик может уточнить участника, объект и результат события через ключи контекста handlers.Audit*.
+func AuditEvent(recorder AuditRecorder, eventType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		recordAuditEvent(c, recorder, eventType, c.GetString(handlers.AuditTargetKey))
+	}
+}
+
+// AuditRejected записывает событие eventType, только если запрос отклонён с 401 или 403.
+// Ставится перед проверкой токена или ключа, чтобы попадали и отказы самой проверки.
+func AuditRejected(recorder AuditRecorder, eventType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		status := c.Writer.Status()
+		if status == http.StatusUnauthorized || status == http.StatusForbidden {
+			recordAuditEvent(c, recorder, eventType, c.Request.Method+" "+c.FullPath())
+		}
+	}
+}
+
+func recordAuditEvent(c *gin.Context, recorder AuditRecorder, action string, target string) {
+	status := c.Writer.Status()
+	outcome := c.GetString(handlers.AuditOutcomeKey)
+	if outcome == "" {
+		outcome = entities.AuditOutcomeSuccess
+		if status >= http.StatusBadRequest {
+			outcome = entities.AuditOutcomeFailure
+		}
+	}
+	err := recorder.Record(c.Request.Context(), dto.AuditEventDTO{
+		ActorID:   auditActor(c),
+		Action:    action,
+		Target:    target,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Status:    status,
+		Outcome:   outcome,
+	})
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Errorf("failed to record audit event: %v", err)
+	}
+}
+
+// auditActor пользователь, выполнивший запрос: из токена или, для входа и регистрации, из обработчика
+func auditActor(c *gin.Context) uint {
+	for _, key := range []string{"userID", handlers.AuditActorKey} {
+		if value, ok := c.Get(key); ok {
+			if actorID, ok := value.(uint); ok {
+				return actorID
+			}
+		}
+	}
+	return 0
+}
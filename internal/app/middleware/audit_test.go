@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordedEvents []dto.AuditEventDTO
+
+func (r *recordedEvents) Record(_ context.Context, event dto.AuditEventDTO) error {
+	*r = append(*r, event)
+	return nil
+}
+
+func TestAuditEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var events recordedEvents
+	router := gin.New()
+	router.POST("/login", AuditEvent(&events, entities.AuditLogin), func(c *gin.Context) {
+		c.Set(handlers.AuditTargetKey, "alice")
+		c.Set(handlers.AuditActorKey, uint(7))
+		c.Set(handlers.AuditOutcomeKey, entities.AuditOutcomeChallenge)
+		c.Status(http.StatusOK)
+	})
+	router.POST("/register", AuditEvent(&events, entities.AuditRegister), func(c *gin.Context) {
+		c.Set(handlers.AuditTargetKey, "bob")
+		c.Status(http.StatusConflict)
+	})
+
+	for _, path := range []string{"/login", "/register"} {
+		request := httptest.NewRequest(http.MethodPost, path, nil)
+		request.Header.Set("User-Agent", "test-agent")
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	login := events[0]
+	if login.Action != entities.AuditLogin || login.ActorID != 7 || login.Target != "alice" ||
+		login.Outcome != entities.AuditOutcomeChallenge || login.UserAgent != "test-agent" {
+		t.Errorf("unexpected login event: %+v", login)
+	}
+	register := events[1]
+	if register.Action != entities.AuditRegister || register.ActorID != 0 || register.Target != "bob" ||
+		register.Outcome != entities.AuditOutcomeFailure || register.Status != http.StatusConflict {
+		t.Errorf("unexpected register event: %+v", register)
+	}
+}
+
+func TestAuditRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var events recordedEvents
+	router := gin.New()
+	router.Use(AuditRejected(&events, entities.AuditTokenRejected))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/denied", func(c *gin.Context) { c.Status(http.StatusUnauthorized) })
+
+	for _, path := range []string{"/ok", "/denied"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if len(events) != 1 || events[0].Action != entities.AuditTokenRejected || events[0].Target != "GET /denied" {
+		t.Errorf("expected one rejected event, got %+v", events)
+	}
+}
@@ -1,48 +1,144 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func AuthMiddleware(secret string) gin.HandlerFunc {
+const bearerScheme = "Bearer"
+
+// TokenRevocationChecker проверяет, не отозван ли токен с данным идентификатором (jti) или его сессия
+type TokenRevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string, sessionID string) (bool, error)
+}
+
+// AuthMiddleware проверяет access-токен. keyfunc выбирает ключ проверки по kid токена.
+// Токен принимается из заголовка Authorization или, если заголовка нет, из cookie-сессии.
+// Для cookie-сессии изменяющие запросы должны нести CSRF-токен.
+func AuthMiddleware(keyfunc jwt.Keyfunc, revocations TokenRevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Получаем токен из заголовка Authorization
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Не предоставлен токен авторизации"})
-			c.Abort()
+		tokenString, fromCookie := extractToken(c)
+		if tokenString == "" {
+			abortWithError(c, apperrors.New(apperrors.CodeUnauthorized, "authorization token is missing"))
+			return
+		}
+		if fromCookie && !validCSRFToken(c) {
+			abortWithError(c, apperrors.New(apperrors.CodeForbidden, "CSRF token is missing or invalid"))
 			return
 		}
 
-		// Проверяем формат токена
-		token, err := jwt.Parse(authHeader, func(token *jwt.Token) (interface{}, error) {
-			// Проверяем, что алгоритм подписи совпадает с ожидаемым
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("неверный алгоритм подписи: %v", token.Header["alg"])
-			}
-			// Возвращаем ключ для проверки подписи
-			return []byte(secret), nil
-		})
+		token, err := VerifyAccessToken(c.Request.Context(), tokenString, keyfunc, revocations)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен авторизации"})
-			c.Abort()
+			abortWithError(c, err)
 			return
 		}
+		c.Set("userID", token.UserID)
+		c.Set("tokenID", token.ID)
+		c.Set("sessionID", token.SessionID)
+		c.Set("tokenExpiresAt", token.ExpiresAt)
+		c.Set("role", token.Role)
+		c.Next()
+	}
+}
 
-		// Проверяем, что токен является объектом типа JWT и содержит правильные поля
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Получаем userID из токена
-			userIDFloat64 := claims["userID"].(float64)
-			userID := uint(userIDFloat64)
-			c.Set("userID", userID)
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен авторизации"})
-			c.Abort()
-			return
+// AccessToken проверенный access-токен
+type AccessToken struct {
+	UserID uint
+	// ID идентификатор токена (jti), по которому токен отзывается
+	ID        string
+	SessionID string
+	ExpiresAt time.Time
+	Role      string
+}
+
+// VerifyAccessToken проверяет подпись, обязательные поля и отзыв access-токена.
+// Общая проверка для HTTP и gRPC.
+func VerifyAccessToken(
+	ctx context.Context,
+	tokenString string,
+	keyfunc jwt.Keyfunc,
+	revocations TokenRevocationChecker,
+) (AccessToken, error) {
+	token, err := jwt.Parse(tokenString, keyfunc)
+	if err != nil || !token.Valid {
+		return AccessToken{}, apperrors.New(apperrors.CodeUnauthorized, "invalid authorization token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return AccessToken{}, apperrors.New(apperrors.CodeUnauthorized, "invalid authorization token")
+	}
+	// Токены без пользователя и без идентификатора (jti), который нужен для отзыва, не принимаются
+	userID, _ := claims["userID"].(float64)
+	jti, _ := claims["jti"].(string)
+	if userID < 1 || jti == "" {
+		return AccessToken{}, apperrors.New(apperrors.CodeUnauthorized, "invalid authorization token")
+	}
+	expiresAt, _ := claims["exp"].(float64)
+	// Токены, выпущенные до появления ролей, роли не содержат
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = entities.RoleUser
+	}
+	// Токены, выпущенные до появления сессий, идентификатора сессии не содержат
+	sessionID, _ := claims["sid"].(string)
+	revoked, err := revocations.IsRevoked(ctx, jti, sessionID)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return AccessToken{}, apperrors.New(apperrors.CodeUnauthorized, "authorization token has been revoked")
+	}
+	return AccessToken{
+		UserID:    uint(userID),
+		ID:        jti,
+		SessionID: sessionID,
+		ExpiresAt: time.Unix(int64(expiresAt), 0),
+		Role:      role,
+	}, nil
+}
+
+// extractToken достаёт токен из заголовка Authorization по схеме Bearer. Заголовок без схемы
+// принимается как есть для совместимости со старыми клиентами. fromCookie сообщает,
+// что токен взят из cookie-сессии.
+func extractToken(c *gin.Context) (token string, fromCookie bool) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, credentials, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, bearerScheme) {
+			return strings.TrimSpace(credentials), false
 		}
+		return header, false
+	}
+	cookie, err := c.Cookie(handlers.AccessTokenCookie)
+	if err != nil {
+		return "", false
 	}
+	return cookie, true
+}
+
+// validCSRFToken проверяет CSRF-токен по схеме double submit: значение заголовка
+// должно совпадать со значением cookie. Безопасные методы не проверяются.
+func validCSRFToken(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, err := c.Cookie(handlers.CSRFTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(c.GetHeader(handlers.CSRFHeader))) == 1
+}
+
+func abortWithError(c *gin.Context, err error) {
+	handlers.RespondError(c, err)
+	c.Abort()
 }
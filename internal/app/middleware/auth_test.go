@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type noRevocations struct{}
+
+func (noRevocations) IsRevoked(context.Context, string, string) (bool, error) {
+	return false, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	secret := []byte("test secret key of at least 32 bytes")
+	keyfunc := func(*jwt.Token) (any, error) { return secret, nil }
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+	valid := sign(jwt.MapClaims{"userID": 7, "jti": "jti-1", "exp": exp})
+	withoutUser := sign(jwt.MapClaims{"jti": "jti-2", "exp": exp})
+
+	tests := []struct {
+		name    string
+		method  string
+		header  string
+		cookies map[string]string
+		csrf    string
+		status  int
+	}{
+		{name: "Bearer scheme", method: http.MethodPost, header: "Bearer " + valid, status: http.StatusOK},
+		{name: "Raw token", method: http.MethodPost, header: valid, status: http.StatusOK},
+		{name: "Missing token", method: http.MethodGet, status: http.StatusUnauthorized},
+		{name: "Missing userID claim", method: http.MethodGet, header: "Bearer " + withoutUser, status: http.StatusUnauthorized},
+		{
+			name:    "Cookie on safe method",
+			method:  http.MethodGet,
+			cookies: map[string]string{handlers.AccessTokenCookie: valid},
+			status:  http.StatusOK,
+		},
+		{
+			name:    "Cookie with CSRF token",
+			method:  http.MethodPost,
+			cookies: map[string]string{handlers.AccessTokenCookie: valid, handlers.CSRFTokenCookie: "csrf"},
+			csrf:    "csrf",
+			status:  http.StatusOK,
+		},
+		{
+			name:    "Cookie without CSRF token",
+			method:  http.MethodPost,
+			cookies: map[string]string{handlers.AccessTokenCookie: valid, handlers.CSRFTokenCookie: "csrf"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "Cookie with wrong CSRF token",
+			method:  http.MethodDelete,
+			cookies: map[string]string{handlers.AccessTokenCookie: valid, handlers.CSRFTokenCookie: "csrf"},
+			csrf:    "other",
+			status:  http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(AuthMiddleware(keyfunc, noRevocations{}))
+			router.Handle(tt.method, "/", func(c *gin.Context) {
+				if c.MustGet("userID").(uint) != 7 {
+					t.Errorf("unexpected userID %v", c.MustGet("userID"))
+				}
+				c.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(tt.method, "/", nil)
+			if tt.header != "" {
+				request.Header.Set("Authorization", tt.header)
+			}
+			for name, value := range tt.cookies {
+				request.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.csrf != "" {
+				request.Header.Set(handlers.CSRFHeader, tt.csrf)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
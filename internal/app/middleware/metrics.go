@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute метка запросов без маршрута, чтобы произвольные пути не раздували число серий
+const unmatchedRoute = "unmatched"
+
+// Metrics учитывает время обработки запросов по шаблону маршрута и ответы 429
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := c.Writer.Status()
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+		if status == http.StatusTooManyRequests {
+			metrics.TooManyRequests.WithLabelValues(metrics.SourceHTTP).Inc()
+		}
+	}
+}
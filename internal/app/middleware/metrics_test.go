@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/orders/:number", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/limited", func(c *gin.Context) { c.Status(http.StatusTooManyRequests) })
+
+	limited := testutil.ToFloat64(metrics.TooManyRequests.WithLabelValues(metrics.SourceHTTP))
+	for _, path := range []string{"/orders/1", "/orders/2", "/limited", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route    string
+		status   string
+		expected int
+	}{
+		{route: "/orders/:number", status: "200", expected: 2},
+		{route: "/limited", status: "429", expected: 1},
+		{route: unmatchedRoute, status: "404", expected: 1},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"method": http.MethodGet, "route": tt.route, "status": tt.status}
+		if got := sampleCount(t, metrics.HTTPRequestDuration.With(labels)); got != uint64(tt.expected) {
+			t.Errorf("expected %d observations for %s %s, got %d", tt.expected, tt.route, tt.status, got)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.TooManyRequests.WithLabelValues(metrics.SourceHTTP)); got != limited+1 {
+		t.Errorf("expected 429 counter to grow by 1, got %v -> %v", limited, got)
+	}
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"strings"
+)
+
+// ValidateRequest проверяет параметры и тело запроса по документу OpenAPI и отвечает 400 со списком нарушений.
+// Маршруты, которых нет в документе, не проверяются. Доступ проверяют AuthMiddleware и APIKeyMiddleware,
+// поэтому схемы безопасности документа здесь не учитываются.
+func ValidateRequest(router routers.Router) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+		err = openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		})
+		if err != nil {
+			abortWithError(c, apperrors.ValidationFailed(requestViolations(nil, err)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestViolations раскладывает ошибку проверки на короткие сообщения вида "query parameter "limit": ...",
+// не раскрывая клиенту саму схему
+func requestViolations(violations []string, err error) []string {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, item := range e {
+			violations = requestViolations(violations, item)
+		}
+		return violations
+	case *openapi3filter.RequestError:
+		subject := "request body"
+		if e.Parameter != nil {
+			subject = fmt.Sprintf("%s parameter %q", e.Parameter.In, e.Parameter.Name)
+		}
+		if e.Err == nil {
+			return append(violations, subject+": "+e.Reason)
+		}
+		for _, violation := range requestViolations(nil, e.Err) {
+			violations = append(violations, subject+": "+violation)
+		}
+		return violations
+	case *openapi3.SchemaError:
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			return append(violations, strings.Join(pointer, ".")+": "+e.Reason)
+		}
+		return append(violations, e.Reason)
+	}
+	return append(violations, err.Error())
+}
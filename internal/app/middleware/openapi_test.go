@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/openapi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	document, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiRouter, err := gorillamux.NewRouter(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+		violations  int
+	}{
+		{
+			name:        "Valid withdrawal",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":751}`,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "Zero withdrawal sum",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":"2377225624","sum":0}`,
+			status:      http.StatusBadRequest,
+			violations:  1,
+		},
+		{
+			name:        "Missing fields and wrong types",
+			method:      http.MethodPost,
+			target:      "/api/user/balance/withdraw",
+			contentType: "application/json",
+			body:        `{"order":2377225624}`,
+			status:      http.StatusBadRequest,
+			violations:  2,
+		},
+		{
+			name:        "Malformed JSON",
+			method:      http.MethodPost,
+			target:      "/api/user/register",
+			contentType: "application/json",
+			body:        `{"login":`,
+			status:      http.StatusBadRequest,
+			violations:  1,
+		},
+		{
+			name:        "Unexpected content type",
+			method:      http.MethodPost,
+			target:      "/api/user/orders",
+			contentType: "application/xml",
+			body:        "<number>12345678903</number>",
+			status:      http.StatusBadRequest,
+			violations:  1,
+		},
+		{
+			name:        "Plain text order",
+			method:      http.MethodPost,
+			target:      "/api/user/orders",
+			contentType: "text/plain",
+			body:        "12345678903",
+			status:      http.StatusOK,
+		},
+		{name: "Valid query", method: http.MethodGet, target: "/api/user/orders?limit=10&order=desc", status: http.StatusOK},
+		{
+			name:       "Invalid query",
+			method:     http.MethodGet,
+			target:     "/api/user/orders?limit=0&order=random&from=yesterday",
+			status:     http.StatusBadRequest,
+			violations: 3,
+		},
+		{
+			name:       "Invalid path parameter",
+			method:     http.MethodGet,
+			target:     "/api/admin/users/abc",
+			status:     http.StatusBadRequest,
+			violations: 1,
+		},
+		{name: "Undocumented route", method: http.MethodGet, target: "/internal/debug", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ValidateRequest(apiRouter))
+			router.NoRoute(func(c *gin.Context) {
+				if c.Request.Body != nil {
+					// Тело должно остаться доступным обработчику после проверки
+					body, _ := c.GetRawData()
+					if string(body) != tt.body {
+						t.Errorf("expected body %q, got %q", tt.body, body)
+					}
+				}
+				c.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+			if tt.status != http.StatusBadRequest {
+				return
+			}
+			var problem models.Problem
+			if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if len(problem.Violations) != tt.violations {
+				t.Errorf("expected %d violations, got %q", tt.violations, problem.Violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"strconv"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength идентификаторы длиннее не принимаются, чтобы клиент не раздувал ими логи
+	maxRequestIDLength = 128
+)
+
+// RequestID берёт идентификатор запроса из заголовка X-Request-ID или создаёт новый, возвращает его
+// в ответе и кладёт в контекст запроса логер, который добавляет идентификатор к каждой записи.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := EnsureRequestID(c.GetHeader(RequestIDHeader))
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// EnsureRequestID возвращает идентификатор, переданный клиентом, если он допустим, иначе новый.
+// Допустимы непустые идентификаторы из латинских букв, цифр и символов "-", "_", ".", ":".
+func EnsureRequestID(requestID string) string {
+	if validRequestID(requestID) {
+		return requestID
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "client id is kept", header: "abc-123_x.y:z", expected: "abc-123_x.y:z"},
+		{name: "missing id is generated", header: ""},
+		{name: "invalid id is replaced", header: "bad id\n"},
+		{name: "too long id is replaced", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/", func(c *gin.Context) {
+				fromContext = logger.RequestID(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				request.Header.Set(RequestIDHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			requestID := recorder.Header().Get(RequestIDHeader)
+			if requestID == "" || requestID != fromContext {
+				t.Fatalf("response id %q does not match context id %q", requestID, fromContext)
+			}
+			if tt.expected != "" && requestID != tt.expected {
+				t.Errorf("expected id %q, got %q", tt.expected, requestID)
+			}
+			if tt.expected == "" && requestID == tt.header {
+				t.Errorf("expected generated id, got %q", requestID)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	saved := logger.Log
+	logger.Log = *zap.New(core).Sugar()
+	defer func() { logger.Log = saved }()
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog())
+	router.GET("/orders/:number", func(c *gin.Context) {
+		c.Set("userID", uint(7))
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+	for _, path := range []string{"/orders/42", "/fail"} {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set(RequestIDHeader, "req-1")
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+	ok := entries[0].ContextMap()
+	if entries[0].Level != zap.InfoLevel || ok["request_id"] != "req-1" || ok["route"] != "/orders/:number" ||
+		ok["path"] != "/orders/42" || ok["status"] != int64(http.StatusOK) || ok["user_id"] != uint64(7) ||
+		ok["bytes"] != int64(2) {
+		t.Errorf("unexpected access log entry: %+v", ok)
+	}
+	if entries[1].Level != zap.ErrorLevel || entries[1].ContextMap()["status"] != int64(http.StatusInternalServerError) {
+		t.Errorf("expected error entry for 5xx, got %+v", entries[1])
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+)
+
+// RequireRole пропускает только пользователей с одной из перечисленных ролей.
+// Должен стоять после AuthMiddleware, который кладёт роль в контекст.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+	return func(c *gin.Context) {
+		if !allowed[c.GetString("role")] {
+			handlers.RespondError(c, apperrors.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Tracing открывает серверный спан на каждый запрос, продолжая трассу из заголовка traceparent,
+// и кладёт его в контекст запроса, чтобы спаны сервисов и запросов к БД стали дочерними.
+// Ставится после RequestID, чтобы спан и записи лога несли идентификатор запроса и трассы.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.HTTPTarget(c.Request.URL.Path),
+				semconv.HTTPClientIP(c.ClientIP()),
+				attribute.String("request_id", logger.RequestID(ctx)),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(tracing.WithLogger(ctx))
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if userID := c.GetUint("userID"); userID != 0 {
+			span.SetAttributes(attribute.Int64("user_id", int64(userID)))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -1,17 +1,191 @@
 package dto
 
+import (
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
 type OrderDTO struct {
 	Number string
 	UserID uint
 }
 
+// OrderUploadResult итог загрузки заказа. Повторная загрузка своего заказа не ошибка,
+// поэтому о ней сообщает флаг, а не ошибка, и все транспорты отвечают на неё одинаково.
+type OrderUploadResult struct {
+	Order           entities.Order
+	AlreadyUploaded bool
+}
+
 type UserDTO struct {
 	UserName string
 	Password string
+	// IP адрес клиента, с которого выполняется вход
+	IP    string
+	Email string
+}
+
+type PasswordChangeDTO struct {
+	UserID      uint
+	OldPassword string
+	NewPassword string
+	IP          string
 }
 
 type WithdrawDTO struct {
 	OrderNumber string
 	Sum         float64
 	UserID      uint
+	TOTPCode    string
+}
+
+// PageQuery параметры постраничного запроса списка, как их передал клиент
+type PageQuery struct {
+	UserID     uint
+	Limit      int
+	Cursor     string
+	Descending bool
+	Statuses   []string
+	From       *time.Time
+	To         *time.Time
+	MinAmount  *float64
+	MaxAmount  *float64
+}
+
+// PageCursor позиция в выдаче, после которой начинается следующая страница
+type PageCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// PageFilter параметры запроса страницы к репозиторию
+type PageFilter struct {
+	PageQuery
+	After *PageCursor
+}
+
+type AuditEventDTO struct {
+	ActorID   uint
+	Action    string
+	Target    string
+	IP        string
+	UserAgent string
+	Status    int
+	Outcome   string
+}
+
+// Stats количество записей и их сумма
+type Stats struct {
+	Count int64
+	Sum   float64
+}
+
+// UserSearchQuery параметры поиска пользователей администратором
+type UserSearchQuery struct {
+	Query  string
+	Limit  int
+	Cursor string
+}
+
+// Notification сообщение пользователю, которое доставляет Notifier
+type Notification struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// APIKeyDTO параметры нового ключа партнёра
+type APIKeyDTO struct {
+	PartnerID uint
+	Scopes    []string
+	RateLimit int
+}
+
+// PartnerPrincipal партнёр, прошедший проверку по API-ключу
+type PartnerPrincipal struct {
+	PartnerID uint
+	KeyID     uint
+	Scopes    []string
+	RateLimit int
+}
+
+// AuditEventQuery фильтры поиска по журналу аудита. Нулевые значения не ограничивают выдачу.
+type AuditEventQuery struct {
+	ActorID uint
+	Action  string
+	Target  string
+	Outcome string
+	IP      string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Cursor  string
+}
+
+// AuditEventFilter параметры запроса страницы журнала аудита к репозиторию
+type AuditEventFilter struct {
+	AuditEventQuery
+	Before *PageCursor
+}
+
+// ClientInfo откуда пришёл запрос на вход: по этим данным пользователь узнаёт свои сессии
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// OIDCLoginResult итог возврата пользователя от провайдера OpenID Connect
+type OIDCLoginResult struct {
+	UserID      uint
+	TOTPEnabled bool
+	// Linked внешняя учётная запись привязана к уже вошедшему пользователю, новые токены не нужны
+	Linked bool
+}
+
+// OrderTask заказ, переданный на расчёт в систему Accrual, идентификатор запроса и спан,
+// в которых он загружен, чтобы записи и спаны обработчика можно было связать с запросом
+type OrderTask struct {
+	Order     entities.Order
+	RequestID string
+	Trace     trace.SpanContext
+}
+
+// EventSubscription подписка на события пользователя. Missed события после Last-Event-ID,
+// сохранённые до подписки, Events новые события. Канал Events закрывается, если подписчик
+// не успевает их читать. Cancel освобождает подписку.
+type EventSubscription struct {
+	Missed []entities.UserEvent
+	Events <-chan entities.UserEvent
+	Cancel func()
+}
+
+// WebhookSubscriptionDTO параметры новой подписки партнёра
+type WebhookSubscriptionDTO struct {
+	PartnerID  uint
+	URL        string
+	EventTypes []string
+	Secret     string
+}
+
+// WebhookTarget подписка партнёра, к которому привязан пользователь, и идентификатор пользователя у партнёра
+type WebhookTarget struct {
+	SubscriptionID uint
+	PartnerID      uint
+	EventTypes     string
+	ExternalID     string
+}
+
+// WebhookDeliveryQuery параметры журнала доставок по подписке
+type WebhookDeliveryQuery struct {
+	PartnerID      uint
+	SubscriptionID uint
+	Limit          int
+	Cursor         string
+}
+
+// WebhookDeliveryFilter параметры запроса страницы журнала доставок к репозиторию
+type WebhookDeliveryFilter struct {
+	WebhookDeliveryQuery
+	Before *PageCursor
 }
@@ -1,6 +1,9 @@
 package entities
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type Entity struct {
 	gorm.Model
@@ -14,12 +17,26 @@ type Withdraw struct {
 	UserID      uint    `json:"user_id" db:"user_id" gorm:"not null"`
 }
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	Entity
 	UserName  string  `json:"user_name" db:"user_name" gorm:"unique;not null"`
 	Password  string  `json:"password" db:"password" gorm:"not null"`
 	Balance   float64 `json:"balance" db:"balance" gorm:"default:0.0;not null"`
 	Withdrawn float64 `json:"withdrawn" db:"withdrawn" gorm:"default:0.0;not null"`
+	Role      string  `json:"role" db:"role" gorm:"default:user;not null"`
+	// Email адрес для восстановления пароля, может быть пустым
+	Email string `json:"email" db:"email" gorm:"index"`
+	// TOTPSecret секрет второго фактора. Пока TOTPEnabled не выставлен, подключение не подтверждено.
+	TOTPSecret  string `json:"-" db:"totp_secret"`
+	TOTPEnabled bool   `json:"totp_enabled" db:"totp_enabled" gorm:"default:false;not null"`
+	// TOTPLastStep шаг последнего принятого кода, чтобы один код нельзя было использовать дважды
+	TOTPLastStep int64 `json:"-" db:"totp_last_step" gorm:"default:0;not null"`
 }
 
 type Order struct {
@@ -29,3 +46,243 @@ type Order struct {
 	Status  string  `json:"status" db:"status" gorm:"default:NEW;not null"`
 	Accrual float64 `json:"accrual" db:"accrual"`
 }
+
+// OrderAttempt попытка получить расчёт по заказу из системы Accrual
+type OrderAttempt struct {
+	Entity
+	OrderID    uint   `json:"order_id" db:"order_id" gorm:"not null;index"`
+	HTTPStatus int    `json:"http_status" db:"http_status"`
+	Status     string `json:"status" db:"status"`
+	Error      string `json:"error" db:"error"`
+}
+
+// RefreshToken выданный refresh-токен. Хранится только хэш токена.
+// Токены одной цепочки ротации объединены FamilyID.
+type RefreshToken struct {
+	Entity
+	UserID          uint       `json:"user_id" db:"user_id" gorm:"not null;index"`
+	FamilyID        string     `json:"family_id" db:"family_id" gorm:"not null;index"`
+	TokenHash       string     `json:"-" db:"token_hash" gorm:"unique;not null"`
+	AccessJTI       string     `json:"access_jti" db:"access_jti" gorm:"not null"`
+	AccessExpiresAt time.Time  `json:"access_expires_at" db:"access_expires_at" gorm:"not null"`
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at" gorm:"not null"`
+	UsedAt          *time.Time `json:"used_at" db:"used_at"`
+	RevokedAt       *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// Session сессия пользователя: цепочка ротации refresh-токенов (FamilyID) и устройство, на котором выполнен вход
+type Session struct {
+	Entity
+	UserID     uint       `json:"user_id" db:"user_id" gorm:"not null;index"`
+	FamilyID   string     `json:"-" db:"family_id" gorm:"unique;not null"`
+	Device     string     `json:"device" db:"device"`
+	IP         string     `json:"ip" db:"ip"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// RevokedToken отозванный access-токен. Запись нужна только до истечения срока действия токена.
+type RevokedToken struct {
+	Entity
+	JTI       string    `json:"jti" db:"jti" gorm:"unique;not null"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at" gorm:"not null"`
+}
+
+// Типы событий журнала аудита. Действия администратора записываются как метод и путь запроса.
+const (
+	AuditRegister             = "auth.register"
+	AuditLogin                = "auth.login"
+	AuditLoginTwoFactor       = "auth.login_2fa"
+	AuditLoginOIDC            = "auth.login_oidc"
+	AuditOIDCLink             = "auth.oidc_link"
+	AuditTokenRefresh         = "auth.token_refresh"
+	AuditTokenRejected        = "auth.token_rejected"
+	AuditLogout               = "auth.logout"
+	AuditSessionRevoke        = "auth.session_revoke"
+	AuditPasswordChange       = "auth.password_change"
+	AuditPasswordResetRequest = "auth.password_reset_request"
+	AuditPasswordReset        = "auth.password_reset"
+	AuditTwoFactorEnroll      = "auth.2fa_enroll"
+	AuditTwoFactorConfirm     = "auth.2fa_confirm"
+	AuditTwoFactorDisable     = "auth.2fa_disable"
+	AuditAccountDelete        = "account.delete"
+	AuditWithdraw             = "balance.withdraw"
+	AuditAPIKeyRejected       = "partner.api_key_rejected"
+)
+
+// Результаты событий журнала аудита
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	// AuditOutcomeChallenge пароль верный, но для входа нужен второй фактор
+	AuditOutcomeChallenge = "challenge"
+)
+
+// AuditEvent запись журнала аудита
+type AuditEvent struct {
+	Entity
+	ActorID   uint   `json:"actor_id" db:"actor_id" gorm:"index"`
+	Action    string `json:"action" db:"action" gorm:"not null;index"`
+	Target    string `json:"target" db:"target"`
+	IP        string `json:"ip" db:"ip" gorm:"index"`
+	UserAgent string `json:"user_agent" db:"user_agent"`
+	Status    int    `json:"status" db:"status"`
+	Outcome   string `json:"outcome" db:"outcome" gorm:"not null"`
+}
+
+// LoginThrottle счётчик неудачных попыток входа для логина или IP-адреса
+type LoginThrottle struct {
+	Entity
+	ThrottleKey   string     `json:"throttle_key" db:"throttle_key" gorm:"unique;not null"`
+	Failures      int        `json:"failures" db:"failures" gorm:"not null"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at" gorm:"not null"`
+	BlockedUntil  *time.Time `json:"blocked_until" db:"blocked_until"`
+}
+
+// PasswordResetToken одноразовый токен сброса пароля. Хранится только хэш токена.
+type PasswordResetToken struct {
+	Entity
+	UserID    uint       `json:"user_id" db:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" db:"token_hash" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+}
+
+// RecoveryCode одноразовый код восстановления на случай потери второго фактора
+type RecoveryCode struct {
+	Entity
+	UserID   uint       `json:"user_id" db:"user_id" gorm:"not null;index"`
+	CodeHash string     `json:"-" db:"code_hash" gorm:"not null"`
+	UsedAt   *time.Time `json:"used_at" db:"used_at"`
+}
+
+// LoginChallenge незавершённый вход: пароль проверен, ожидается код второго фактора
+type LoginChallenge struct {
+	Entity
+	UserID    uint       `json:"user_id" db:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" db:"token_hash" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at" gorm:"not null"`
+	Attempts  int        `json:"attempts" db:"attempts" gorm:"default:0;not null"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+}
+
+// ExternalIdentity учётная запись у внешнего провайдера OpenID Connect, привязанная к пользователю
+type ExternalIdentity struct {
+	Entity
+	Issuer  string `json:"issuer" db:"issuer" gorm:"not null;uniqueIndex:idx_issuer_subject"`
+	Subject string `json:"subject" db:"subject" gorm:"not null;uniqueIndex:idx_issuer_subject"`
+	UserID  uint   `json:"user_id" db:"user_id" gorm:"not null;index"`
+	Email   string `json:"email" db:"email"`
+}
+
+// OIDCAuthRequest начатый вход через OpenID Connect. Хранит nonce и PKCE-верификатор,
+// пока пользователь не вернётся от провайдера.
+type OIDCAuthRequest struct {
+	Entity
+	StateHash    string `json:"-" db:"state_hash" gorm:"unique;not null"`
+	Nonce        string `json:"-" db:"nonce" gorm:"not null"`
+	CodeVerifier string `json:"-" db:"code_verifier" gorm:"not null"`
+	// LinkUserID пользователь, который привязывает внешнюю учётную запись к своей. Ноль для входа.
+	LinkUserID uint       `json:"link_user_id" db:"link_user_id" gorm:"default:0;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at" gorm:"not null"`
+	UsedAt     *time.Time `json:"used_at" db:"used_at"`
+}
+
+// Области доступа ключей партнёров
+const (
+	ScopeOrdersWrite = "orders:write"
+	ScopeBalanceRead = "balance:read"
+	// ScopeWebhooksManage управление подписками на webhook
+	ScopeWebhooksManage = "webhooks:manage"
+)
+
+// Partner внешняя система, которая загружает заказы за своих клиентов
+type Partner struct {
+	Entity
+	Name string `json:"name" db:"name" gorm:"unique;not null"`
+}
+
+// APIKey ключ доступа партнёра. Хранится только хэш ключа, префикс нужен для поиска.
+type APIKey struct {
+	Entity
+	PartnerID uint   `json:"partner_id" db:"partner_id" gorm:"not null;index"`
+	Prefix    string `json:"prefix" db:"prefix" gorm:"unique;not null"`
+	KeyHash   string `json:"-" db:"key_hash" gorm:"not null"`
+	// Scopes области доступа через запятую
+	Scopes string `json:"scopes" db:"scopes" gorm:"not null"`
+	// RateLimit допустимое число запросов в минуту
+	RateLimit  int        `json:"rate_limit" db:"rate_limit" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+// PartnerUser связь клиента партнёра (ExternalID) с пользователем системы
+type PartnerUser struct {
+	Entity
+	PartnerID  uint   `json:"partner_id" db:"partner_id" gorm:"not null;uniqueIndex:idx_partner_external"`
+	ExternalID string `json:"external_id" db:"external_id" gorm:"not null;uniqueIndex:idx_partner_external"`
+	UserID     uint   `json:"user_id" db:"user_id" gorm:"not null;index"`
+}
+
+// Типы событий пользователя
+const (
+	EventOrderStatusChanged = "order.status_changed"
+	EventBalanceChanged     = "balance.changed"
+	EventWithdrawalCreated  = "withdrawal.created"
+)
+
+// UserEvent событие для пользователя: смена статуса заказа или изменение баланса.
+// Идентификатор события передаётся клиенту как id события SSE и используется для возобновления потока.
+type UserEvent struct {
+	Entity
+	UserID uint   `json:"user_id" db:"user_id" gorm:"not null;index"`
+	Type   string `json:"type" db:"type" gorm:"not null"`
+	// Payload данные события в JSON
+	Payload string `json:"payload" db:"payload" gorm:"type:text;not null"`
+}
+
+// Типы событий, на которые партнёр может подписать webhook
+const (
+	WebhookOrderProcessed    = "order.processed"
+	WebhookOrderInvalid      = "order.invalid"
+	WebhookWithdrawalCreated = "withdrawal.created"
+)
+
+// Состояния доставки webhook
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription адрес партнёра, куда отправляются события его клиентов.
+// Secret хранится открыто: им подписывается каждая доставка.
+type WebhookSubscription struct {
+	Entity
+	PartnerID uint   `json:"partner_id" db:"partner_id" gorm:"not null;index"`
+	URL       string `json:"url" db:"url" gorm:"not null"`
+	Secret    string `json:"-" db:"secret" gorm:"not null"`
+	// EventTypes типы событий через запятую
+	EventTypes string `json:"event_types" db:"event_types" gorm:"not null"`
+}
+
+// WebhookDelivery доставка события по подписке. Неудачные попытки повторяются,
+// пока доставка не удастся или не кончатся попытки.
+type WebhookDelivery struct {
+	Entity
+	SubscriptionID uint   `json:"subscription_id" db:"subscription_id" gorm:"not null;index"`
+	PartnerID      uint   `json:"partner_id" db:"partner_id" gorm:"not null;index"`
+	EventType      string `json:"event_type" db:"event_type" gorm:"not null"`
+	// Payload тело запроса в JSON, одинаковое для всех попыток
+	Payload  string `json:"payload" db:"payload" gorm:"type:text;not null"`
+	Status   string `json:"status" db:"status" gorm:"not null;index:idx_webhook_delivery_due"`
+	Attempts int    `json:"attempts" db:"attempts" gorm:"default:0;not null"`
+	// NextAttemptAt когда доставку можно пробовать снова. Взятая в работу доставка сдвигается вперёд,
+	// чтобы её не отправил второй обработчик.
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at" gorm:"not null;index:idx_webhook_delivery_due"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at" db:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status" db:"response_status"`
+	Error          string     `json:"error" db:"error"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+}
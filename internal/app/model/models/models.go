@@ -5,6 +5,61 @@ import "time"
 type AuthRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetRequest struct {
+	Login string `json:"login"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordChangeRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginChallengeResponse ответ на вход с паролем, когда нужен ещё код второго фактора
+type LoginChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type LoginChallengeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type NewOrderRequest struct {
@@ -20,6 +75,68 @@ type AllOrderResponse struct {
 	UploadedAt   string    `json:"uploaded_at"`
 }
 
+// Результаты обработки номера заказа при пакетной загрузке
+const (
+	BatchResultAccepted         = "accepted"
+	BatchResultDuplicateOwn     = "duplicate-own"
+	BatchResultDuplicateForeign = "duplicate-foreign"
+	BatchResultInvalidLuhn      = "invalid-luhn"
+)
+
+type BatchOrderResult struct {
+	Number string `json:"number"`
+	Result string `json:"result"`
+}
+
+type BatchOrderResponse struct {
+	Results []BatchOrderResult `json:"results"`
+}
+
+type OrderResponse struct {
+	Number     string                 `json:"number"`
+	Status     string                 `json:"status"`
+	Accrual    float64                `json:"accrual,omitempty"`
+	UploadedAt string                 `json:"uploaded_at"`
+	Attempts   []OrderAttemptResponse `json:"attempts"`
+}
+
+type OrderAttemptResponse struct {
+	AttemptedAt string `json:"attempted_at"`
+	HTTPStatus  int    `json:"http_status,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type AdminUserResponse struct {
+	ID        uint   `json:"id"`
+	Login     string `json:"login"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AdminUserPage struct {
+	Items      []AdminUserResponse
+	NextCursor string
+}
+
+type AdminUserDetailsResponse struct {
+	AdminUserResponse
+	Balance          float64 `json:"balance"`
+	Withdrawn        float64 `json:"withdrawn"`
+	OrdersCount      int64   `json:"orders_count"`
+	WithdrawalsCount int64   `json:"withdrawals_count"`
+}
+
+// AdminBalanceResponse баланс пользователя вместе с суммами по заказам и списаниям,
+// по которым можно сверить текущий баланс
+type AdminBalanceResponse struct {
+	UserID           uint    `json:"user_id"`
+	Current          float64 `json:"current"`
+	Withdrawn        float64 `json:"withdrawn"`
+	Accrued          float64 `json:"accrued"`
+	WithdrawalsTotal float64 `json:"withdrawals_total"`
+}
+
 type BalanceResponse struct {
 	Current   float64 `json:"current"`
 	Withdrawn float64 `json:"withdrawn"`
@@ -29,6 +146,8 @@ type WithdrawRequest struct {
 	UserID uint    `json:"-"`
 	Order  string  `json:"order"`
 	Sum    float64 `json:"sum"`
+	// TOTPCode код второго фактора, обязателен для списаний выше порога
+	TOTPCode string `json:"totp_code,omitempty"`
 }
 
 type WithdrawResponse struct {
@@ -37,3 +156,183 @@ type WithdrawResponse struct {
 	ProcessedDate time.Time `json:"-"`
 	ProcessedAt   string    `json:"processed_at"`
 }
+
+// Problem тело ответа об ошибке в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	// Violations перечень нарушенных правил для ошибок валидации
+	Violations []string `json:"violations,omitempty"`
+}
+
+type OrderPage struct {
+	Items      []AllOrderResponse
+	NextCursor string
+}
+
+type WithdrawPage struct {
+	Items      []WithdrawResponse
+	NextCursor string
+}
+
+type PartnerRequest struct {
+	Name string `json:"name"`
+}
+
+type PartnerResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Scopes []string `json:"scopes"`
+	// RateLimit запросов в минуту, если не указан, используется значение по умолчанию
+	RateLimit int `json:"rate_limit,omitempty"`
+}
+
+// APIKeyResponse описание ключа партнёра. Сам ключ возвращается только при создании.
+type APIKeyResponse struct {
+	ID        uint     `json:"id"`
+	Key       string   `json:"key,omitempty"`
+	Prefix    string   `json:"prefix"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rate_limit"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type PartnerUserLinkRequest struct {
+	UserID uint `json:"user_id"`
+}
+
+// Виды записей истории баланса
+const (
+	LedgerEntryAccrual    = "accrual"
+	LedgerEntryWithdrawal = "withdrawal"
+)
+
+// LedgerEntry изменение баланса: начисление по заказу или списание
+type LedgerEntry struct {
+	Type    string  `json:"type"`
+	Order   string  `json:"order"`
+	Amount  float64 `json:"amount"`
+	Balance float64 `json:"balance"`
+	At      string  `json:"at"`
+}
+
+type UserProfileExport struct {
+	ID        uint    `json:"id"`
+	Login     string  `json:"login"`
+	Email     string  `json:"email,omitempty"`
+	Role      string  `json:"role"`
+	CreatedAt string  `json:"created_at"`
+	Balance   float64 `json:"balance"`
+	Withdrawn float64 `json:"withdrawn"`
+}
+
+// UserDataExport все данные пользователя для выгрузки по его запросу
+type UserDataExport struct {
+	ExportedAt  string             `json:"exported_at"`
+	User        UserProfileExport  `json:"user"`
+	Orders      []AllOrderResponse `json:"orders"`
+	Withdrawals []WithdrawResponse `json:"withdrawals"`
+	Ledger      []LedgerEntry      `json:"ledger"`
+}
+
+type AuditEventResponse struct {
+	ID        uint   `json:"id"`
+	ActorID   uint   `json:"actor_id,omitempty"`
+	Action    string `json:"action"`
+	Target    string `json:"target,omitempty"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Status    int    `json:"status"`
+	Outcome   string `json:"outcome"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AuditEventPage struct {
+	Items      []AuditEventResponse
+	NextCursor string
+}
+
+type SessionResponse struct {
+	ID         uint   `json:"id"`
+	Device     string `json:"device"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	// Current сессия, из которой сделан запрос
+	Current bool `json:"current"`
+}
+
+type OIDCAuthorizationResponse struct {
+	// AuthorizationURL адрес провайдера, куда нужно отправить пользователя
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OrderStatusEvent данные события смены статуса заказа
+type OrderStatusEvent struct {
+	Number  string  `json:"number"`
+	Status  string  `json:"status"`
+	Accrual float64 `json:"accrual,omitempty"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret ключ подписи доставок, если не указан, генерируется
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookSubscriptionResponse описание подписки. Секрет возвращается только при создании.
+type WebhookSubscriptionResponse struct {
+	ID         uint     `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint   `json:"id"`
+	EventType      string `json:"event_type"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	LastAttemptAt  string `json:"last_attempt_at,omitempty"`
+	// NextAttemptAt время следующей попытки, только для ожидающих доставок
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+	DeliveredAt   string `json:"delivered_at,omitempty"`
+}
+
+type WebhookDeliveryPage struct {
+	Items      []WebhookDeliveryResponse
+	NextCursor string
+}
+
+// WebhookPayload тело запроса, которое получает партнёр
+type WebhookPayload struct {
+	Type      string `json:"type"`
+	CreatedAt string `json:"created_at"`
+	Data      any    `json:"data"`
+}
+
+// WebhookOrderData данные событий order.processed и order.invalid
+type WebhookOrderData struct {
+	ExternalID string `json:"external_id"`
+	OrderStatusEvent
+}
+
+// WebhookWithdrawalData данные события withdrawal.created
+type WebhookWithdrawalData struct {
+	ExternalID  string  `json:"external_id"`
+	Order       string  `json:"order"`
+	Sum         float64 `json:"sum"`
+	ProcessedAt string  `json:"processed_at"`
+}
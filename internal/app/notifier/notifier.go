@@ -0,0 +1,48 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier только отмечает в логе, что сообщение было бы отправлено. Текст не пишется:
+// в нём бывают токены сброса пароля, а логи читает больше людей, чем почту пользователя.
+// Чтобы прочитать сами сообщения при разработке, нужен FileNotifier.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, notification dto.Notification) error {
+	logger.FromContext(ctx).Infof("notification to %s: %s (body not logged)", notification.To, notification.Subject)
+	return nil
+}
+
+// FileNotifier дописывает сообщения в файл, чтобы их можно было прочитать в тестах без почтового сервера
+type FileNotifier struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Send(ctx context.Context, notification dto.Notification) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), notification.To, notification.Subject, notification.Body)
+	return err
+}
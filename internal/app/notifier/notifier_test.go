@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	n := NewFileNotifier(path)
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := n.Send(context.Background(), dto.Notification{To: to, Subject: "Password reset", Body: "token"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"To: alice@example.com", "To: bob@example.com", "Subject: Password reset"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("notification file does not contain %q:\n%s", expected, content)
+		}
+	}
+}
+
+func TestSMTPNotifier_Message(t *testing.T) {
+	n, err := NewSMTPNotifier("localhost:25", "", "", "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := string(n.message(dto.Notification{To: "alice@example.com", Subject: "Сброс пароля", Body: "line 1\nline 2"}))
+	if !strings.Contains(message, "To: alice@example.com\r\n") || !strings.Contains(message, "line 1\r\nline 2") {
+		t.Errorf("unexpected message:\n%s", message)
+	}
+	if strings.Contains(message, "Сброс") {
+		t.Error("non-ASCII subject must be encoded")
+	}
+	if _, err := NewSMTPNotifier("localhost", "", "", ""); err == nil {
+		t.Error("address without port must be rejected")
+	}
+}
+
+func TestSMTPNotifier_SendTimeout(t *testing.T) {
+	// сервер принимает соединение, но так и не присылает приветствие
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+	n, err := NewSMTPNotifier(listener.Addr().String(), "", "", "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := n.Send(ctx, dto.Notification{To: "alice@example.com", Subject: "Password reset", Body: "token"}); err == nil {
+		t.Error("expected a timeout error")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("send did not respect the deadline, took %s", elapsed)
+	}
+}
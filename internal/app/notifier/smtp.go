@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout предел на всю отправку одного сообщения, если контекст не задаёт меньший
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier отправляет сообщения по электронной почте
+type SMTPNotifier struct {
+	address string
+	host    string
+	from    string
+	auth    smtp.Auth
+}
+
+// NewSMTPNotifier address в формате host:port. Если username пустой, сервер используется без авторизации.
+func NewSMTPNotifier(address string, username string, password string, from string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", address, err)
+	}
+	n := &SMTPNotifier{address: address, host: host, from: from}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n, nil
+}
+
+// Send делает то же, что smtp.SendMail, но с ограничением по времени: зависший почтовый сервер
+// не должен копить горутины отправки
+func (n *SMTPNotifier) Send(ctx context.Context, notification dto.Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(notification.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(notification)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (n *SMTPNotifier) message(notification dto.Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", notification.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,24 @@
+// Package openapi содержит описание HTTP API сервиса в формате OpenAPI 3.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var specification []byte
+
+// Load разбирает встроенный документ и проверяет, что он сам соответствует спецификации OpenAPI
+func Load() (*openapi3.T, error) {
+	document, err := openapi3.NewLoader().LoadFromData(specification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := document.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return document, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// AccountService удаление учётной записи и выгрузка данных пользователя
+type AccountService struct {
+	userRepository     UserRepository
+	orderRepository    OrderRepository
+	withdrawRepository WithdrawRepository
+}
+
+func NewAccountService(
+	userRepository UserRepository,
+	orderRepository OrderRepository,
+	withdrawRepository WithdrawRepository,
+) *AccountService {
+	return &AccountService{
+		userRepository:     userRepository,
+		orderRepository:    orderRepository,
+		withdrawRepository: withdrawRepository,
+	}
+}
+
+// DeleteAccount помечает пользователя удалённым и обезличивает его. Логин заменяется на
+// уникальный служебный с зарезервированным префиксом, поэтому прежний логин снова можно
+// зарегистрировать, а служебный - нет.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "AccountService.DeleteAccount")
+	defer span.End()
+	deleted, err := s.userRepository.SoftDeleteUser(ctx, userID, fmt.Sprintf("%s%d", deletedLoginPrefix, userID))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.Newf(apperrors.CodeNotFound, "user %d not found", userID)
+	}
+	return nil
+}
+
+// ExportData собирает профиль, заказы, списания и историю изменений баланса пользователя
+func (s *AccountService) ExportData(ctx context.Context, userID uint) (models.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ExportData")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.UserDataExport{}, apperrors.Newf(apperrors.CodeNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		return models.UserDataExport{}, err
+	}
+	orders, err := s.orderRepository.GetAllOrders(ctx, userID)
+	if err != nil {
+		return models.UserDataExport{}, err
+	}
+	withdrawals, err := s.withdrawRepository.GetWithdrawals(ctx, userID)
+	if err != nil {
+		return models.UserDataExport{}, err
+	}
+	export := models.UserDataExport{
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		User: models.UserProfileExport{
+			ID:        user.ID,
+			Login:     user.UserName,
+			Email:     user.Email,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt.Format(time.RFC3339),
+			Balance:   user.Balance,
+			Withdrawn: user.Withdrawn,
+		},
+		Orders:      make([]models.AllOrderResponse, 0, len(orders)),
+		Withdrawals: make([]models.WithdrawResponse, 0, len(withdrawals)),
+		Ledger:      buildLedger(orders, withdrawals),
+	}
+	for _, order := range orders {
+		export.Orders = append(export.Orders, models.AllOrderResponse{
+			Number:       order.Number,
+			Status:       order.Status,
+			Accrual:      order.Accrual,
+			UploadedDate: order.CreatedAt,
+			UploadedAt:   order.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	for _, withdraw := range withdrawals {
+		export.Withdrawals = append(export.Withdrawals, models.WithdrawResponse{
+			Order:         withdraw.OrderNumber,
+			Sum:           withdraw.Sum,
+			ProcessedDate: withdraw.CreatedAt,
+			ProcessedAt:   withdraw.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return export, nil
+}
+
+type ledgerEvent struct {
+	entry models.LedgerEntry
+	at    time.Time
+}
+
+// buildLedger восстанавливает историю баланса по начислениям за заказы и списаниям.
+// Время начисления не хранится, поэтому используется время последнего изменения заказа.
+func buildLedger(orders []entities.Order, withdrawals []entities.Withdraw) []models.LedgerEntry {
+	events := make([]ledgerEvent, 0, len(orders)+len(withdrawals))
+	for _, order := range orders {
+		if order.Accrual <= 0 {
+			continue
+		}
+		events = append(events, ledgerEvent{
+			entry: models.LedgerEntry{Type: models.LedgerEntryAccrual, Order: order.Number, Amount: order.Accrual},
+			at:    order.UpdatedAt,
+		})
+	}
+	for _, withdraw := range withdrawals {
+		events = append(events, ledgerEvent{
+			entry: models.LedgerEntry{Type: models.LedgerEntryWithdrawal, Order: withdraw.OrderNumber, Amount: -withdraw.Sum},
+			at:    withdraw.CreatedAt,
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	ledger := make([]models.LedgerEntry, 0, len(events))
+	var balance float64
+	for _, event := range events {
+		balance += event.entry.Amount
+		event.entry.Balance = balance
+		event.entry.At = event.at.Format(time.RFC3339)
+		ledger = append(ledger, event.entry)
+	}
+	return ledger
+}
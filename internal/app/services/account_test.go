@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	service := NewAccountService(users, mocks.NewMockOrderRepository(ctrl), mocks.NewMockWithdrawRepository(ctrl))
+
+	users.EXPECT().SoftDeleteUser(gomock.Any(), uint(7), "deleted-user-7").Return(true, nil)
+	if err := service.DeleteAccount(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+
+	users.EXPECT().SoftDeleteUser(gomock.Any(), uint(7), "deleted-user-7").Return(false, nil)
+	if err := service.DeleteAccount(context.Background(), 7); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestAccountService_ExportData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	orders := mocks.NewMockOrderRepository(ctrl)
+	withdrawals := mocks.NewMockWithdrawRepository(ctrl)
+	service := NewAccountService(users, orders, withdrawals)
+
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).
+		Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7, CreatedAt: day(1)}}, UserName: "alice", Balance: 70, Withdrawn: 30}, nil)
+	orders.EXPECT().GetAllOrders(gomock.Any(), uint(7)).Return([]entities.Order{
+		{Entity: entities.Entity{Model: gorm.Model{CreatedAt: day(2), UpdatedAt: day(3)}}, Number: "1", Status: "PROCESSED", Accrual: 100},
+		{Entity: entities.Entity{Model: gorm.Model{CreatedAt: day(5), UpdatedAt: day(5)}}, Number: "2", Status: "NEW"},
+	}, nil)
+	withdrawals.EXPECT().GetWithdrawals(gomock.Any(), uint(7)).Return([]entities.Withdraw{
+		{Entity: entities.Entity{Model: gorm.Model{CreatedAt: day(4)}}, OrderNumber: "3", Sum: 30},
+	}, nil)
+
+	export, err := service.ExportData(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.User.Login != "alice" || len(export.Orders) != 2 || len(export.Withdrawals) != 1 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+	expectedLedger := []models.LedgerEntry{
+		{Type: models.LedgerEntryAccrual, Order: "1", Amount: 100, Balance: 100, At: "2023-01-03T00:00:00Z"},
+		{Type: models.LedgerEntryWithdrawal, Order: "3", Amount: -30, Balance: 70, At: "2023-01-04T00:00:00Z"},
+	}
+	if !reflect.DeepEqual(export.Ledger, expectedLedger) {
+		t.Errorf("unexpected ledger: %+v", export.Ledger)
+	}
+
+	users.EXPECT().FindUserByID(gomock.Any(), uint(8)).Return(entities.User{}, gorm.ErrRecordNotFound)
+	if _, err := service.ExportData(context.Background(), 8); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AdminService операции администратора над чужими учётными записями
+type AdminService struct {
+	userRepository     UserRepository
+	orderRepository    OrderRepository
+	withdrawRepository WithdrawRepository
+}
+
+func NewAdminService(
+	userRepository UserRepository,
+	orderRepository OrderRepository,
+	withdrawRepository WithdrawRepository,
+) *AdminService {
+	return &AdminService{
+		userRepository:     userRepository,
+		orderRepository:    orderRepository,
+		withdrawRepository: withdrawRepository,
+	}
+}
+
+// SearchUsers ищет пользователей по подстроке логина. Выдача идёт в порядке регистрации.
+func (s *AdminService) SearchUsers(ctx context.Context, query dto.UserSearchQuery) (models.AdminUserPage, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SearchUsers")
+	defer span.End()
+	after, err := decodeCursor(query.Cursor, false, query.Query)
+	if err != nil {
+		return models.AdminUserPage{}, err
+	}
+	users, err := s.userRepository.SearchUsers(ctx, query.Query, after, query.Limit+1)
+	if err != nil {
+		return models.AdminUserPage{}, err
+	}
+	var page models.AdminUserPage
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
+		last := users[len(users)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID, false, query.Query)
+	}
+	page.Items = make([]models.AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		page.Items = append(page.Items, newAdminUserResponse(user))
+	}
+	return page, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID uint) (models.AdminUserDetailsResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetUser")
+	defer span.End()
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return models.AdminUserDetailsResponse{}, err
+	}
+	orders, err := s.orderRepository.GetUserOrderStats(ctx, userID)
+	if err != nil {
+		return models.AdminUserDetailsResponse{}, err
+	}
+	withdrawals, err := s.withdrawRepository.GetUserWithdrawStats(ctx, userID)
+	if err != nil {
+		return models.AdminUserDetailsResponse{}, err
+	}
+	return models.AdminUserDetailsResponse{
+		AdminUserResponse: newAdminUserResponse(user),
+		Balance:           user.Balance,
+		Withdrawn:         user.Withdrawn,
+		OrdersCount:       orders.Count,
+		WithdrawalsCount:  withdrawals.Count,
+	}, nil
+}
+
+// GetUserBalance баланс пользователя и суммы, из которых он должен складываться
+func (s *AdminService) GetUserBalance(ctx context.Context, userID uint) (models.AdminBalanceResponse, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetUserBalance")
+	defer span.End()
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return models.AdminBalanceResponse{}, err
+	}
+	orders, err := s.orderRepository.GetUserOrderStats(ctx, userID)
+	if err != nil {
+		return models.AdminBalanceResponse{}, err
+	}
+	withdrawals, err := s.withdrawRepository.GetUserWithdrawStats(ctx, userID)
+	if err != nil {
+		return models.AdminBalanceResponse{}, err
+	}
+	return models.AdminBalanceResponse{
+		UserID:           user.ID,
+		Current:          user.Balance,
+		Withdrawn:        user.Withdrawn,
+		Accrued:          orders.Sum,
+		WithdrawalsTotal: withdrawals.Sum,
+	}, nil
+}
+
+func (s *AdminService) findUser(ctx context.Context, userID uint) (entities.User, error) {
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entities.User{}, apperrors.Newf(apperrors.CodeNotFound, "user %d not found", userID)
+	}
+	return user, err
+}
+
+func newAdminUserResponse(user entities.User) models.AdminUserResponse {
+	return models.AdminUserResponse{
+		ID:        user.ID,
+		Login:     user.UserName,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAdminService_SearchUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	service := NewAdminService(users, mocks.NewMockOrderRepository(ctrl), mocks.NewMockWithdrawRepository(ctrl))
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	found := []entities.User{
+		{Entity: entities.Entity{Model: gorm.Model{ID: 1, CreatedAt: createdAt}}, UserName: "alice", Role: entities.RoleUser},
+		{Entity: entities.Entity{Model: gorm.Model{ID: 2, CreatedAt: createdAt}}, UserName: "alina", Role: entities.RoleAdmin},
+	}
+
+	users.EXPECT().SearchUsers(gomock.Any(), "ali", nil, 2).Return(found, nil)
+	page, err := service.SearchUsers(context.Background(), dto.UserSearchQuery{Query: "ali", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Login != "alice" || page.NextCursor == "" {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+
+	users.EXPECT().SearchUsers(gomock.Any(), "ali", &dto.PageCursor{CreatedAt: createdAt, ID: 1}, 2).Return(found[1:], nil)
+	page, err = service.SearchUsers(context.Background(), dto.UserSearchQuery{Query: "ali", Limit: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Role != entities.RoleAdmin || page.NextCursor != "" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+}
+
+func TestAdminService_GetUserBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	orders := mocks.NewMockOrderRepository(ctrl)
+	withdrawals := mocks.NewMockWithdrawRepository(ctrl)
+	service := NewAdminService(users, orders, withdrawals)
+
+	users.EXPECT().FindUserByID(gomock.Any(), uint(5)).
+		Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 5}}, Balance: 70, Withdrawn: 30}, nil)
+	orders.EXPECT().GetUserOrderStats(gomock.Any(), uint(5)).Return(dto.Stats{Count: 2, Sum: 100}, nil)
+	withdrawals.EXPECT().GetUserWithdrawStats(gomock.Any(), uint(5)).Return(dto.Stats{Count: 1, Sum: 30}, nil)
+	balance, err := service.GetUserBalance(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.UserID != 5 || balance.Current != 70 || balance.Accrued != 100 || balance.WithdrawalsTotal != 30 {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+
+	users.EXPECT().FindUserByID(gomock.Any(), uint(6)).Return(entities.User{}, gorm.ErrRecordNotFound)
+	if _, err := service.GetUserBalance(context.Background(), 6); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"time"
+)
+
+// AuditService журнал аудита действий пользователей и администраторов
+type AuditService struct {
+	auditRepository AuditRepository
+}
+
+func NewAuditService(auditRepository AuditRepository) *AuditService {
+	return &AuditService{auditRepository: auditRepository}
+}
+
+// Record сохраняет событие в журнал аудита
+func (s *AuditService) Record(ctx context.Context, event dto.AuditEventDTO) error {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+	return s.auditRepository.Save(ctx, &entities.AuditEvent{
+		ActorID:   event.ActorID,
+		Action:    event.Action,
+		Target:    event.Target,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Status:    event.Status,
+		Outcome:   event.Outcome,
+	})
+}
+
+// SearchEvents ищет события журнала аудита. Выдача идёт от новых событий к старым.
+func (s *AuditService) SearchEvents(ctx context.Context, query dto.AuditEventQuery) (models.AuditEventPage, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.SearchEvents")
+	defer span.End()
+	filters := query
+	filters.Cursor, filters.Limit = "", 0
+	before, err := decodeCursor(query.Cursor, true, filters)
+	if err != nil {
+		return models.AuditEventPage{}, err
+	}
+	filter := dto.AuditEventFilter{AuditEventQuery: query, Before: before}
+	filter.Limit = query.Limit + 1
+	events, err := s.auditRepository.FindEvents(ctx, filter)
+	if err != nil {
+		return models.AuditEventPage{}, err
+	}
+	var page models.AuditEventPage
+	if len(events) > query.Limit {
+		events = events[:query.Limit]
+		last := events[len(events)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID, true, filters)
+	}
+	page.Items = make([]models.AuditEventResponse, 0, len(events))
+	for _, event := range events {
+		page.Items = append(page.Items, models.AuditEventResponse{
+			ID:        event.ID,
+			ActorID:   event.ActorID,
+			Action:    event.Action,
+			Target:    event.Target,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Status:    event.Status,
+			Outcome:   event.Outcome,
+			CreatedAt: event.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAuditService_SearchEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockAuditRepository(ctrl)
+	service := NewAuditService(repository)
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []entities.AuditEvent{
+		{Entity: entities.Entity{Model: gorm.Model{ID: 9, CreatedAt: createdAt}}, Action: entities.AuditLogin, Outcome: entities.AuditOutcomeFailure},
+		{Entity: entities.Entity{Model: gorm.Model{ID: 8, CreatedAt: createdAt}}, Action: entities.AuditLogin, Outcome: entities.AuditOutcomeFailure},
+	}
+	query := dto.AuditEventQuery{Action: entities.AuditLogin, Limit: 1}
+
+	repository.EXPECT().FindEvents(gomock.Any(), dto.AuditEventFilter{
+		AuditEventQuery: dto.AuditEventQuery{Action: entities.AuditLogin, Limit: 2},
+	}).Return(events, nil)
+	page, err := service.SearchEvents(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != 9 || page.NextCursor == "" {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+
+	query.Cursor = page.NextCursor
+	repository.EXPECT().FindEvents(gomock.Any(), dto.AuditEventFilter{
+		AuditEventQuery: dto.AuditEventQuery{Action: entities.AuditLogin, Limit: 2, Cursor: page.NextCursor},
+		Before:          &dto.PageCursor{CreatedAt: createdAt, ID: 9},
+	}).Return(events[1:], nil)
+	page, err = service.SearchEvents(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != 8 || page.NextCursor != "" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes bcrypt учитывает только первые 72 байта пароля
+const maxPasswordBytes = 72
+
+// Начала служебных логинов, которые нельзя зарегистрировать: заранее занятый служебный логин
+// сорвал бы удаление учётной записи или первый вход через внешнего провайдера.
+const (
+	// deletedLoginPrefix логин удалённого пользователя
+	deletedLoginPrefix = "deleted-user-"
+	// oidcLoginPrefix логин пользователя, заведённого при входе через OIDC
+	oidcLoginPrefix = "oidc-"
+)
+
+var reservedLoginPrefixes = []string{deletedLoginPrefix, oidcLoginPrefix}
+
+// CredentialsPolicy правила для логина и пароля при регистрации и смене пароля.
+// Проверки возвращают список всех нарушений, а не только первое.
+type CredentialsPolicy struct {
+	LoginMinLength int
+	LoginMaxLength int
+	// LoginPattern допустимые символы логина, nil - любые
+	LoginPattern      *regexp.Regexp
+	PasswordMinLength int
+	// PasswordMinClasses сколько классов символов из четырёх (строчные, заглавные, цифры, прочие)
+	// должно быть в пароле
+	PasswordMinClasses int
+}
+
+func (p CredentialsPolicy) ValidateLogin(login string) []string {
+	var violations []string
+	length := utf8.RuneCountInString(login)
+	if length < p.LoginMinLength || length > p.LoginMaxLength {
+		violations = append(violations,
+			fmt.Sprintf("login must be between %d and %d characters", p.LoginMinLength, p.LoginMaxLength))
+	}
+	if p.LoginPattern != nil && login != "" && !p.LoginPattern.MatchString(login) {
+		violations = append(violations, fmt.Sprintf("login must match %s", p.LoginPattern))
+	}
+	for _, prefix := range reservedLoginPrefixes {
+		if strings.HasPrefix(strings.ToLower(login), prefix) {
+			violations = append(violations, fmt.Sprintf("login must not start with %s", prefix))
+		}
+	}
+	return violations
+}
+
+func (p CredentialsPolicy) ValidatePassword(password string, login string) []string {
+	var violations []string
+	if utf8.RuneCountInString(password) < p.PasswordMinLength {
+		violations = append(violations, fmt.Sprintf("password must be at least %d characters", p.PasswordMinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		violations = append(violations, fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes))
+	}
+	if countCharClasses(password) < p.PasswordMinClasses {
+		violations = append(violations, fmt.Sprintf(
+			"password must contain at least %d of: lowercase letters, uppercase letters, digits, other characters",
+			p.PasswordMinClasses))
+	}
+	if password != "" && password == login {
+		violations = append(violations, "password must not match the login")
+	}
+	return violations
+}
+
+// ValidateEmail адрес необязателен, но если указан, должен быть простым адресом без имени
+func (p CredentialsPolicy) ValidateEmail(email string) []string {
+	if email == "" {
+		return nil
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return []string{"email must be a valid address"}
+	}
+	return nil
+}
+
+func countCharClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"time"
+)
+
+// pageCursor содержимое курсора. Клиенту он отдаётся непрозрачной base64-строкой,
+// направление сортировки и отпечаток фильтров зашиты внутрь, чтобы курсор нельзя было
+// применить к выдаче в другом порядке или с другими фильтрами.
+type pageCursor struct {
+	CreatedAt  int64  `json:"t"`
+	ID         uint   `json:"id"`
+	Descending bool   `json:"d"`
+	Filters    string `json:"f"`
+}
+
+// encodeCursor строит курсор следующей страницы. filters - параметры выдачи без курсора
+// и размера страницы, в курсор попадает только их хэш.
+func encodeCursor(createdAt time.Time, id uint, descending bool, filters any) string {
+	raw, _ := json.Marshal(pageCursor{
+		CreatedAt:  createdAt.UnixNano(),
+		ID:         id,
+		Descending: descending,
+		Filters:    filtersHash(filters),
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func filtersHash(filters any) string {
+	raw, _ := json.Marshal(filters)
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func decodeCursor(cursor string, descending bool, filters any) (*dto.PageCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, apperrors.InvalidRequest("invalid cursor")
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == 0 {
+		return nil, apperrors.InvalidRequest("invalid cursor")
+	}
+	if c.Descending != descending {
+		return nil, apperrors.InvalidRequest("cursor does not match sort order")
+	}
+	if c.Filters != filtersHash(filters) {
+		return nil, apperrors.InvalidRequest("cursor does not match the request filters")
+	}
+	return &dto.PageCursor{CreatedAt: time.Unix(0, c.CreatedAt).UTC(), ID: c.ID}, nil
+}
+
+// newPageFilter готовит фильтр для репозитория. Запрашивается на одну запись больше,
+// чтобы понять, есть ли следующая страница.
+func newPageFilter(query dto.PageQuery) (dto.PageFilter, error) {
+	after, err := decodeCursor(query.Cursor, query.Descending, pageFilters(query))
+	if err != nil {
+		return dto.PageFilter{}, err
+	}
+	filter := dto.PageFilter{PageQuery: query, After: after}
+	filter.Limit = query.Limit + 1
+	return filter, nil
+}
+
+// pageFilters параметры выдачи заказов или списаний, к которым привязан курсор
+func pageFilters(query dto.PageQuery) dto.PageQuery {
+	query.Cursor = ""
+	query.Limit = 0
+	return query
+}
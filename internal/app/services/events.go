@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"sync"
+)
+
+const (
+	// eventBufferSize сколько непрочитанных событий может накопить подписчик, прежде чем его отключат
+	eventBufferSize = 64
+	// maxMissedEvents сколько пропущенных событий отдаётся при возобновлении потока
+	maxMissedEvents = 1000
+)
+
+// EventBus внутренняя шина событий пользователя. События сохраняются в базе, чтобы клиент
+// мог получить пропущенное после переподключения, и рассылаются активным подпискам.
+type EventBus struct {
+	repository  EventRepository
+	mutex       sync.Mutex
+	subscribers map[uint]map[chan entities.UserEvent]struct{}
+	listeners   []func(ctx context.Context, event entities.UserEvent) error
+}
+
+func NewEventBus(repository EventRepository) *EventBus {
+	return &EventBus{
+		repository:  repository,
+		subscribers: make(map[uint]map[chan entities.UserEvent]struct{}),
+	}
+}
+
+// Publish сохраняет событие и рассылает его подпискам пользователя. Подписка, которая не успевает
+// читать события, закрывается: клиент переподключится с Last-Event-ID и получит пропущенное из базы.
+func (b *EventBus) Publish(ctx context.Context, userID uint, eventType string, payload any) error {
+	ctx, span := tracing.Start(ctx, "EventBus.Publish")
+	defer span.End()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	event := entities.UserEvent{
+		UserID:  userID,
+		Type:    eventType,
+		Payload: string(data),
+	}
+	if err := b.repository.SaveEvent(ctx, &event); err != nil {
+		return fmt.Errorf("failed to save %s event: %w", eventType, err)
+	}
+	for _, listener := range b.listeners {
+		if err := listener(ctx, event); err != nil {
+			logger.FromContext(ctx).Errorf("failed to handle %s event %d: %v", eventType, event.ID, err)
+		}
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for subscriber := range b.subscribers[userID] {
+		select {
+		case subscriber <- event:
+		default:
+			b.unsubscribe(userID, subscriber)
+		}
+	}
+	return nil
+}
+
+// AddListener добавляет обработчик, который получает каждое сохранённое событие до рассылки подпискам.
+// Ошибка обработчика не отменяет публикацию. Обработчики добавляются при запуске, до первой публикации.
+func (b *EventBus) AddListener(listener func(ctx context.Context, event entities.UserEvent) error) {
+	b.listeners = append(b.listeners, listener)
+}
+
+// PublishLogged публикует событие, записывая ошибку в лог. Для мест, где событие не должно
+// влиять на результат уже выполненной операции.
+func (b *EventBus) PublishLogged(ctx context.Context, userID uint, eventType string, payload any) {
+	if err := b.Publish(ctx, userID, eventType, payload); err != nil {
+		logger.FromContext(ctx).Errorf("failed to publish event for user %d: %v", userID, err)
+	}
+}
+
+// Subscribe подписывает на события пользователя. Если lastEventID не ноль, в подписку попадают
+// и сохранённые события после него. Подписка оформляется до чтения пропущенных событий,
+// поэтому одно событие может прийти и в Missed, и в Events.
+func (b *EventBus) Subscribe(ctx context.Context, userID uint, lastEventID uint) (dto.EventSubscription, error) {
+	subscriber := make(chan entities.UserEvent, eventBufferSize)
+	b.mutex.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan entities.UserEvent]struct{})
+	}
+	b.subscribers[userID][subscriber] = struct{}{}
+	b.mutex.Unlock()
+	cancel := func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.unsubscribe(userID, subscriber)
+	}
+
+	var missed []entities.UserEvent
+	if lastEventID > 0 {
+		var err error
+		missed, err = b.repository.FindEventsAfter(ctx, userID, lastEventID, maxMissedEvents)
+		if err != nil {
+			cancel()
+			return dto.EventSubscription{}, err
+		}
+	}
+	return dto.EventSubscription{Missed: missed, Events: subscriber, Cancel: cancel}, nil
+}
+
+// unsubscribe снимает подписку и закрывает её канал. Вызывается под mutex.
+func (b *EventBus) unsubscribe(userID uint, subscriber chan entities.UserEvent) {
+	if _, ok := b.subscribers[userID][subscriber]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], subscriber)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+	close(subscriber)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestEventBus_PublishAndSubscribe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockEventRepository(ctrl)
+	bus := NewEventBus(repository)
+	var nextID uint = 10
+	repository.EXPECT().SaveEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event *entities.UserEvent) error {
+		nextID++
+		event.ID = nextID
+		return nil
+	}).AnyTimes()
+
+	missed := []entities.UserEvent{{Entity: entities.Entity{Model: gorm.Model{ID: 6}}, UserID: 7, Type: entities.EventBalanceChanged}}
+	repository.EXPECT().FindEventsAfter(gomock.Any(), uint(7), uint(5), maxMissedEvents).Return(missed, nil)
+	resumed, err := bus.Subscribe(context.Background(), 7, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Cancel()
+	if len(resumed.Missed) != 1 || resumed.Missed[0].ID != 6 {
+		t.Fatalf("unexpected missed events: %+v", resumed.Missed)
+	}
+	fresh, err := bus.Subscribe(context.Background(), 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := bus.Subscribe(context.Background(), 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Cancel()
+
+	status := models.OrderStatusEvent{Number: "12345678903", Status: "PROCESSED", Accrual: 500}
+	if err := bus.Publish(context.Background(), 7, entities.EventOrderStatusChanged, status); err != nil {
+		t.Fatal(err)
+	}
+	for _, events := range []<-chan entities.UserEvent{resumed.Events, fresh.Events} {
+		event := <-events
+		if event.ID != 11 || event.Type != entities.EventOrderStatusChanged ||
+			event.Payload != `{"number":"12345678903","status":"PROCESSED","accrual":500}` {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}
+	select {
+	case event := <-other.Events:
+		t.Errorf("event of another user delivered: %+v", event)
+	default:
+	}
+
+	fresh.Cancel()
+	if _, ok := <-fresh.Events; ok {
+		t.Error("cancelled subscription is still open")
+	}
+	fresh.Cancel()
+}
+
+func TestEventBus_SlowSubscriberIsClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockEventRepository(ctrl)
+	bus := NewEventBus(repository)
+	repository.EXPECT().SaveEvent(gomock.Any(), gomock.Any()).Return(nil).Times(eventBufferSize + 1)
+	subscription, err := bus.Subscribe(context.Background(), 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Cancel()
+
+	for i := 0; i <= eventBufferSize; i++ {
+		if err := bus.Publish(context.Background(), 7, entities.EventBalanceChanged, models.BalanceResponse{Current: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+	if received != eventBufferSize {
+		t.Errorf("expected %d buffered events before close, got %d", eventBufferSize, received)
+	}
+}
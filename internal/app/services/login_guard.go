@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"strconv"
+	"time"
+)
+
+// LoginPolicy правила ограничения попыток входа для одного вида счётчика
+type LoginPolicy struct {
+	// FreeAttempts число неудач, после которых ещё нет задержки
+	FreeAttempts int
+	// MaxFailures число неудач, после которого ключ блокируется на Lockout
+	MaxFailures int
+	// BaseDelay задержка после первой неудачи сверх FreeAttempts, каждая следующая удваивает её
+	BaseDelay time.Duration
+	// Lockout длительность блокировки. Неудачи старше Lockout перестают учитываться.
+	Lockout time.Duration
+}
+
+// delay через сколько после неудачи с номером failures разрешена следующая попытка
+func (p LoginPolicy) delay(failures int) time.Duration {
+	if failures >= p.MaxFailures {
+		return p.Lockout
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay << (failures - p.FreeAttempts - 1)
+	if delay <= 0 || delay > p.Lockout {
+		return p.Lockout
+	}
+	return delay
+}
+
+// LoginGuard считает неудачные попытки входа отдельно по логину и по IP-адресу
+// и временно запрещает вход, когда их становится слишком много.
+type LoginGuard struct {
+	repository  LoginThrottleRepository
+	loginPolicy LoginPolicy
+	ipPolicy    LoginPolicy
+}
+
+func NewLoginGuard(repository LoginThrottleRepository, loginPolicy LoginPolicy, ipPolicy LoginPolicy) *LoginGuard {
+	return &LoginGuard{
+		repository:  repository,
+		loginPolicy: loginPolicy,
+		ipPolicy:    ipPolicy,
+	}
+}
+
+type throttleKey struct {
+	key    string
+	policy LoginPolicy
+}
+
+func (g *LoginGuard) keys(login string, ip string) []throttleKey {
+	keys := []throttleKey{{key: "login:" + login, policy: g.loginPolicy}}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: "ip:" + ip, policy: g.ipPolicy})
+	}
+	return keys
+}
+
+// Check возвращает ошибку, если вход для логина или IP-адреса сейчас запрещён
+func (g *LoginGuard) Check(ctx context.Context, login string, ip string) error {
+	return g.check(ctx, g.keys(login, ip))
+}
+
+// RegisterFailure учитывает неудачную попытку и при необходимости блокирует дальнейшие
+func (g *LoginGuard) RegisterFailure(ctx context.Context, login string, ip string) error {
+	return g.registerFailure(ctx, g.keys(login, ip))
+}
+
+// twoFactorKeys счётчик неверных кодов второго фактора пользователя. Действует по правилам
+// для логина: код проверяется уже после входа, и без счётчика его можно было бы подобрать
+// с украденным access-токеном.
+func (g *LoginGuard) twoFactorKeys(userID uint) []throttleKey {
+	return []throttleKey{{key: "2fa:" + strconv.FormatUint(uint64(userID), 10), policy: g.loginPolicy}}
+}
+
+// CheckTwoFactor возвращает ошибку, если ввод кодов второго фактора пользователем сейчас запрещён
+func (g *LoginGuard) CheckTwoFactor(ctx context.Context, userID uint) error {
+	return g.check(ctx, g.twoFactorKeys(userID))
+}
+
+// RegisterTwoFactorFailure учитывает неверный код второго фактора
+func (g *LoginGuard) RegisterTwoFactorFailure(ctx context.Context, userID uint) error {
+	return g.registerFailure(ctx, g.twoFactorKeys(userID))
+}
+
+// RegisterTwoFactorSuccess сбрасывает счётчик неверных кодов второго фактора
+func (g *LoginGuard) RegisterTwoFactorSuccess(ctx context.Context, userID uint) error {
+	return g.repository.ResetLoginFailures(ctx, []string{g.twoFactorKeys(userID)[0].key})
+}
+
+// resetKeys счётчики запросов сброса пароля. Считается каждый запрос, а не только неудачный:
+// иначе можно было бы без ограничений слать письма чужому пользователю.
+func (g *LoginGuard) resetKeys(login string, ip string) []throttleKey {
+	keys := g.keys(login, ip)
+	for i := range keys {
+		keys[i].key = "reset:" + keys[i].key
+	}
+	return keys
+}
+
+// CheckReset возвращает ошибку, если запросы сброса пароля для логина или IP-адреса сейчас запрещены
+func (g *LoginGuard) CheckReset(ctx context.Context, login string, ip string) error {
+	return g.check(ctx, g.resetKeys(login, ip))
+}
+
+// RegisterReset учитывает запрос сброса пароля
+func (g *LoginGuard) RegisterReset(ctx context.Context, login string, ip string) error {
+	return g.registerFailure(ctx, g.resetKeys(login, ip))
+}
+
+func (g *LoginGuard) check(ctx context.Context, keys []throttleKey) error {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.key)
+	}
+	throttles, err := g.repository.FindLoginThrottles(ctx, names)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if throttle.BlockedUntil != nil && throttle.BlockedUntil.Sub(now) > wait {
+			wait = throttle.BlockedUntil.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return apperrors.TooManyAttempts(wait)
+	}
+	return nil
+}
+
+func (g *LoginGuard) registerFailure(ctx context.Context, keys []throttleKey) error {
+	now := time.Now()
+	for _, k := range keys {
+		failures, err := g.repository.IncrementLoginFailures(ctx, k.key, now, now.Add(-k.policy.Lockout))
+		if err != nil {
+			return err
+		}
+		delay := k.policy.delay(failures)
+		if delay == 0 {
+			continue
+		}
+		if err := g.repository.BlockLogin(ctx, k.key, now.Add(delay)); err != nil {
+			return err
+		}
+		if failures >= k.policy.MaxFailures {
+			logger.FromContext(ctx).Warnf("locked out %s until %s after %d failed attempts",
+				k.key, now.Add(delay).Format(time.RFC3339), failures)
+		}
+	}
+	return nil
+}
+
+// RegisterSuccess сбрасывает счётчик логина. Счётчик IP-адреса не сбрасывается,
+// иначе вход в свою учётную запись позволял бы продолжать перебор чужих.
+func (g *LoginGuard) RegisterSuccess(ctx context.Context, login string) error {
+	return g.repository.ResetLoginFailures(ctx, []string{"login:" + login})
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"testing"
+	"time"
+)
+
+func TestLoginPolicy_Delay(t *testing.T) {
+	policy := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{failures: 1, delay: 0},
+		{failures: 3, delay: 0},
+		{failures: 4, delay: time.Second},
+		{failures: 5, delay: 2 * time.Second},
+		{failures: 9, delay: 32 * time.Second},
+		{failures: 10, delay: 15 * time.Minute},
+		{failures: 100, delay: 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if delay := policy.delay(tt.failures); delay != tt.delay {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, delay, tt.delay)
+		}
+	}
+}
+
+func TestLoginGuard(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockLoginThrottleRepository(ctrl)
+	loginPolicy := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	ipPolicy := LoginPolicy{FreeAttempts: 20, MaxFailures: 100, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	guard := NewLoginGuard(repository, loginPolicy, ipPolicy)
+
+	t.Run("Blocked", func(t *testing.T) {
+		blockedUntil := time.Now().Add(time.Minute)
+		repository.EXPECT().FindLoginThrottles(gomock.Any(), []string{"login:alice", "ip:10.0.0.1"}).
+			Return([]entities.LoginThrottle{{ThrottleKey: "ip:10.0.0.1", BlockedUntil: &blockedUntil}}, nil)
+		err := guard.Check(context.Background(), "alice", "10.0.0.1")
+		var appErr *apperrors.Error
+		if !errors.As(err, &appErr) || appErr.Code != apperrors.CodeTooManyAttempts || appErr.RetryAfter <= 0 {
+			t.Errorf("expected too many attempts, got %v", err)
+		}
+	})
+
+	t.Run("Block expired", func(t *testing.T) {
+		blockedUntil := time.Now().Add(-time.Minute)
+		repository.EXPECT().FindLoginThrottles(gomock.Any(), []string{"login:alice"}).
+			Return([]entities.LoginThrottle{{ThrottleKey: "login:alice", BlockedUntil: &blockedUntil}}, nil)
+		if err := guard.Check(context.Background(), "alice", ""); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Lockout", func(t *testing.T) {
+		repository.EXPECT().IncrementLoginFailures(gomock.Any(), "login:alice", gomock.Any(), gomock.Any()).Return(10, nil)
+		repository.EXPECT().BlockLogin(gomock.Any(), "login:alice", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, until time.Time) error {
+			if time.Until(until) < 14*time.Minute {
+				t.Errorf("expected lockout, blocked until %s", until)
+			}
+			return nil
+		})
+		repository.EXPECT().IncrementLoginFailures(gomock.Any(), "ip:10.0.0.1", gomock.Any(), gomock.Any()).Return(10, nil)
+		if err := guard.RegisterFailure(context.Background(), "alice", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Success resets login only", func(t *testing.T) {
+		repository.EXPECT().ResetLoginFailures(gomock.Any(), []string{"login:alice"}).Return(nil)
+		if err := guard.RegisterSuccess(context.Background(), "alice"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: EventRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockEventRepository is a mock of EventRepository interface.
+type MockEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventRepositoryMockRecorder
+}
+
+// MockEventRepositoryMockRecorder is the mock recorder for MockEventRepository.
+type MockEventRepositoryMockRecorder struct {
+	mock *MockEventRepository
+}
+
+// NewMockEventRepository creates a new mock instance.
+func NewMockEventRepository(ctrl *gomock.Controller) *MockEventRepository {
+	mock := &MockEventRepository{ctrl: ctrl}
+	mock.recorder = &MockEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventRepository) EXPECT() *MockEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindEventsAfter mocks base method.
+func (m *MockEventRepository) FindEventsAfter(arg0 context.Context, arg1, arg2 uint, arg3 int) ([]entities.UserEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindEventsAfter", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]entities.UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindEventsAfter indicates an expected call of FindEventsAfter.
+func (mr *MockEventRepositoryMockRecorder) FindEventsAfter(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindEventsAfter", reflect.TypeOf((*MockEventRepository)(nil).FindEventsAfter), arg0, arg1, arg2, arg3)
+}
+
+// SaveEvent mocks base method.
+func (m *MockEventRepository) SaveEvent(arg0 context.Context, arg1 *entities.UserEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveEvent indicates an expected call of SaveEvent.
+func (mr *MockEventRepositoryMockRecorder) SaveEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEvent", reflect.TypeOf((*MockEventRepository)(nil).SaveEvent), arg0, arg1)
+}
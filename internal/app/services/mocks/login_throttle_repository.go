@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: LoginThrottleRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockLoginThrottleRepository is a mock of LoginThrottleRepository interface.
+type MockLoginThrottleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginThrottleRepositoryMockRecorder
+}
+
+// MockLoginThrottleRepositoryMockRecorder is the mock recorder for MockLoginThrottleRepository.
+type MockLoginThrottleRepositoryMockRecorder struct {
+	mock *MockLoginThrottleRepository
+}
+
+// NewMockLoginThrottleRepository creates a new mock instance.
+func NewMockLoginThrottleRepository(ctrl *gomock.Controller) *MockLoginThrottleRepository {
+	mock := &MockLoginThrottleRepository{ctrl: ctrl}
+	mock.recorder = &MockLoginThrottleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginThrottleRepository) EXPECT() *MockLoginThrottleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// BlockLogin mocks base method.
+func (m *MockLoginThrottleRepository) BlockLogin(arg0 context.Context, arg1 string, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockLogin", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockLogin indicates an expected call of BlockLogin.
+func (mr *MockLoginThrottleRepositoryMockRecorder) BlockLogin(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockLogin", reflect.TypeOf((*MockLoginThrottleRepository)(nil).BlockLogin), arg0, arg1, arg2)
+}
+
+// FindLoginThrottles mocks base method.
+func (m *MockLoginThrottleRepository) FindLoginThrottles(arg0 context.Context, arg1 []string) ([]entities.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLoginThrottles", arg0, arg1)
+	ret0, _ := ret[0].([]entities.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLoginThrottles indicates an expected call of FindLoginThrottles.
+func (mr *MockLoginThrottleRepositoryMockRecorder) FindLoginThrottles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLoginThrottles", reflect.TypeOf((*MockLoginThrottleRepository)(nil).FindLoginThrottles), arg0, arg1)
+}
+
+// IncrementLoginFailures mocks base method.
+func (m *MockLoginThrottleRepository) IncrementLoginFailures(arg0 context.Context, arg1 string, arg2, arg3 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementLoginFailures", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementLoginFailures indicates an expected call of IncrementLoginFailures.
+func (mr *MockLoginThrottleRepositoryMockRecorder) IncrementLoginFailures(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementLoginFailures", reflect.TypeOf((*MockLoginThrottleRepository)(nil).IncrementLoginFailures), arg0, arg1, arg2, arg3)
+}
+
+// ResetLoginFailures mocks base method.
+func (m *MockLoginThrottleRepository) ResetLoginFailures(arg0 context.Context, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginFailures", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginFailures indicates an expected call of ResetLoginFailures.
+func (mr *MockLoginThrottleRepositoryMockRecorder) ResetLoginFailures(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginFailures", reflect.TypeOf((*MockLoginThrottleRepository)(nil).ResetLoginFailures), arg0, arg1)
+}
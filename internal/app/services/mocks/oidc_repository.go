@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: OIDCRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockOIDCRepository is a mock of OIDCRepository interface.
+type MockOIDCRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCRepositoryMockRecorder
+}
+
+// MockOIDCRepositoryMockRecorder is the mock recorder for MockOIDCRepository.
+type MockOIDCRepositoryMockRecorder struct {
+	mock *MockOIDCRepository
+}
+
+// NewMockOIDCRepository creates a new mock instance.
+func NewMockOIDCRepository(ctrl *gomock.Controller) *MockOIDCRepository {
+	mock := &MockOIDCRepository{ctrl: ctrl}
+	mock.recorder = &MockOIDCRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCRepository) EXPECT() *MockOIDCRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateUserWithIdentity mocks base method.
+func (m *MockOIDCRepository) CreateUserWithIdentity(arg0 context.Context, arg1 *entities.User, arg2 *entities.ExternalIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserWithIdentity", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUserWithIdentity indicates an expected call of CreateUserWithIdentity.
+func (mr *MockOIDCRepositoryMockRecorder) CreateUserWithIdentity(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithIdentity", reflect.TypeOf((*MockOIDCRepository)(nil).CreateUserWithIdentity), arg0, arg1, arg2)
+}
+
+// FindAuthRequestByStateHash mocks base method.
+func (m *MockOIDCRepository) FindAuthRequestByStateHash(arg0 context.Context, arg1 string) (entities.OIDCAuthRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAuthRequestByStateHash", arg0, arg1)
+	ret0, _ := ret[0].(entities.OIDCAuthRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAuthRequestByStateHash indicates an expected call of FindAuthRequestByStateHash.
+func (mr *MockOIDCRepositoryMockRecorder) FindAuthRequestByStateHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuthRequestByStateHash", reflect.TypeOf((*MockOIDCRepository)(nil).FindAuthRequestByStateHash), arg0, arg1)
+}
+
+// FindIdentity mocks base method.
+func (m *MockOIDCRepository) FindIdentity(arg0 context.Context, arg1, arg2 string) (entities.ExternalIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIdentity", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.ExternalIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIdentity indicates an expected call of FindIdentity.
+func (mr *MockOIDCRepositoryMockRecorder) FindIdentity(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdentity", reflect.TypeOf((*MockOIDCRepository)(nil).FindIdentity), arg0, arg1, arg2)
+}
+
+// MarkAuthRequestUsed mocks base method.
+func (m *MockOIDCRepository) MarkAuthRequestUsed(arg0 context.Context, arg1 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAuthRequestUsed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAuthRequestUsed indicates an expected call of MarkAuthRequestUsed.
+func (mr *MockOIDCRepositoryMockRecorder) MarkAuthRequestUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAuthRequestUsed", reflect.TypeOf((*MockOIDCRepository)(nil).MarkAuthRequestUsed), arg0, arg1)
+}
+
+// SaveAuthRequest mocks base method.
+func (m *MockOIDCRepository) SaveAuthRequest(arg0 context.Context, arg1 *entities.OIDCAuthRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAuthRequest", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAuthRequest indicates an expected call of SaveAuthRequest.
+func (mr *MockOIDCRepositoryMockRecorder) SaveAuthRequest(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAuthRequest", reflect.TypeOf((*MockOIDCRepository)(nil).SaveAuthRequest), arg0, arg1)
+}
+
+// SaveIdentity mocks base method.
+func (m *MockOIDCRepository) SaveIdentity(arg0 context.Context, arg1 *entities.ExternalIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveIdentity", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveIdentity indicates an expected call of SaveIdentity.
+func (mr *MockOIDCRepositoryMockRecorder) SaveIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdentity", reflect.TypeOf((*MockOIDCRepository)(nil).SaveIdentity), arg0, arg1)
+}
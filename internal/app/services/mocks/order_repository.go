@@ -5,9 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 )
 
@@ -35,45 +37,119 @@ func (m *MockOrderRepository) EXPECT() *MockOrderRepositoryMockRecorder {
 }
 
 // GetAllOrders mocks base method.
-func (m *MockOrderRepository) GetAllOrders(arg0 uint) ([]entities.Order, error) {
+func (m *MockOrderRepository) GetAllOrders(arg0 context.Context, arg1 uint) ([]entities.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllOrders", arg0)
+	ret := m.ctrl.Call(m, "GetAllOrders", arg0, arg1)
 	ret0, _ := ret[0].([]entities.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllOrders indicates an expected call of GetAllOrders.
-func (mr *MockOrderRepositoryMockRecorder) GetAllOrders(arg0 interface{}) *gomock.Call {
+func (mr *MockOrderRepositoryMockRecorder) GetAllOrders(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockOrderRepository)(nil).GetAllOrders), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockOrderRepository)(nil).GetAllOrders), arg0, arg1)
+}
+
+// GetOrderAttempts mocks base method.
+func (m *MockOrderRepository) GetOrderAttempts(arg0 context.Context, arg1 uint) ([]entities.OrderAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderAttempts", arg0, arg1)
+	ret0, _ := ret[0].([]entities.OrderAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderAttempts indicates an expected call of GetOrderAttempts.
+func (mr *MockOrderRepositoryMockRecorder) GetOrderAttempts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderAttempts", reflect.TypeOf((*MockOrderRepository)(nil).GetOrderAttempts), arg0, arg1)
 }
 
 // GetOrderByNumber mocks base method.
-func (m *MockOrderRepository) GetOrderByNumber(arg0 string) (entities.Order, error) {
+func (m *MockOrderRepository) GetOrderByNumber(arg0 context.Context, arg1 string) (entities.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrderByNumber", arg0)
+	ret := m.ctrl.Call(m, "GetOrderByNumber", arg0, arg1)
 	ret0, _ := ret[0].(entities.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrderByNumber indicates an expected call of GetOrderByNumber.
-func (mr *MockOrderRepositoryMockRecorder) GetOrderByNumber(arg0 interface{}) *gomock.Call {
+func (mr *MockOrderRepositoryMockRecorder) GetOrderByNumber(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByNumber", reflect.TypeOf((*MockOrderRepository)(nil).GetOrderByNumber), arg0, arg1)
+}
+
+// GetOrdersByNumbers mocks base method.
+func (m *MockOrderRepository) GetOrdersByNumbers(arg0 context.Context, arg1 []string) ([]entities.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersByNumbers", arg0, arg1)
+	ret0, _ := ret[0].([]entities.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersByNumbers indicates an expected call of GetOrdersByNumbers.
+func (mr *MockOrderRepositoryMockRecorder) GetOrdersByNumbers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersByNumbers", reflect.TypeOf((*MockOrderRepository)(nil).GetOrdersByNumbers), arg0, arg1)
+}
+
+// GetOrdersPage mocks base method.
+func (m *MockOrderRepository) GetOrdersPage(arg0 context.Context, arg1 dto.PageFilter) ([]entities.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersPage", arg0, arg1)
+	ret0, _ := ret[0].([]entities.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersPage indicates an expected call of GetOrdersPage.
+func (mr *MockOrderRepositoryMockRecorder) GetOrdersPage(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByNumber", reflect.TypeOf((*MockOrderRepository)(nil).GetOrderByNumber), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersPage", reflect.TypeOf((*MockOrderRepository)(nil).GetOrdersPage), arg0, arg1)
+}
+
+// GetUserOrderStats mocks base method.
+func (m *MockOrderRepository) GetUserOrderStats(arg0 context.Context, arg1 uint) (dto.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserOrderStats", arg0, arg1)
+	ret0, _ := ret[0].(dto.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserOrderStats indicates an expected call of GetUserOrderStats.
+func (mr *MockOrderRepositoryMockRecorder) GetUserOrderStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrderStats", reflect.TypeOf((*MockOrderRepository)(nil).GetUserOrderStats), arg0, arg1)
 }
 
 // Save mocks base method.
-func (m *MockOrderRepository) Save(arg0 *entities.Order) error {
+func (m *MockOrderRepository) Save(arg0 context.Context, arg1 *entities.Order) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", arg0)
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockOrderRepositoryMockRecorder) Save(arg0 interface{}) *gomock.Call {
+func (mr *MockOrderRepositoryMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockOrderRepository)(nil).Save), arg0, arg1)
+}
+
+// SaveAll mocks base method.
+func (m *MockOrderRepository) SaveAll(arg0 context.Context, arg1 []entities.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAll", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAll indicates an expected call of SaveAll.
+func (mr *MockOrderRepositoryMockRecorder) SaveAll(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockOrderRepository)(nil).Save), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockOrderRepository)(nil).SaveAll), arg0, arg1)
 }
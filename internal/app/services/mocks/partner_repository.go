@@ -0,0 +1,153 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: PartnerRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockPartnerRepository is a mock of PartnerRepository interface.
+type MockPartnerRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPartnerRepositoryMockRecorder
+}
+
+// MockPartnerRepositoryMockRecorder is the mock recorder for MockPartnerRepository.
+type MockPartnerRepositoryMockRecorder struct {
+	mock *MockPartnerRepository
+}
+
+// NewMockPartnerRepository creates a new mock instance.
+func NewMockPartnerRepository(ctrl *gomock.Controller) *MockPartnerRepository {
+	mock := &MockPartnerRepository{ctrl: ctrl}
+	mock.recorder = &MockPartnerRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPartnerRepository) EXPECT() *MockPartnerRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindAPIKeyByPrefix mocks base method.
+func (m *MockPartnerRepository) FindAPIKeyByPrefix(arg0 context.Context, arg1 string) (entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeyByPrefix", arg0, arg1)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeyByPrefix indicates an expected call of FindAPIKeyByPrefix.
+func (mr *MockPartnerRepositoryMockRecorder) FindAPIKeyByPrefix(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeyByPrefix", reflect.TypeOf((*MockPartnerRepository)(nil).FindAPIKeyByPrefix), arg0, arg1)
+}
+
+// FindPartnerByID mocks base method.
+func (m *MockPartnerRepository) FindPartnerByID(arg0 context.Context, arg1 uint) (entities.Partner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPartnerByID", arg0, arg1)
+	ret0, _ := ret[0].(entities.Partner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPartnerByID indicates an expected call of FindPartnerByID.
+func (mr *MockPartnerRepositoryMockRecorder) FindPartnerByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPartnerByID", reflect.TypeOf((*MockPartnerRepository)(nil).FindPartnerByID), arg0, arg1)
+}
+
+// FindPartnerUser mocks base method.
+func (m *MockPartnerRepository) FindPartnerUser(arg0 context.Context, arg1 uint, arg2 string) (entities.PartnerUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPartnerUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.PartnerUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPartnerUser indicates an expected call of FindPartnerUser.
+func (mr *MockPartnerRepositoryMockRecorder) FindPartnerUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPartnerUser", reflect.TypeOf((*MockPartnerRepository)(nil).FindPartnerUser), arg0, arg1, arg2)
+}
+
+// LinkPartnerUser mocks base method.
+func (m *MockPartnerRepository) LinkPartnerUser(arg0 context.Context, arg1 *entities.PartnerUser) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkPartnerUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkPartnerUser indicates an expected call of LinkPartnerUser.
+func (mr *MockPartnerRepositoryMockRecorder) LinkPartnerUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkPartnerUser", reflect.TypeOf((*MockPartnerRepository)(nil).LinkPartnerUser), arg0, arg1)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockPartnerRepository) RevokeAPIKey(arg0 context.Context, arg1, arg2 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockPartnerRepositoryMockRecorder) RevokeAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockPartnerRepository)(nil).RevokeAPIKey), arg0, arg1, arg2)
+}
+
+// SaveAPIKey mocks base method.
+func (m *MockPartnerRepository) SaveAPIKey(arg0 context.Context, arg1 *entities.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAPIKey indicates an expected call of SaveAPIKey.
+func (mr *MockPartnerRepositoryMockRecorder) SaveAPIKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAPIKey", reflect.TypeOf((*MockPartnerRepository)(nil).SaveAPIKey), arg0, arg1)
+}
+
+// SavePartner mocks base method.
+func (m *MockPartnerRepository) SavePartner(arg0 context.Context, arg1 *entities.Partner) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePartner", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePartner indicates an expected call of SavePartner.
+func (mr *MockPartnerRepositoryMockRecorder) SavePartner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePartner", reflect.TypeOf((*MockPartnerRepository)(nil).SavePartner), arg0, arg1)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockPartnerRepository) TouchAPIKey(arg0 context.Context, arg1 uint, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockPartnerRepositoryMockRecorder) TouchAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockPartnerRepository)(nil).TouchAPIKey), arg0, arg1, arg2)
+}
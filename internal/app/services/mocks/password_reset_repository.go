@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: PasswordResetRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockPasswordResetRepository is a mock of PasswordResetRepository interface.
+type MockPasswordResetRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetRepositoryMockRecorder
+}
+
+// MockPasswordResetRepositoryMockRecorder is the mock recorder for MockPasswordResetRepository.
+type MockPasswordResetRepositoryMockRecorder struct {
+	mock *MockPasswordResetRepository
+}
+
+// NewMockPasswordResetRepository creates a new mock instance.
+func NewMockPasswordResetRepository(ctrl *gomock.Controller) *MockPasswordResetRepository {
+	mock := &MockPasswordResetRepository{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetRepository) EXPECT() *MockPasswordResetRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindResetTokenByHash mocks base method.
+func (m *MockPasswordResetRepository) FindResetTokenByHash(arg0 context.Context, arg1 string) (entities.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindResetTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(entities.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindResetTokenByHash indicates an expected call of FindResetTokenByHash.
+func (mr *MockPasswordResetRepositoryMockRecorder) FindResetTokenByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindResetTokenByHash", reflect.TypeOf((*MockPasswordResetRepository)(nil).FindResetTokenByHash), arg0, arg1)
+}
+
+// MarkResetTokenUsed mocks base method.
+func (m *MockPasswordResetRepository) MarkResetTokenUsed(arg0 context.Context, arg1 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkResetTokenUsed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkResetTokenUsed indicates an expected call of MarkResetTokenUsed.
+func (mr *MockPasswordResetRepositoryMockRecorder) MarkResetTokenUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkResetTokenUsed", reflect.TypeOf((*MockPasswordResetRepository)(nil).MarkResetTokenUsed), arg0, arg1)
+}
+
+// SaveResetToken mocks base method.
+func (m *MockPasswordResetRepository) SaveResetToken(arg0 context.Context, arg1 *entities.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveResetToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveResetToken indicates an expected call of SaveResetToken.
+func (mr *MockPasswordResetRepositoryMockRecorder) SaveResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveResetToken", reflect.TypeOf((*MockPasswordResetRepository)(nil).SaveResetToken), arg0, arg1)
+}
@@ -0,0 +1,225 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: TokenRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockTokenRepository is a mock of TokenRepository interface.
+type MockTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRepositoryMockRecorder
+}
+
+// MockTokenRepositoryMockRecorder is the mock recorder for MockTokenRepository.
+type MockTokenRepositoryMockRecorder struct {
+	mock *MockTokenRepository
+}
+
+// NewMockTokenRepository creates a new mock instance.
+func NewMockTokenRepository(ctrl *gomock.Controller) *MockTokenRepository {
+	mock := &MockTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRepository) EXPECT() *MockTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ExtendSession mocks base method.
+func (m *MockTokenRepository) ExtendSession(arg0 context.Context, arg1, arg2 string, arg3, arg4 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtendSession", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExtendSession indicates an expected call of ExtendSession.
+func (mr *MockTokenRepositoryMockRecorder) ExtendSession(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendSession", reflect.TypeOf((*MockTokenRepository)(nil).ExtendSession), arg0, arg1, arg2, arg3, arg4)
+}
+
+// FindActiveSessions mocks base method.
+func (m *MockTokenRepository) FindActiveSessions(arg0 context.Context, arg1 uint, arg2 time.Time) ([]entities.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActiveSessions", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]entities.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActiveSessions indicates an expected call of FindActiveSessions.
+func (mr *MockTokenRepositoryMockRecorder) FindActiveSessions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActiveSessions", reflect.TypeOf((*MockTokenRepository)(nil).FindActiveSessions), arg0, arg1, arg2)
+}
+
+// FindRefreshTokenByHash mocks base method.
+func (m *MockTokenRepository) FindRefreshTokenByHash(arg0 context.Context, arg1 string) (entities.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRefreshTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(entities.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRefreshTokenByHash indicates an expected call of FindRefreshTokenByHash.
+func (mr *MockTokenRepositoryMockRecorder) FindRefreshTokenByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRefreshTokenByHash", reflect.TypeOf((*MockTokenRepository)(nil).FindRefreshTokenByHash), arg0, arg1)
+}
+
+// FindSessionByFamily mocks base method.
+func (m *MockTokenRepository) FindSessionByFamily(arg0 context.Context, arg1 string) (entities.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSessionByFamily", arg0, arg1)
+	ret0, _ := ret[0].(entities.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSessionByFamily indicates an expected call of FindSessionByFamily.
+func (mr *MockTokenRepositoryMockRecorder) FindSessionByFamily(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSessionByFamily", reflect.TypeOf((*MockTokenRepository)(nil).FindSessionByFamily), arg0, arg1)
+}
+
+// FindUserSession mocks base method.
+func (m *MockTokenRepository) FindUserSession(arg0 context.Context, arg1, arg2 uint) (entities.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserSession", arg0, arg1, arg2)
+	ret0, _ := ret[0].(entities.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserSession indicates an expected call of FindUserSession.
+func (mr *MockTokenRepositoryMockRecorder) FindUserSession(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserSession", reflect.TypeOf((*MockTokenRepository)(nil).FindUserSession), arg0, arg1, arg2)
+}
+
+// IsRevoked mocks base method.
+func (m *MockTokenRepository) IsRevoked(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockTokenRepositoryMockRecorder) IsRevoked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockTokenRepository)(nil).IsRevoked), arg0, arg1)
+}
+
+// MarkRefreshTokenUsed mocks base method.
+func (m *MockTokenRepository) MarkRefreshTokenUsed(arg0 context.Context, arg1 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRefreshTokenUsed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRefreshTokenUsed indicates an expected call of MarkRefreshTokenUsed.
+func (mr *MockTokenRepositoryMockRecorder) MarkRefreshTokenUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRefreshTokenUsed", reflect.TypeOf((*MockTokenRepository)(nil).MarkRefreshTokenUsed), arg0, arg1)
+}
+
+// RevokeFamily mocks base method.
+func (m *MockTokenRepository) RevokeFamily(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeFamily", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeFamily indicates an expected call of RevokeFamily.
+func (mr *MockTokenRepositoryMockRecorder) RevokeFamily(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeFamily", reflect.TypeOf((*MockTokenRepository)(nil).RevokeFamily), arg0, arg1)
+}
+
+// RevokeJTI mocks base method.
+func (m *MockTokenRepository) RevokeJTI(arg0 context.Context, arg1 string, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeJTI", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeJTI indicates an expected call of RevokeJTI.
+func (mr *MockTokenRepositoryMockRecorder) RevokeJTI(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeJTI", reflect.TypeOf((*MockTokenRepository)(nil).RevokeJTI), arg0, arg1, arg2)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockTokenRepository) RevokeUserTokens(arg0 context.Context, arg1 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockTokenRepositoryMockRecorder) RevokeUserTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockTokenRepository)(nil).RevokeUserTokens), arg0, arg1)
+}
+
+// SaveRefreshToken mocks base method.
+func (m *MockTokenRepository) SaveRefreshToken(arg0 context.Context, arg1 *entities.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRefreshToken indicates an expected call of SaveRefreshToken.
+func (mr *MockTokenRepositoryMockRecorder) SaveRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRefreshToken", reflect.TypeOf((*MockTokenRepository)(nil).SaveRefreshToken), arg0, arg1)
+}
+
+// SaveSession mocks base method.
+func (m *MockTokenRepository) SaveSession(arg0 context.Context, arg1 *entities.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSession", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSession indicates an expected call of SaveSession.
+func (mr *MockTokenRepositoryMockRecorder) SaveSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSession", reflect.TypeOf((*MockTokenRepository)(nil).SaveSession), arg0, arg1)
+}
+
+// TouchSession mocks base method.
+func (m *MockTokenRepository) TouchSession(arg0 context.Context, arg1 string, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MockTokenRepositoryMockRecorder) TouchSession(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession", reflect.TypeOf((*MockTokenRepository)(nil).TouchSession), arg0, arg1, arg2)
+}
@@ -0,0 +1,168 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/keyjin88/go-loyalty-system/internal/app/services (interfaces: TwoFactorRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+)
+
+// MockTwoFactorRepository is a mock of TwoFactorRepository interface.
+type MockTwoFactorRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorRepositoryMockRecorder
+}
+
+// MockTwoFactorRepositoryMockRecorder is the mock recorder for MockTwoFactorRepository.
+type MockTwoFactorRepositoryMockRecorder struct {
+	mock *MockTwoFactorRepository
+}
+
+// NewMockTwoFactorRepository creates a new mock instance.
+func NewMockTwoFactorRepository(ctrl *gomock.Controller) *MockTwoFactorRepository {
+	mock := &MockTwoFactorRepository{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorRepository) EXPECT() *MockTwoFactorRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AdvanceTOTPStep mocks base method.
+func (m *MockTwoFactorRepository) AdvanceTOTPStep(arg0 context.Context, arg1 uint, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceTOTPStep", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceTOTPStep indicates an expected call of AdvanceTOTPStep.
+func (mr *MockTwoFactorRepositoryMockRecorder) AdvanceTOTPStep(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceTOTPStep", reflect.TypeOf((*MockTwoFactorRepository)(nil).AdvanceTOTPStep), arg0, arg1, arg2)
+}
+
+// FindChallengeByHash mocks base method.
+func (m *MockTwoFactorRepository) FindChallengeByHash(arg0 context.Context, arg1 string) (entities.LoginChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindChallengeByHash", arg0, arg1)
+	ret0, _ := ret[0].(entities.LoginChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindChallengeByHash indicates an expected call of FindChallengeByHash.
+func (mr *MockTwoFactorRepositoryMockRecorder) FindChallengeByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindChallengeByHash", reflect.TypeOf((*MockTwoFactorRepository)(nil).FindChallengeByHash), arg0, arg1)
+}
+
+// FindUnusedRecoveryCodes mocks base method.
+func (m *MockTwoFactorRepository) FindUnusedRecoveryCodes(arg0 context.Context, arg1 uint) ([]entities.RecoveryCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUnusedRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].([]entities.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUnusedRecoveryCodes indicates an expected call of FindUnusedRecoveryCodes.
+func (mr *MockTwoFactorRepositoryMockRecorder) FindUnusedRecoveryCodes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnusedRecoveryCodes", reflect.TypeOf((*MockTwoFactorRepository)(nil).FindUnusedRecoveryCodes), arg0, arg1)
+}
+
+// IncrementChallengeAttempts mocks base method.
+func (m *MockTwoFactorRepository) IncrementChallengeAttempts(arg0 context.Context, arg1 uint, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementChallengeAttempts", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementChallengeAttempts indicates an expected call of IncrementChallengeAttempts.
+func (mr *MockTwoFactorRepositoryMockRecorder) IncrementChallengeAttempts(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementChallengeAttempts", reflect.TypeOf((*MockTwoFactorRepository)(nil).IncrementChallengeAttempts), arg0, arg1, arg2)
+}
+
+// MarkChallengeUsed mocks base method.
+func (m *MockTwoFactorRepository) MarkChallengeUsed(arg0 context.Context, arg1 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkChallengeUsed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkChallengeUsed indicates an expected call of MarkChallengeUsed.
+func (mr *MockTwoFactorRepositoryMockRecorder) MarkChallengeUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkChallengeUsed", reflect.TypeOf((*MockTwoFactorRepository)(nil).MarkChallengeUsed), arg0, arg1)
+}
+
+// MarkRecoveryCodeUsed mocks base method.
+func (m *MockTwoFactorRepository) MarkRecoveryCodeUsed(arg0 context.Context, arg1 uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRecoveryCodeUsed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRecoveryCodeUsed indicates an expected call of MarkRecoveryCodeUsed.
+func (mr *MockTwoFactorRepositoryMockRecorder) MarkRecoveryCodeUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRecoveryCodeUsed", reflect.TypeOf((*MockTwoFactorRepository)(nil).MarkRecoveryCodeUsed), arg0, arg1)
+}
+
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(arg0 context.Context, arg1 uint, arg2 []entities.RecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockTwoFactorRepositoryMockRecorder) ReplaceRecoveryCodes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockTwoFactorRepository)(nil).ReplaceRecoveryCodes), arg0, arg1, arg2)
+}
+
+// SaveChallenge mocks base method.
+func (m *MockTwoFactorRepository) SaveChallenge(arg0 context.Context, arg1 *entities.LoginChallenge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveChallenge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveChallenge indicates an expected call of SaveChallenge.
+func (mr *MockTwoFactorRepositoryMockRecorder) SaveChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveChallenge", reflect.TypeOf((*MockTwoFactorRepository)(nil).SaveChallenge), arg0, arg1)
+}
+
+// UpdateTOTP mocks base method.
+func (m *MockTwoFactorRepository) UpdateTOTP(arg0 context.Context, arg1 uint, arg2 string, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTOTP", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTOTP indicates an expected call of UpdateTOTP.
+func (mr *MockTwoFactorRepositoryMockRecorder) UpdateTOTP(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTOTP", reflect.TypeOf((*MockTwoFactorRepository)(nil).UpdateTOTP), arg0, arg1, arg2, arg3)
+}
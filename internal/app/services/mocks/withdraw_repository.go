@@ -5,9 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	dto "github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	entities "github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 )
 
@@ -34,31 +36,61 @@ func (m *MockWithdrawRepository) EXPECT() *MockWithdrawRepositoryMockRecorder {
 	return m.recorder
 }
 
+// GetUserWithdrawStats mocks base method.
+func (m *MockWithdrawRepository) GetUserWithdrawStats(arg0 context.Context, arg1 uint) (dto.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserWithdrawStats", arg0, arg1)
+	ret0, _ := ret[0].(dto.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserWithdrawStats indicates an expected call of GetUserWithdrawStats.
+func (mr *MockWithdrawRepositoryMockRecorder) GetUserWithdrawStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserWithdrawStats", reflect.TypeOf((*MockWithdrawRepository)(nil).GetUserWithdrawStats), arg0, arg1)
+}
+
 // GetWithdrawals mocks base method.
-func (m *MockWithdrawRepository) GetWithdrawals(arg0 uint) ([]entities.Withdraw, error) {
+func (m *MockWithdrawRepository) GetWithdrawals(arg0 context.Context, arg1 uint) ([]entities.Withdraw, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetWithdrawals", arg0)
+	ret := m.ctrl.Call(m, "GetWithdrawals", arg0, arg1)
 	ret0, _ := ret[0].([]entities.Withdraw)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetWithdrawals indicates an expected call of GetWithdrawals.
-func (mr *MockWithdrawRepositoryMockRecorder) GetWithdrawals(arg0 interface{}) *gomock.Call {
+func (mr *MockWithdrawRepositoryMockRecorder) GetWithdrawals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockWithdrawRepository)(nil).GetWithdrawals), arg0, arg1)
+}
+
+// GetWithdrawalsPage mocks base method.
+func (m *MockWithdrawRepository) GetWithdrawalsPage(arg0 context.Context, arg1 dto.PageFilter) ([]entities.Withdraw, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawalsPage", arg0, arg1)
+	ret0, _ := ret[0].([]entities.Withdraw)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawalsPage indicates an expected call of GetWithdrawalsPage.
+func (mr *MockWithdrawRepositoryMockRecorder) GetWithdrawalsPage(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockWithdrawRepository)(nil).GetWithdrawals), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawalsPage", reflect.TypeOf((*MockWithdrawRepository)(nil).GetWithdrawalsPage), arg0, arg1)
 }
 
 // Save mocks base method.
-func (m *MockWithdrawRepository) Save(arg0 *entities.Withdraw) error {
+func (m *MockWithdrawRepository) Save(arg0 context.Context, arg1 *entities.Withdraw) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", arg0)
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockWithdrawRepositoryMockRecorder) Save(arg0 interface{}) *gomock.Call {
+func (mr *MockWithdrawRepositoryMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockWithdrawRepository)(nil).Save), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockWithdrawRepository)(nil).Save), arg0, arg1)
 }
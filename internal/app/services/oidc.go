@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"time"
+)
+
+// oidcRequestTimeout сколько ждать ответа провайдера при обмене кода на токены
+const oidcRequestTimeout = 10 * time.Second
+
+// OIDCConfig настройки клиента OpenID Connect
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// StateTTL сколько у пользователя есть времени, чтобы вернуться от провайдера
+	StateTTL time.Duration
+}
+
+// OIDCService вход через внешний провайдер OpenID Connect по коду авторизации с PKCE.
+// ID-токен проверяется ключами из JWKS провайдера, внешняя учётная запись привязывается
+// к существующему пользователю или для неё заводится новый.
+type OIDCService struct {
+	repository     OIDCRepository
+	userRepository UserRepository
+	oauth          oauth2.Config
+	verifier       *oidc.IDTokenVerifier
+	issuer         string
+	stateTTL       time.Duration
+}
+
+// NewOIDCService читает настройки провайдера из его discovery-документа
+func NewOIDCService(
+	ctx context.Context,
+	config OIDCConfig,
+	repository OIDCRepository,
+	userRepository UserRepository,
+) (*OIDCService, error) {
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: %w", config.Issuer, err)
+	}
+	return &OIDCService{
+		repository:     repository,
+		userRepository: userRepository,
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		issuer:   config.Issuer,
+		stateTTL: config.StateTTL,
+	}, nil
+}
+
+// StartAuthorization открывает вход у провайдера и возвращает адрес, куда отправить пользователя.
+// linkUserID не ноль, если уже вошедший пользователь привязывает внешнюю учётную запись.
+func (s *OIDCService) StartAuthorization(ctx context.Context, linkUserID uint) (models.OIDCAuthorizationResponse, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.StartAuthorization")
+	defer span.End()
+	state, err := randomToken(32)
+	if err != nil {
+		return models.OIDCAuthorizationResponse{}, err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return models.OIDCAuthorizationResponse{}, err
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		return models.OIDCAuthorizationResponse{}, err
+	}
+	err = s.repository.SaveAuthRequest(ctx, &entities.OIDCAuthRequest{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	})
+	if err != nil {
+		return models.OIDCAuthorizationResponse{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	url := s.oauth.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	return models.OIDCAuthorizationResponse{AuthorizationURL: url}, nil
+}
+
+// CompleteAuthorization обменивает код провайдера на ID-токен и находит по нему пользователя.
+// Неизвестная внешняя учётная запись привязывается к пользователю, начавшему привязку,
+// а при обычном входе для неё заводится новый пользователь.
+func (s *OIDCService) CompleteAuthorization(ctx context.Context, code string, state string) (dto.OIDCLoginResult, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.CompleteAuthorization")
+	defer span.End()
+	request, err := s.consumeAuthRequest(ctx, state)
+	if err != nil {
+		return dto.OIDCLoginResult{}, err
+	}
+	// Таймаут только на запросы к провайдеру, обращения к базе ниже идут в контексте запроса
+	exchangeCtx, cancel := context.WithTimeout(ctx, oidcRequestTimeout)
+	defer cancel()
+	token, err := s.oauth.Exchange(exchangeCtx, code, oauth2.SetAuthURLParam("code_verifier", request.CodeVerifier))
+	if err != nil {
+		logger.FromContext(ctx).Infof("OIDC code exchange failed: %v", err)
+		return dto.OIDCLoginResult{}, apperrors.ErrOIDCLoginFailed
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		logger.FromContext(ctx).Infof("OIDC token response has no id_token")
+		return dto.OIDCLoginResult{}, apperrors.ErrOIDCLoginFailed
+	}
+	idToken, err := s.verifier.Verify(exchangeCtx, rawIDToken)
+	if err != nil {
+		logger.FromContext(ctx).Infof("OIDC ID token verification failed: %v", err)
+		return dto.OIDCLoginResult{}, apperrors.ErrOIDCLoginFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(request.Nonce)) != 1 {
+		logger.FromContext(ctx).Infof("OIDC ID token nonce mismatch for subject %s", idToken.Subject)
+		return dto.OIDCLoginResult{}, apperrors.ErrOIDCLoginFailed
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return dto.OIDCLoginResult{}, err
+	}
+	// Непроверенный адрес не сохраняется: на него уходят ссылки восстановления пароля
+	if !claims.EmailVerified {
+		claims.Email = ""
+	}
+
+	identity, err := s.repository.FindIdentity(ctx, s.issuer, idToken.Subject)
+	if err == nil {
+		if request.LinkUserID != 0 && identity.UserID != request.LinkUserID {
+			return dto.OIDCLoginResult{}, apperrors.ErrIdentityAlreadyLinked
+		}
+		return s.result(ctx, identity.UserID, request.LinkUserID != 0)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.OIDCLoginResult{}, err
+	}
+	identity = entities.ExternalIdentity{Issuer: s.issuer, Subject: idToken.Subject, Email: claims.Email}
+	if request.LinkUserID != 0 {
+		identity.UserID = request.LinkUserID
+		if err := s.repository.SaveIdentity(ctx, &identity); err != nil {
+			return dto.OIDCLoginResult{}, identityError(err)
+		}
+		return s.result(ctx, request.LinkUserID, true)
+	}
+	user, err := s.newExternalUser(idToken.Subject, claims.Email)
+	if err != nil {
+		return dto.OIDCLoginResult{}, err
+	}
+	if err := s.repository.CreateUserWithIdentity(ctx, &user, &identity); err != nil {
+		return dto.OIDCLoginResult{}, identityError(err)
+	}
+	return dto.OIDCLoginResult{UserID: user.ID}, nil
+}
+
+func (s *OIDCService) consumeAuthRequest(ctx context.Context, state string) (entities.OIDCAuthRequest, error) {
+	request, err := s.repository.FindAuthRequestByStateHash(ctx, hashToken(state))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entities.OIDCAuthRequest{}, apperrors.ErrInvalidOIDCState
+	}
+	if err != nil {
+		return entities.OIDCAuthRequest{}, err
+	}
+	if request.UsedAt != nil || time.Now().After(request.ExpiresAt) {
+		return entities.OIDCAuthRequest{}, apperrors.ErrInvalidOIDCState
+	}
+	marked, err := s.repository.MarkAuthRequestUsed(ctx, request.ID)
+	if err != nil {
+		return entities.OIDCAuthRequest{}, err
+	}
+	if !marked {
+		return entities.OIDCAuthRequest{}, apperrors.ErrInvalidOIDCState
+	}
+	return request, nil
+}
+
+func (s *OIDCService) result(ctx context.Context, userID uint, linked bool) (dto.OIDCLoginResult, error) {
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.OIDCLoginResult{}, apperrors.ErrOIDCLoginFailed
+	}
+	if err != nil {
+		return dto.OIDCLoginResult{}, err
+	}
+	return dto.OIDCLoginResult{UserID: user.ID, TOTPEnabled: user.TOTPEnabled, Linked: linked}, nil
+}
+
+// newExternalUser пользователь для внешней учётной записи. Логин выводится из издателя и subject
+// и начинается с oidcLoginPrefix, который нельзя занять при регистрации. Пароль случайный и никому
+// не известен: войти по паролю можно только после его восстановления.
+func (s *OIDCService) newExternalUser(subject string, email string) (entities.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return entities.User{}, err
+	}
+	sum := sha256.Sum256([]byte(s.issuer + "|" + subject))
+	return entities.User{
+		UserName: oidcLoginPrefix + hex.EncodeToString(sum[:8]),
+		Password: hashPassword(password),
+		Email:    email,
+	}, nil
+}
+
+// identityError внешняя учётная запись могла быть привязана параллельным запросом
+func identityError(err error) error {
+	pgErr, ok := err.(*pgconn.PgError)
+	if ok && pgErr.Code == pgerrcode.UniqueViolation {
+		return apperrors.ErrIdentityAlreadyLinked
+	}
+	return err
+}
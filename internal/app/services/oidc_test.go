@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/keyring"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	stubClientID     = "gophermart"
+	stubClientSecret = "client secret"
+)
+
+// stubOIDCProvider локальный провайдер OpenID Connect: discovery-документ, JWKS
+// и обмен кода на ID-токен с проверкой PKCE
+type stubOIDCProvider struct {
+	server *httptest.Server
+	keys   *keyring.Keyring
+	mutex  sync.Mutex
+	codes  map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	subject   string
+	nonce     string
+	challenge string
+}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	provider := &stubOIDCProvider{
+		keys:  newRSAKeyring(t, "stub-1"),
+		codes: map[string]stubAuthorization{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeStubJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                provider.server.URL,
+			"authorization_endpoint":                provider.server.URL + "/authorize",
+			"token_endpoint":                        provider.server.URL + "/token",
+			"jwks_uri":                              provider.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{keyring.AlgRS256},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeStubJSON(w, http.StatusOK, provider.keys.JWKS())
+	})
+	mux.HandleFunc("/token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// authorize имитирует вход пользователя subject у провайдера и возвращает код для redirect_uri
+func (p *stubOIDCProvider) authorize(t *testing.T, authorizationURL string, subject string) string {
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != stubClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request: %s", authorizationURL)
+	}
+	code := "code-" + subject
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.codes[code] = stubAuthorization{
+		subject:   subject,
+		nonce:     query.Get("nonce"),
+		challenge: query.Get("code_challenge"),
+	}
+	return code
+}
+
+func (p *stubOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if clientID != stubClientID || clientSecret != stubClientSecret {
+		writeStubJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	p.mutex.Lock()
+	authorization, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mutex.Unlock()
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	idToken, err := p.keys.Sign(jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            stubClientID,
+		"sub":            authorization.subject,
+		"nonce":          authorization.nonce,
+		"email":          authorization.subject + "@example.com",
+		"email_verified": true,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		writeStubJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeStubJSON(w, http.StatusOK, map[string]any{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newRSAKeyring(t *testing.T, id string) *keyring.Keyring {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := keyring.New()
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := keys.AddPEM(id, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetSigningKey(id); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestOIDCService_CompleteAuthorization(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	provider := newStubOIDCProvider(t)
+	issuer := provider.server.URL
+
+	tests := []struct {
+		name       string
+		linkUserID uint
+		// prepare настраивает моки после выдачи кода провайдером
+		prepare func(repository *mocks.MockOIDCRepository, users *mocks.MockUserRepository)
+		// tamper портит сохранённый вход перед его возвратом из базы
+		tamper func(request *entities.OIDCAuthRequest)
+		want   dto.OIDCLoginResult
+		err    error
+	}{
+		{
+			name: "New user",
+			prepare: func(repository *mocks.MockOIDCRepository, users *mocks.MockUserRepository) {
+				repository.EXPECT().FindIdentity(gomock.Any(), issuer, "alice").Return(entities.ExternalIdentity{}, gorm.ErrRecordNotFound)
+				repository.EXPECT().CreateUserWithIdentity(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, user *entities.User, identity *entities.ExternalIdentity) error {
+						if !strings.HasPrefix(user.UserName, "oidc-") || user.Email != "alice@example.com" || user.Password == "" {
+							t.Errorf("unexpected user: %+v", user)
+						}
+						if identity.Issuer != issuer || identity.Subject != "alice" {
+							t.Errorf("unexpected identity: %+v", identity)
+						}
+						user.ID = 42
+						return nil
+					})
+			},
+			want: dto.OIDCLoginResult{UserID: 42},
+		},
+		{
+			name: "Known identity",
+			prepare: func(repository *mocks.MockOIDCRepository, users *mocks.MockUserRepository) {
+				repository.EXPECT().FindIdentity(gomock.Any(), issuer, "alice").Return(entities.ExternalIdentity{UserID: 7}, nil)
+				users.EXPECT().FindUserByID(gomock.Any(), uint(7)).
+					Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true}, nil)
+			},
+			want: dto.OIDCLoginResult{UserID: 7, TOTPEnabled: true},
+		},
+		{
+			name:       "Link to password user",
+			linkUserID: 7,
+			prepare: func(repository *mocks.MockOIDCRepository, users *mocks.MockUserRepository) {
+				repository.EXPECT().FindIdentity(gomock.Any(), issuer, "alice").Return(entities.ExternalIdentity{}, gorm.ErrRecordNotFound)
+				repository.EXPECT().SaveIdentity(gomock.Any(), &entities.ExternalIdentity{
+					Issuer:  issuer,
+					Subject: "alice",
+					UserID:  7,
+					Email:   "alice@example.com",
+				}).Return(nil)
+				users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}}, nil)
+			},
+			want: dto.OIDCLoginResult{UserID: 7, Linked: true},
+		},
+		{
+			name:       "Identity linked to another user",
+			linkUserID: 7,
+			prepare: func(repository *mocks.MockOIDCRepository, users *mocks.MockUserRepository) {
+				repository.EXPECT().FindIdentity(gomock.Any(), issuer, "alice").Return(entities.ExternalIdentity{UserID: 8}, nil)
+			},
+			err: apperrors.ErrIdentityAlreadyLinked,
+		},
+		{
+			name:   "Wrong PKCE verifier",
+			tamper: func(request *entities.OIDCAuthRequest) { request.CodeVerifier = "other verifier" },
+			err:    apperrors.ErrOIDCLoginFailed,
+		},
+		{
+			name:   "Nonce mismatch",
+			tamper: func(request *entities.OIDCAuthRequest) { request.Nonce = "other nonce" },
+			err:    apperrors.ErrOIDCLoginFailed,
+		},
+		{
+			name:   "Expired state",
+			tamper: func(request *entities.OIDCAuthRequest) { request.ExpiresAt = time.Now().Add(-time.Second) },
+			err:    apperrors.ErrInvalidOIDCState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repository := mocks.NewMockOIDCRepository(ctrl)
+			users := mocks.NewMockUserRepository(ctrl)
+			service, err := NewOIDCService(context.Background(), OIDCConfig{
+				Issuer:       issuer,
+				ClientID:     stubClientID,
+				ClientSecret: stubClientSecret,
+				RedirectURL:  "http://gophermart.test/api/user/oidc/callback",
+				StateTTL:     time.Minute,
+			}, repository, users)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var request entities.OIDCAuthRequest
+			repository.EXPECT().SaveAuthRequest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, saved *entities.OIDCAuthRequest) error {
+				request = *saved
+				request.ID = 3
+				return nil
+			})
+			started, err := service.StartAuthorization(context.Background(), tt.linkUserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := url.Parse(started.AuthorizationURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := parsed.Query().Get("state")
+			code := provider.authorize(t, started.AuthorizationURL, "alice")
+			if request.LinkUserID != tt.linkUserID || request.StateHash != hashToken(state) {
+				t.Fatalf("unexpected auth request: %+v", request)
+			}
+
+			if tt.tamper != nil {
+				tt.tamper(&request)
+			}
+			repository.EXPECT().FindAuthRequestByStateHash(gomock.Any(), hashToken(state)).Return(request, nil)
+			repository.EXPECT().MarkAuthRequestUsed(gomock.Any(), uint(3)).Return(true, nil).MaxTimes(1)
+			if tt.prepare != nil {
+				tt.prepare(repository, users)
+			}
+
+			result, err := service.CompleteAuthorization(context.Background(), code, state)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if result != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestOIDCService_CompleteAuthorizationUnknownState(t *testing.T) {
+	provider := newStubOIDCProvider(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockOIDCRepository(ctrl)
+	service, err := NewOIDCService(context.Background(), OIDCConfig{
+		Issuer:   provider.server.URL,
+		ClientID: stubClientID,
+		StateTTL: time.Minute,
+	}, repository, mocks.NewMockUserRepository(ctrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository.EXPECT().FindAuthRequestByStateHash(gomock.Any(), hashToken("state")).
+		Return(entities.OIDCAuthRequest{}, gorm.ErrRecordNotFound)
+	if _, err := service.CompleteAuthorization(context.Background(), "code", "state"); !errors.Is(err, apperrors.ErrInvalidOIDCState) {
+		t.Errorf("expected invalid state, got %v", err)
+	}
+}
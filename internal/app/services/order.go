@@ -1,13 +1,19 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
-	"sort"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 	"strconv"
 	"strings"
 	"time"
@@ -21,12 +27,12 @@ type AccrualDetails struct {
 
 type OrderService struct {
 	orderRepository        OrderRepository
-	orderProcessingChannel chan entities.Order
+	orderProcessingChannel chan dto.OrderTask
 }
 
 func NewOrderService(
 	orderRepository OrderRepository,
-	channel chan entities.Order,
+	channel chan dto.OrderTask,
 ) *OrderService {
 	return &OrderService{
 		orderRepository:        orderRepository,
@@ -34,40 +40,59 @@ func NewOrderService(
 	}
 }
 
-func (s *OrderService) SaveOrder(orderDTO dto.OrderDTO) (entities.Order, error) {
+func (s *OrderService) SaveOrder(ctx context.Context, orderDTO dto.OrderDTO) (dto.OrderUploadResult, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.SaveOrder")
+	defer span.End()
 	// закомментировано, длч облегчния тестирования
 	if !checkOrderNumber(orderDTO.Number) {
-		return entities.Order{}, handlers.ErrOrderHasWrongFormat
+		return dto.OrderUploadResult{}, apperrors.ErrInvalidOrderNumber
 	}
 	var order = entities.Order{
 		Number: orderDTO.Number,
 		UserID: orderDTO.UserID,
 	}
-	err := s.orderRepository.Save(&order)
+	err := s.orderRepository.Save(ctx, &order)
 	if err != nil {
 		pgErr, ok := err.(*pgconn.PgError)
 		if ok && pgErr.Code == pgerrcode.UniqueViolation {
-			order, err = s.orderRepository.GetOrderByNumber(orderDTO.Number)
+			order, err = s.orderRepository.GetOrderByNumber(ctx, orderDTO.Number)
 			if err != nil {
-				return entities.Order{}, err
+				return dto.OrderUploadResult{}, err
 			}
 			if order.UserID == orderDTO.UserID {
-				return entities.Order{}, handlers.ErrOrderAlreadyUploadedByUser
+				return dto.OrderUploadResult{Order: order, AlreadyUploaded: true}, nil
 			}
-			return entities.Order{}, handlers.ErrOrderAlreadyUploaded
+			return dto.OrderUploadResult{}, apperrors.ErrOrderUploadedByOtherUser
 		}
-		return entities.Order{}, err
+		return dto.OrderUploadResult{}, err
 	}
-	s.orderProcessingChannel <- order
-	return order, nil
+	metrics.OrdersUploaded.Inc()
+	s.orderProcessingChannel <- dto.OrderTask{
+		Order:     order,
+		RequestID: logger.RequestID(ctx),
+		Trace:     trace.SpanContextFromContext(ctx),
+	}
+	return dto.OrderUploadResult{Order: order}, nil
 }
 
-func (s *OrderService) GetAllOrders(userID uint) ([]models.AllOrderResponse, error) {
-	orders, err := s.orderRepository.GetAllOrders(userID)
+func (s *OrderService) GetOrdersPage(ctx context.Context, query dto.PageQuery) (models.OrderPage, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrdersPage")
+	defer span.End()
+	filter, err := newPageFilter(query)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+	orders, err := s.orderRepository.GetOrdersPage(ctx, filter)
 	if err != nil {
-		return nil, err
+		return models.OrderPage{}, err
+	}
+	var page models.OrderPage
+	if len(orders) > query.Limit {
+		orders = orders[:query.Limit]
+		last := orders[len(orders)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID, query.Descending, pageFilters(query))
 	}
-	var response = make([]models.AllOrderResponse, 0)
+	page.Items = make([]models.AllOrderResponse, 0, len(orders))
 	for _, order := range orders {
 		resp := models.AllOrderResponse{
 			Number:       order.Number,
@@ -76,11 +101,123 @@ func (s *OrderService) GetAllOrders(userID uint) ([]models.AllOrderResponse, err
 			UploadedDate: order.CreatedAt,
 			UploadedAt:   order.CreatedAt.Format(time.RFC3339),
 		}
-		response = append(response, resp)
+		page.Items = append(page.Items, resp)
+	}
+	return page, nil
+}
+
+// SaveOrders загружает пакет номеров заказов по тем же правилам, что и SaveOrder.
+// Новые заказы сохраняются одной транзакцией. Если параллельный запрос успел сохранить
+// часть номеров, пакет классифицируется заново и сохраняется повторно.
+func (s *OrderService) SaveOrders(ctx context.Context, userID uint, numbers []string) ([]models.BatchOrderResult, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.SaveOrders")
+	defer span.End()
+	const maxSaveAttempts = 3
+	var err error
+	for i := 0; i < maxSaveAttempts; i++ {
+		var results []models.BatchOrderResult
+		var accepted []entities.Order
+		results, accepted, err = s.classifyBatch(ctx, userID, numbers)
+		if err != nil {
+			return nil, err
+		}
+		if len(accepted) == 0 {
+			return results, nil
+		}
+		err = s.orderRepository.SaveAll(ctx, accepted)
+		if err == nil {
+			metrics.OrdersUploaded.Add(float64(len(accepted)))
+			// Постановка в очередь синхронная, как в SaveOrder: при заполненной очереди запрос ждёт,
+			// а не оставляет по горутине на каждый пакет
+			requestID := logger.RequestID(ctx)
+			spanContext := trace.SpanContextFromContext(ctx)
+			for _, order := range accepted {
+				s.orderProcessingChannel <- dto.OrderTask{Order: order, RequestID: requestID, Trace: spanContext}
+			}
+			return results, nil
+		}
+		pgErr, ok := err.(*pgconn.PgError)
+		if !ok || pgErr.Code != pgerrcode.UniqueViolation {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// classifyBatch определяет результат для каждого номера и собирает заказы, которые нужно сохранить.
+// Повтор номера внутри пакета считается повторной загрузкой этим же пользователем.
+func (s *OrderService) classifyBatch(ctx context.Context, userID uint, numbers []string) ([]models.BatchOrderResult, []entities.Order, error) {
+	isValid := make(map[string]bool, len(numbers))
+	valid := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		if checkOrderNumber(number) {
+			isValid[number] = true
+			valid = append(valid, number)
+		}
+	}
+	owners := make(map[string]uint, len(valid))
+	if len(valid) > 0 {
+		existing, err := s.orderRepository.GetOrdersByNumbers(ctx, valid)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, order := range existing {
+			owners[order.Number] = order.UserID
+		}
+	}
+	results := make([]models.BatchOrderResult, 0, len(numbers))
+	accepted := make([]entities.Order, 0, len(valid))
+	for _, number := range numbers {
+		result := models.BatchOrderResult{Number: number}
+		owner, exists := owners[number]
+		switch {
+		case !isValid[number]:
+			result.Result = models.BatchResultInvalidLuhn
+		case !exists:
+			result.Result = models.BatchResultAccepted
+			owners[number] = userID
+			accepted = append(accepted, entities.Order{Number: number, UserID: userID})
+		case owner == userID:
+			result.Result = models.BatchResultDuplicateOwn
+		default:
+			result.Result = models.BatchResultDuplicateForeign
+		}
+		results = append(results, result)
+	}
+	return results, accepted, nil
+}
+
+// GetOrder возвращает заказ пользователя вместе с историей попыток расчёта.
+// Чужой заказ неотличим от несуществующего.
+func (s *OrderService) GetOrder(ctx context.Context, userID uint, number string) (models.OrderResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrder")
+	defer span.End()
+	order, err := s.orderRepository.GetOrderByNumber(ctx, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && order.UserID != userID {
+		return models.OrderResponse{}, apperrors.Newf(apperrors.CodeNotFound, "order %s not found", number)
+	}
+	if err != nil {
+		return models.OrderResponse{}, err
+	}
+	attempts, err := s.orderRepository.GetOrderAttempts(ctx, order.ID)
+	if err != nil {
+		return models.OrderResponse{}, err
+	}
+	response := models.OrderResponse{
+		Number:     order.Number,
+		Status:     order.Status,
+		Accrual:    order.Accrual,
+		UploadedAt: order.CreatedAt.Format(time.RFC3339),
+		Attempts:   make([]models.OrderAttemptResponse, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		response.Attempts = append(response.Attempts, models.OrderAttemptResponse{
+			AttemptedAt: attempt.CreatedAt.Format(time.RFC3339),
+			HTTPStatus:  attempt.HTTPStatus,
+			Status:      attempt.Status,
+			Error:       attempt.Error,
+		})
 	}
-	sort.Slice(response, func(i, j int) bool {
-		return response[i].UploadedDate.Before(response[j].UploadedDate)
-	})
 	return response, nil
 }
 
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestOrderService_GetOrdersPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	createdAt := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	orders := []entities.Order{
+		{Entity: entities.Entity{Model: gorm.Model{ID: 1, CreatedAt: createdAt}}, Number: "1"},
+		{Entity: entities.Entity{Model: gorm.Model{ID: 2, CreatedAt: createdAt.Add(time.Minute)}}, Number: "2"},
+		{Entity: entities.Entity{Model: gorm.Model{ID: 3, CreatedAt: createdAt.Add(2 * time.Minute)}}, Number: "3"},
+	}
+	repository := mocks.NewMockOrderRepository(ctrl)
+	service := NewOrderService(repository, nil)
+
+	// первая страница: репозиторий просят на одну запись больше лимита
+	repository.EXPECT().GetOrdersPage(gomock.Any(), dto.PageFilter{
+		PageQuery: dto.PageQuery{UserID: 7, Limit: 3},
+	}).Return(orders, nil)
+	page, err := service.GetOrdersPage(context.Background(), dto.PageQuery{UserID: 7, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[1].Number != "2" {
+		t.Fatalf("unexpected items: %+v", page.Items)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("next cursor must be set when more rows are available")
+	}
+
+	// вторая страница начинается после последнего заказа первой
+	repository.EXPECT().GetOrdersPage(gomock.Any(), dto.PageFilter{
+		PageQuery: dto.PageQuery{UserID: 7, Limit: 3, Cursor: page.NextCursor},
+		After:     &dto.PageCursor{CreatedAt: orders[1].CreatedAt, ID: 2},
+	}).Return(orders[2:], nil)
+	page, err = service.GetOrdersPage(context.Background(), dto.PageQuery{UserID: 7, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+}
+
+func TestOrderService_GetOrdersPageInvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service := NewOrderService(mocks.NewMockOrderRepository(ctrl), nil)
+
+	descCursor := encodeCursor(time.Now(), 10, true, pageFilters(dto.PageQuery{Descending: true}))
+	processedCursor := encodeCursor(time.Now(), 10, false, pageFilters(dto.PageQuery{Statuses: []string{"PROCESSED"}}))
+	tests := []struct {
+		name  string
+		query dto.PageQuery
+	}{
+		{name: "Garbage", query: dto.PageQuery{Limit: 10, Cursor: "!!!"}},
+		{name: "Other sort order", query: dto.PageQuery{Limit: 10, Cursor: descCursor}},
+		{name: "Other filters", query: dto.PageQuery{Limit: 10, Cursor: processedCursor, Statuses: []string{"NEW"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetOrdersPage(context.Background(), tt.query)
+			if !errors.Is(err, apperrors.ErrInvalidRequest) {
+				t.Errorf("expected invalid request error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOrderService_GetOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	createdAt := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	order := entities.Order{
+		Entity:  entities.Entity{Model: gorm.Model{ID: 5, CreatedAt: createdAt}},
+		Number:  "12345678903",
+		UserID:  7,
+		Status:  "PROCESSED",
+		Accrual: 500,
+	}
+	attempts := []entities.OrderAttempt{
+		{Entity: entities.Entity{Model: gorm.Model{CreatedAt: createdAt}}, OrderID: 5, HTTPStatus: 429, Error: "too many requests"},
+		{Entity: entities.Entity{Model: gorm.Model{CreatedAt: createdAt.Add(time.Second)}}, OrderID: 5, HTTPStatus: 200, Status: "PROCESSED"},
+	}
+	repository := mocks.NewMockOrderRepository(ctrl)
+	service := NewOrderService(repository, nil)
+
+	repository.EXPECT().GetOrderByNumber(gomock.Any(), "12345678903").Return(order, nil).Times(2)
+	repository.EXPECT().GetOrderAttempts(gomock.Any(), uint(5)).Return(attempts, nil)
+	response, err := service.GetOrder(context.Background(), 7, "12345678903")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != "PROCESSED" || len(response.Attempts) != 2 || response.Attempts[0].HTTPStatus != 429 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	// заказ другого пользователя выглядит как несуществующий
+	_, err = service.GetOrder(context.Background(), 8, "12345678903")
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found for foreign order, got %v", err)
+	}
+
+	repository.EXPECT().GetOrderByNumber(gomock.Any(), "79927398713").Return(entities.Order{}, gorm.ErrRecordNotFound)
+	_, err = service.GetOrder(context.Background(), 7, "79927398713")
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found for unknown order, got %v", err)
+	}
+}
+
+func TestOrderService_SaveOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockOrderRepository(ctrl)
+	channel := make(chan dto.OrderTask, 10)
+	service := NewOrderService(repository, channel)
+
+	numbers := []string{"12345678903", "79927398713", "4561261212345467", "1234", "12345678903"}
+	repository.EXPECT().GetOrdersByNumbers(gomock.Any(), []string{"12345678903", "79927398713", "4561261212345467", "12345678903"}).
+		Return([]entities.Order{
+			{Number: "79927398713", UserID: 7},
+			{Number: "4561261212345467", UserID: 8},
+		}, nil)
+	repository.EXPECT().SaveAll(gomock.Any(), []entities.Order{{Number: "12345678903", UserID: 7}}).Return(nil)
+
+	results, err := service.SaveOrders(context.Background(), 7, numbers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		models.BatchResultAccepted,
+		models.BatchResultDuplicateOwn,
+		models.BatchResultDuplicateForeign,
+		models.BatchResultInvalidLuhn,
+		models.BatchResultDuplicateOwn,
+	}
+	for i, result := range results {
+		if result.Number != numbers[i] || result.Result != want[i] {
+			t.Errorf("result %d = %+v, want %s", i, result, want[i])
+		}
+	}
+	if task := <-channel; task.Order.Number != "12345678903" {
+		t.Errorf("unexpected order sent to processing: %+v", task.Order)
+	}
+}
+
+func TestOrderService_SaveOrdersRetriesOnConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockOrderRepository(ctrl)
+	service := NewOrderService(repository, make(chan dto.OrderTask, 10))
+
+	gomock.InOrder(
+		repository.EXPECT().GetOrdersByNumbers(gomock.Any(), []string{"12345678903"}).Return(nil, nil),
+		repository.EXPECT().SaveAll(gomock.Any(), gomock.Any()).Return(&pgconn.PgError{Code: pgerrcode.UniqueViolation}),
+		repository.EXPECT().GetOrdersByNumbers(gomock.Any(), []string{"12345678903"}).
+			Return([]entities.Order{{Number: "12345678903", UserID: 8}}, nil),
+	)
+
+	results, err := service.SaveOrders(context.Background(), 7, []string{"12345678903"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Result != models.BatchResultDuplicateForeign {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestOrderService_SaveOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockOrderRepository(ctrl)
+	channel := make(chan dto.OrderTask, 10)
+	service := NewOrderService(repository, channel)
+	conflict := &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+
+	t.Run("Already uploaded by this user", func(t *testing.T) {
+		repository.EXPECT().Save(gomock.Any(), gomock.Any()).Return(conflict)
+		repository.EXPECT().GetOrderByNumber(gomock.Any(), "12345678903").Return(entities.Order{Number: "12345678903", UserID: 7}, nil)
+		result, err := service.SaveOrder(context.Background(), dto.OrderDTO{Number: "12345678903", UserID: 7})
+		if err != nil || !result.AlreadyUploaded || result.Order.Number != "12345678903" {
+			t.Errorf("expected repeat upload result, got %+v, %v", result, err)
+		}
+		if len(channel) != 0 {
+			t.Error("repeat upload must not be queued for processing again")
+		}
+	})
+
+	t.Run("Uploaded by another user", func(t *testing.T) {
+		repository.EXPECT().Save(gomock.Any(), gomock.Any()).Return(conflict)
+		repository.EXPECT().GetOrderByNumber(gomock.Any(), "12345678903").Return(entities.Order{Number: "12345678903", UserID: 8}, nil)
+		_, err := service.SaveOrder(context.Background(), dto.OrderDTO{Number: "12345678903", UserID: 7})
+		if !errors.Is(err, apperrors.ErrOrderUploadedByOtherUser) {
+			t.Errorf("expected order uploaded by another user, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefix отличает ключи партнёров от прочих секретов, например при поиске утечек
+	apiKeyPrefix = "gm"
+	// maxAPIKeyRateLimit верхняя граница лимита запросов в минуту для одного ключа
+	maxAPIKeyRateLimit = 6000
+)
+
+var knownScopes = map[string]bool{
+	entities.ScopeOrdersWrite:    true,
+	entities.ScopeBalanceRead:    true,
+	entities.ScopeWebhooksManage: true,
+}
+
+// PartnerService управление партнёрами, их ключами и клиентами
+type PartnerService struct {
+	partnerRepository PartnerRepository
+	userRepository    UserRepository
+	defaultRateLimit  int
+}
+
+func NewPartnerService(
+	partnerRepository PartnerRepository,
+	userRepository UserRepository,
+	defaultRateLimit int,
+) *PartnerService {
+	return &PartnerService{
+		partnerRepository: partnerRepository,
+		userRepository:    userRepository,
+		defaultRateLimit:  defaultRateLimit,
+	}
+}
+
+func (s *PartnerService) CreatePartner(ctx context.Context, name string) (models.PartnerResponse, error) {
+	ctx, span := tracing.Start(ctx, "PartnerService.CreatePartner")
+	defer span.End()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.PartnerResponse{}, apperrors.InvalidRequest("partner name is required")
+	}
+	partner := entities.Partner{Name: name}
+	err := s.partnerRepository.SavePartner(ctx, &partner)
+	if err != nil {
+		pgErr, ok := err.(*pgconn.PgError)
+		if ok && pgErr.Code == pgerrcode.UniqueViolation {
+			return models.PartnerResponse{}, apperrors.ErrPartnerAlreadyExists
+		}
+		return models.PartnerResponse{}, err
+	}
+	return models.PartnerResponse{
+		ID:        partner.ID,
+		Name:      partner.Name,
+		CreatedAt: partner.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// CreateAPIKey выпускает ключ партнёру. Ключ возвращается только в ответе, в базе остаётся хэш.
+func (s *PartnerService) CreateAPIKey(ctx context.Context, keyDTO dto.APIKeyDTO) (models.APIKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "PartnerService.CreateAPIKey")
+	defer span.End()
+	if err := s.findPartner(ctx, keyDTO.PartnerID); err != nil {
+		return models.APIKeyResponse{}, err
+	}
+	var violations []string
+	if len(keyDTO.Scopes) == 0 {
+		violations = append(violations, "at least one scope is required")
+	}
+	for _, scope := range keyDTO.Scopes {
+		if !knownScopes[scope] {
+			violations = append(violations, "unknown scope "+scope)
+		}
+	}
+	rateLimit := keyDTO.RateLimit
+	if rateLimit == 0 {
+		rateLimit = s.defaultRateLimit
+	}
+	if rateLimit < 1 || rateLimit > maxAPIKeyRateLimit {
+		violations = append(violations, fmt.Sprintf("rate limit must be between 1 and %d requests per minute", maxAPIKeyRateLimit))
+	}
+	if len(violations) > 0 {
+		return models.APIKeyResponse{}, apperrors.ValidationFailed(violations)
+	}
+	prefix, err := randomHex(4)
+	if err != nil {
+		return models.APIKeyResponse{}, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return models.APIKeyResponse{}, err
+	}
+	key := apiKeyPrefix + "_" + prefix + "_" + secret
+	apiKey := entities.APIKey{
+		PartnerID: keyDTO.PartnerID,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		Scopes:    strings.Join(keyDTO.Scopes, ","),
+		RateLimit: rateLimit,
+	}
+	if err := s.partnerRepository.SaveAPIKey(ctx, &apiKey); err != nil {
+		return models.APIKeyResponse{}, err
+	}
+	return models.APIKeyResponse{
+		ID:        apiKey.ID,
+		Key:       key,
+		Prefix:    apiKey.Prefix,
+		Scopes:    keyDTO.Scopes,
+		RateLimit: apiKey.RateLimit,
+		CreatedAt: apiKey.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (s *PartnerService) RevokeAPIKey(ctx context.Context, partnerID uint, keyID uint) error {
+	ctx, span := tracing.Start(ctx, "PartnerService.RevokeAPIKey")
+	defer span.End()
+	revoked, err := s.partnerRepository.RevokeAPIKey(ctx, partnerID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return apperrors.Newf(apperrors.CodeNotFound, "active key %d of partner %d not found", keyID, partnerID)
+	}
+	return nil
+}
+
+// LinkUser связывает клиента партнёра с пользователем системы
+func (s *PartnerService) LinkUser(ctx context.Context, partnerID uint, externalID string, userID uint) error {
+	ctx, span := tracing.Start(ctx, "PartnerService.LinkUser")
+	defer span.End()
+	if externalID == "" {
+		return apperrors.InvalidRequest("external id is required")
+	}
+	if err := s.findPartner(ctx, partnerID); err != nil {
+		return err
+	}
+	_, err := s.userRepository.FindUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.Newf(apperrors.CodeNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.partnerRepository.LinkPartnerUser(ctx, &entities.PartnerUser{
+		PartnerID:  partnerID,
+		ExternalID: externalID,
+		UserID:     userID,
+	})
+}
+
+// ResolveUser находит пользователя, от имени которого партнёр действует для своего клиента
+func (s *PartnerService) ResolveUser(ctx context.Context, partnerID uint, externalID string) (uint, error) {
+	ctx, span := tracing.Start(ctx, "PartnerService.ResolveUser")
+	defer span.End()
+	link, err := s.partnerRepository.FindPartnerUser(ctx, partnerID, externalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, apperrors.Newf(apperrors.CodeNotFound, "customer %s is not linked to a user", externalID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return link.UserID, nil
+}
+
+// Authenticate проверяет API-ключ. Неизвестный, испорченный и отозванный ключи неразличимы для клиента.
+func (s *PartnerService) Authenticate(ctx context.Context, key string) (dto.PartnerPrincipal, error) {
+	ctx, span := tracing.Start(ctx, "PartnerService.Authenticate")
+	defer span.End()
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return dto.PartnerPrincipal{}, apperrors.ErrInvalidAPIKey
+	}
+	apiKey, err := s.partnerRepository.FindAPIKeyByPrefix(ctx, parts[1])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.PartnerPrincipal{}, apperrors.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return dto.PartnerPrincipal{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashToken(key))) != 1 || apiKey.RevokedAt != nil {
+		return dto.PartnerPrincipal{}, apperrors.ErrInvalidAPIKey
+	}
+	if err := s.partnerRepository.TouchAPIKey(ctx, apiKey.ID, time.Now()); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update last use of API key %d: %v", apiKey.ID, err)
+	}
+	return dto.PartnerPrincipal{
+		PartnerID: apiKey.PartnerID,
+		KeyID:     apiKey.ID,
+		Scopes:    strings.Split(apiKey.Scopes, ","),
+		RateLimit: apiKey.RateLimit,
+	}, nil
+}
+
+func (s *PartnerService) findPartner(ctx context.Context, partnerID uint) error {
+	_, err := s.partnerRepository.FindPartnerByID(ctx, partnerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.Newf(apperrors.CodeNotFound, "partner %d not found", partnerID)
+	}
+	return err
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
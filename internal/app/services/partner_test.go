@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartnerService_CreateAPIKeyAndAuthenticate(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	partners := mocks.NewMockPartnerRepository(ctrl)
+	service := NewPartnerService(partners, mocks.NewMockUserRepository(ctrl), 60)
+
+	var saved entities.APIKey
+	partners.EXPECT().FindPartnerByID(gomock.Any(), uint(2)).Return(entities.Partner{Name: "shop"}, nil)
+	partners.EXPECT().SaveAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key *entities.APIKey) error {
+		key.ID = 5
+		saved = *key
+		return nil
+	})
+	created, err := service.CreateAPIKey(context.Background(), dto.APIKeyDTO{
+		PartnerID: 2,
+		Scopes:    []string{entities.ScopeOrdersWrite, entities.ScopeBalanceRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, "gm_"+saved.Prefix+"_") || saved.KeyHash == created.Key || saved.RateLimit != 60 {
+		t.Fatalf("unexpected key %+v saved as %+v", created, saved)
+	}
+
+	partners.EXPECT().FindAPIKeyByPrefix(gomock.Any(), saved.Prefix).Return(saved, nil)
+	partners.EXPECT().TouchAPIKey(gomock.Any(), uint(5), gomock.Any()).Return(nil)
+	principal, err := service.Authenticate(context.Background(), created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.PartnerID != 2 || principal.KeyID != 5 || len(principal.Scopes) != 2 {
+		t.Errorf("unexpected principal: %+v", principal)
+	}
+
+	partners.EXPECT().FindAPIKeyByPrefix(gomock.Any(), saved.Prefix).Return(saved, nil)
+	if _, err := service.Authenticate(context.Background(), created.Key+"x"); !errors.Is(err, apperrors.ErrInvalidAPIKey) {
+		t.Errorf("expected invalid key for wrong secret, got %v", err)
+	}
+
+	revokedAt := time.Now()
+	saved.RevokedAt = &revokedAt
+	partners.EXPECT().FindAPIKeyByPrefix(gomock.Any(), saved.Prefix).Return(saved, nil)
+	if _, err := service.Authenticate(context.Background(), created.Key); !errors.Is(err, apperrors.ErrInvalidAPIKey) {
+		t.Errorf("expected invalid key for revoked key, got %v", err)
+	}
+
+	partners.EXPECT().FindAPIKeyByPrefix(gomock.Any(), "nope").Return(entities.APIKey{}, gorm.ErrRecordNotFound)
+	if _, err := service.Authenticate(context.Background(), "gm_nope_secret"); !errors.Is(err, apperrors.ErrInvalidAPIKey) {
+		t.Errorf("expected invalid key for unknown prefix, got %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), "not-a-key"); !errors.Is(err, apperrors.ErrInvalidAPIKey) {
+		t.Errorf("expected invalid key for malformed key, got %v", err)
+	}
+}
+
+func TestPartnerService_CreateAPIKeyValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	partners := mocks.NewMockPartnerRepository(ctrl)
+	service := NewPartnerService(partners, mocks.NewMockUserRepository(ctrl), 60)
+
+	partners.EXPECT().FindPartnerByID(gomock.Any(), uint(2)).Return(entities.Partner{}, nil)
+	_, err := service.CreateAPIKey(context.Background(), dto.APIKeyDTO{PartnerID: 2, Scopes: []string{"orders:delete"}, RateLimit: -1})
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || len(appErr.Violations) != 2 {
+		t.Errorf("expected two violations, got %v", err)
+	}
+
+	partners.EXPECT().FindPartnerByID(gomock.Any(), uint(3)).Return(entities.Partner{}, gorm.ErrRecordNotFound)
+	if _, err := service.CreateAPIKey(context.Background(), dto.APIKeyDTO{PartnerID: 3}); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestPartnerService_ResolveUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	partners := mocks.NewMockPartnerRepository(ctrl)
+	service := NewPartnerService(partners, mocks.NewMockUserRepository(ctrl), 60)
+
+	partners.EXPECT().FindPartnerUser(gomock.Any(), uint(2), "c-1").Return(entities.PartnerUser{UserID: 9}, nil)
+	userID, err := service.ResolveUser(context.Background(), 2, "c-1")
+	if err != nil || userID != 9 {
+		t.Errorf("expected user 9, got %d, %v", userID, err)
+	}
+
+	partners.EXPECT().FindPartnerUser(gomock.Any(), uint(2), "c-2").Return(entities.PartnerUser{}, gorm.ErrRecordNotFound)
+	if _, err := service.ResolveUser(context.Background(), 2, "c-2"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	passwordResetSubject = "Password reset"
+	// notificationTimeout сколько ждать отправки письма, которое уходит уже после ответа клиенту
+	notificationTimeout = time.Minute
+)
+
+// PasswordResetService сброс забытого пароля по одноразовому токену,
+// который доставляется пользователю через Notifier.
+type PasswordResetService struct {
+	userRepository  UserRepository
+	resetRepository PasswordResetRepository
+	notifier        Notifier
+	guard           *LoginGuard
+	policy          CredentialsPolicy
+	tokenTTL        time.Duration
+}
+
+func NewPasswordResetService(
+	userRepository UserRepository,
+	resetRepository PasswordResetRepository,
+	notifier Notifier,
+	guard *LoginGuard,
+	policy CredentialsPolicy,
+	tokenTTL time.Duration,
+) *PasswordResetService {
+	return &PasswordResetService{
+		userRepository:  userRepository,
+		resetRepository: resetRepository,
+		notifier:        notifier,
+		guard:           guard,
+		policy:          policy,
+		tokenTTL:        tokenTTL,
+	}
+}
+
+// RequestReset создаёт токен сброса и отправляет его пользователю. Для неизвестного логина
+// и пользователя без адреса ничего не происходит, а ответ клиенту тот же, чтобы не раскрывать,
+// какие логины существуют. Сообщение отправляется асинхронно по той же причине.
+// Число запросов ограничено по логину и IP-адресу независимо от того, существует ли логин.
+func (s *PasswordResetService) RequestReset(ctx context.Context, login string, ip string) error {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.RequestReset")
+	defer span.End()
+	if err := s.guard.CheckReset(ctx, login, ip); err != nil {
+		return err
+	}
+	if err := s.guard.RegisterReset(ctx, login, ip); err != nil {
+		return err
+	}
+	user, err := s.userRepository.FindUserByUserName(ctx, login)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		logger.FromContext(ctx).Infof("password reset requested for user %d without email", user.ID)
+		return nil
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.tokenTTL)
+	err = s.resetRepository.SaveResetToken(ctx, &entities.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	notification := dto.Notification{
+		To:      user.Email,
+		Subject: passwordResetSubject,
+		Body: fmt.Sprintf("Use this token to reset the password for %s: %s\nThe token expires at %s.",
+			user.UserName, token, expiresAt.UTC().Format(time.RFC1123)),
+	}
+	// Запрос к этому моменту завершится, поэтому письмо отправляется в своём контексте
+	// с собственным таймаутом, сохраняя трассировку и логер запроса
+	sendCtx := logger.NewContext(trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx)),
+		logger.FromContext(ctx))
+	go func() {
+		sendCtx, cancel := context.WithTimeout(sendCtx, notificationTimeout)
+		defer cancel()
+		if err := s.notifier.Send(sendCtx, notification); err != nil {
+			logger.FromContext(sendCtx).Errorf("failed to send password reset notification to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// ConfirmReset устанавливает новый пароль по токену и возвращает пользователя, чей пароль сброшен
+func (s *PasswordResetService) ConfirmReset(ctx context.Context, token string, newPassword string) (uint, error) {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.ConfirmReset")
+	defer span.End()
+	resetToken, err := s.resetRepository.FindResetTokenByHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, apperrors.ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return 0, apperrors.ErrInvalidResetToken
+	}
+	user, err := s.userRepository.FindUserByID(ctx, resetToken.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if violations := s.policy.ValidatePassword(newPassword, user.UserName); len(violations) > 0 {
+		return 0, apperrors.ValidationFailed(violations)
+	}
+	marked, err := s.resetRepository.MarkResetTokenUsed(ctx, resetToken.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !marked {
+		return 0, apperrors.ErrInvalidResetToken
+	}
+	user.Password = hashPassword(newPassword)
+	if err := s.userRepository.Update(ctx, &user); err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetService_RequestReset(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	resets := mocks.NewMockPasswordResetRepository(ctrl)
+	notifier := mocks.NewMockNotifier(ctrl)
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	policy := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	service := NewPasswordResetService(users, resets, notifier, NewLoginGuard(throttles, policy, policy),
+		testCredentialsPolicy, 30*time.Minute)
+
+	t.Run("Unknown login", func(t *testing.T) {
+		throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"reset:login:nobody", "reset:ip:10.0.0.1"}).Return(nil, nil)
+		throttles.EXPECT().IncrementLoginFailures(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+		users.EXPECT().FindUserByUserName(gomock.Any(), "nobody").Return(entities.User{}, gorm.ErrRecordNotFound)
+		if err := service.RequestReset(context.Background(), "nobody", "10.0.0.1"); err != nil {
+			t.Errorf("unknown login must not be reported, got %v", err)
+		}
+	})
+
+	t.Run("Token is sent and only its hash is stored", func(t *testing.T) {
+		user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, UserName: "alice", Email: "alice@example.com"}
+		throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"reset:login:alice", "reset:ip:10.0.0.1"}).Return(nil, nil)
+		throttles.EXPECT().IncrementLoginFailures(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+		users.EXPECT().FindUserByUserName(gomock.Any(), "alice").Return(user, nil)
+		var saved *entities.PasswordResetToken
+		resets.EXPECT().SaveResetToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token *entities.PasswordResetToken) error {
+			saved = token
+			return nil
+		})
+		sent := make(chan dto.Notification, 1)
+		requestDone := make(chan struct{})
+		notifier.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, notification dto.Notification) error {
+			// отправка переживает завершение запроса, но ограничена по времени
+			<-requestDone
+			if _, ok := ctx.Deadline(); !ok || ctx.Err() != nil {
+				t.Errorf("notification must be sent with its own deadline, got err %v", ctx.Err())
+			}
+			sent <- notification
+			return nil
+		})
+		requestCtx, cancel := context.WithCancel(context.Background())
+		if err := service.RequestReset(requestCtx, "alice", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		close(requestDone)
+		notification := <-sent
+		if notification.To != "alice@example.com" {
+			t.Errorf("unexpected recipient: %s", notification.To)
+		}
+		if saved.UserID != 7 || strings.Contains(notification.Body, saved.TokenHash) {
+			t.Errorf("unexpected reset token record: %+v", saved)
+		}
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		blockedUntil := time.Now().Add(time.Minute)
+		throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"reset:login:alice", "reset:ip:10.0.0.1"}).
+			Return([]entities.LoginThrottle{{ThrottleKey: "reset:login:alice", BlockedUntil: &blockedUntil}}, nil)
+		err := service.RequestReset(context.Background(), "alice", "10.0.0.1")
+		var appErr *apperrors.Error
+		if !errors.As(err, &appErr) || appErr.Code != apperrors.CodeTooManyAttempts || appErr.RetryAfter <= 0 {
+			t.Errorf("expected too many attempts, got %v", err)
+		}
+	})
+}
+
+func TestPasswordResetService_ConfirmReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	resets := mocks.NewMockPasswordResetRepository(ctrl)
+	service := NewPasswordResetService(users, resets, mocks.NewMockNotifier(ctrl), nil, testCredentialsPolicy, 30*time.Minute)
+	usedAt := time.Now().Add(-time.Minute)
+	active := entities.PasswordResetToken{
+		Entity:    entities.Entity{Model: gorm.Model{ID: 3}},
+		UserID:    7,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, UserName: "alice"}
+
+	t.Run("Success", func(t *testing.T) {
+		resets.EXPECT().FindResetTokenByHash(gomock.Any(), hashToken("token")).Return(active, nil)
+		users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil)
+		resets.EXPECT().MarkResetTokenUsed(gomock.Any(), uint(3)).Return(true, nil)
+		users.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		userID, err := service.ConfirmReset(context.Background(), "token", "New password 1")
+		if err != nil || userID != 7 {
+			t.Errorf("unexpected result: %d, %v", userID, err)
+		}
+	})
+
+	t.Run("Used token", func(t *testing.T) {
+		used := active
+		used.UsedAt = &usedAt
+		resets.EXPECT().FindResetTokenByHash(gomock.Any(), hashToken("token")).Return(used, nil)
+		if _, err := service.ConfirmReset(context.Background(), "token", "New password 1"); !errors.Is(err, apperrors.ErrInvalidResetToken) {
+			t.Errorf("expected invalid reset token, got %v", err)
+		}
+	})
+
+	t.Run("Weak password", func(t *testing.T) {
+		resets.EXPECT().FindResetTokenByHash(gomock.Any(), hashToken("token")).Return(active, nil)
+		users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil)
+		if _, err := service.ConfirmReset(context.Background(), "token", "short"); !errors.Is(err, apperrors.ErrInvalidRequest) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		resets.EXPECT().FindResetTokenByHash(gomock.Any(), hashToken("unknown")).Return(entities.PasswordResetToken{}, gorm.ErrRecordNotFound)
+		if _, err := service.ConfirmReset(context.Background(), "unknown", "New password 1"); !errors.Is(err, apperrors.ErrInvalidResetToken) {
+			t.Errorf("expected invalid reset token, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// sessionTouchInterval как часто обновляется время последней активности сессии
+	sessionTouchInterval = time.Minute
+	maxDeviceLength      = 255
+)
+
+type Claims struct {
+	UserID uint   `json:"userID"`
+	Role   string `json:"role"`
+	// SessionID идентификатор сессии (цепочки ротации), в которой выпущен токен
+	SessionID string `json:"sid"`
+	jwt.StandardClaims
+}
+
+// TokenService выдаёт короткоживущие access-токены и ротируемые refresh-токены,
+// а также отвечает за их отзыв.
+type TokenService struct {
+	tokenRepository TokenRepository
+	userRepository  UserRepository
+	signer          TokenSigner
+	accessTTL       time.Duration
+	refreshTTL      time.Duration
+}
+
+func NewTokenService(
+	tokenRepository TokenRepository,
+	userRepository UserRepository,
+	signer TokenSigner,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+) *TokenService {
+	return &TokenService{
+		tokenRepository: tokenRepository,
+		userRepository:  userRepository,
+		signer:          signer,
+		accessTTL:       accessTTL,
+		refreshTTL:      refreshTTL,
+	}
+}
+
+// IssueTokens выдаёт пару токенов, открывая новую сессию и цепочку ротации
+func (s *TokenService) IssueTokens(ctx context.Context, userID uint, client dto.ClientInfo) (models.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.IssueTokens")
+	defer span.End()
+	familyID, err := randomToken(16)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	now := time.Now()
+	err = s.tokenRepository.SaveSession(ctx, &entities.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		Device:     truncate(client.UserAgent, maxDeviceLength),
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.issue(ctx, userID, familyID)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Повторное предъявление уже использованного токена
+// означает, что он утёк, поэтому вся цепочка отзывается.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string, client dto.ClientInfo) (models.TokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Refresh")
+	defer span.End()
+	token, err := s.tokenRepository.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.TokenResponse{}, apperrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return models.TokenResponse{}, apperrors.ErrInvalidRefreshToken
+	}
+	marked, err := s.tokenRepository.MarkRefreshTokenUsed(ctx, token.ID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if token.UsedAt != nil || !marked {
+		logger.FromContext(ctx).Warnf("refresh token reuse detected for user %d, revoking token family", token.UserID)
+		if err := s.tokenRepository.RevokeFamily(ctx, token.FamilyID); err != nil {
+			return models.TokenResponse{}, err
+		}
+		return models.TokenResponse{}, apperrors.ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	if err := s.tokenRepository.ExtendSession(ctx, token.FamilyID, client.IP, now, now.Add(s.refreshTTL)); err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.issue(ctx, token.UserID, token.FamilyID)
+}
+
+// Logout отзывает текущий access-токен и завершает его сессию. Для токенов без сессии
+// завершается цепочка переданного refresh-токена, если он есть.
+func (s *TokenService) Logout(
+	ctx context.Context,
+	userID uint,
+	accessJTI string,
+	accessExpiresAt time.Time,
+	sessionID string,
+	refreshToken string,
+) error {
+	ctx, span := tracing.Start(ctx, "TokenService.Logout")
+	defer span.End()
+	if err := s.tokenRepository.RevokeJTI(ctx, accessJTI, accessExpiresAt); err != nil {
+		return err
+	}
+	if sessionID != "" {
+		if err := s.tokenRepository.RevokeFamily(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.tokenRepository.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && token.UserID != userID {
+		return apperrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokenRepository.RevokeFamily(ctx, token.FamilyID)
+}
+
+// RevokeAllForUser завершает все сессии пользователя
+func (s *TokenService) RevokeAllForUser(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeAllForUser")
+	defer span.End()
+	return s.tokenRepository.RevokeUserTokens(ctx, userID)
+}
+
+// ListSessions активные сессии пользователя. currentSessionID отмечает сессию, из которой сделан запрос.
+func (s *TokenService) ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ListSessions")
+	defer span.End()
+	sessions, err := s.tokenRepository.FindActiveSessions(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, models.SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+			Current:    currentSessionID != "" && session.FamilyID == currentSessionID,
+		})
+	}
+	return response, nil
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве
+func (s *TokenService) RevokeSession(ctx context.Context, userID uint, id uint) error {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeSession")
+	defer span.End()
+	session, err := s.tokenRepository.FindUserSession(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.New(apperrors.CodeNotFound, "session not found")
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokenRepository.RevokeFamily(ctx, session.FamilyID)
+}
+
+// IsRevoked проверяет, отозван ли access-токен сам по себе или вместе со своей сессией.
+// Заодно отмечает активность сессии, но не чаще раза в sessionTouchInterval.
+// У токенов, выпущенных до появления сессий, sessionID пустой, и проверяется только jti.
+func (s *TokenService) IsRevoked(ctx context.Context, jti string, sessionID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.IsRevoked")
+	defer span.End()
+	revoked, err := s.tokenRepository.IsRevoked(ctx, jti)
+	if err != nil || revoked || sessionID == "" {
+		return revoked, err
+	}
+	session, err := s.tokenRepository.FindSessionByFamily(ctx, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if session.RevokedAt != nil {
+		return true, nil
+	}
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.tokenRepository.TouchSession(ctx, sessionID, now); err != nil {
+			logger.FromContext(ctx).Errorf("failed to update session last seen time: %v", err)
+		}
+	}
+	return false, nil
+}
+
+// issue выдаёт пару токенов. Роль читается из базы при каждой выдаче,
+// поэтому её изменение вступает в силу не позже следующей ротации.
+func (s *TokenService) issue(ctx context.Context, userID uint, familyID string) (models.TokenResponse, error) {
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	now := time.Now()
+	jti, err := randomToken(16)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	accessToken, err := createToken(userID, user.Role, jti, familyID, now.Add(s.accessTTL), s.signer)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	err = s.tokenRepository.SaveRefreshToken(ctx, &entities.RefreshToken{
+		UserID:          userID,
+		FamilyID:        familyID,
+		TokenHash:       hashToken(refreshToken),
+		AccessJTI:       jti,
+		AccessExpiresAt: now.Add(s.accessTTL),
+		ExpiresAt:       now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func createToken(
+	userID uint,
+	role string,
+	jti string,
+	sessionID string,
+	expiresAt time.Time,
+	signer TokenSigner,
+) (string, error) {
+	if userID == 0 {
+		return "", errors.New("invalid token credentials")
+	}
+	claims := Claims{
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+	return signer.Sign(claims)
+}
+
+// truncate обрезает строку до max символов, не разрывая их
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken хэш refresh-токена для хранения в базе. Токен случайный и длинный, поэтому соль не нужна.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
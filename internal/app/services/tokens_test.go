@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/keyring"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestTokenService_IssueTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	users := mocks.NewMockUserRepository(ctrl)
+	service := NewTokenService(repository, users, newTestKeyring(t), 15*time.Minute, time.Hour)
+
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(entities.User{Role: entities.RoleAdmin}, nil)
+	var session *entities.Session
+	repository.EXPECT().SaveSession(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, s *entities.Session) error {
+		session = s
+		return nil
+	})
+	var saved *entities.RefreshToken
+	repository.EXPECT().SaveRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token *entities.RefreshToken) error {
+		saved = token
+		return nil
+	})
+	tokens, err := service.IssueTokens(context.Background(), 7, dto.ClientInfo{IP: "10.0.0.1", UserAgent: "test-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.UserID != 7 || saved.TokenHash != hashToken(tokens.RefreshToken) || saved.FamilyID == "" {
+		t.Errorf("unexpected refresh token record: %+v", saved)
+	}
+	if session.UserID != 7 || session.FamilyID != saved.FamilyID || session.Device != "test-agent" || session.IP != "10.0.0.1" {
+		t.Errorf("unexpected session: %+v", session)
+	}
+	var claims Claims
+	_, err = jwt.ParseWithClaims(tokens.AccessToken, &claims, service.signer.(*keyring.Keyring).Keyfunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 7 || claims.Role != entities.RoleAdmin || claims.Id != saved.AccessJTI ||
+		claims.SessionID != saved.FamilyID {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestTokenService_Refresh(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	users := mocks.NewMockUserRepository(ctrl)
+	service := NewTokenService(repository, users, newTestKeyring(t), 15*time.Minute, time.Hour)
+	usedAt := time.Now().Add(-time.Minute)
+	revokedAt := time.Now().Add(-time.Minute)
+	active := entities.RefreshToken{
+		Entity:    entities.Entity{Model: gorm.Model{ID: 3}},
+		UserID:    7,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	t.Run("Rotation", func(t *testing.T) {
+		repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("refresh")).Return(active, nil)
+		repository.EXPECT().MarkRefreshTokenUsed(gomock.Any(), uint(3)).Return(true, nil)
+		repository.EXPECT().ExtendSession(gomock.Any(), "family", "10.0.0.2", gomock.Any(), gomock.Any()).Return(nil)
+		users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(entities.User{Role: entities.RoleUser}, nil)
+		repository.EXPECT().SaveRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token *entities.RefreshToken) error {
+			if token.FamilyID != "family" || token.UserID != 7 {
+				t.Errorf("rotated token must stay in the same family: %+v", token)
+			}
+			return nil
+		})
+		tokens, err := service.Refresh(context.Background(), "refresh", dto.ClientInfo{IP: "10.0.0.2"})
+		if err != nil || tokens.RefreshToken == "" || tokens.RefreshToken == "refresh" {
+			t.Errorf("unexpected result: %+v, %v", tokens, err)
+		}
+	})
+
+	t.Run("Reuse revokes family", func(t *testing.T) {
+		used := active
+		used.UsedAt = &usedAt
+		repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("refresh")).Return(used, nil)
+		repository.EXPECT().MarkRefreshTokenUsed(gomock.Any(), uint(3)).Return(false, nil)
+		repository.EXPECT().RevokeFamily(gomock.Any(), "family").Return(nil)
+		_, err := service.Refresh(context.Background(), "refresh", dto.ClientInfo{})
+		if !errors.Is(err, apperrors.ErrInvalidRefreshToken) {
+			t.Errorf("expected invalid refresh token, got %v", err)
+		}
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		revoked := active
+		revoked.RevokedAt = &revokedAt
+		repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("refresh")).Return(revoked, nil)
+		_, err := service.Refresh(context.Background(), "refresh", dto.ClientInfo{})
+		if !errors.Is(err, apperrors.ErrInvalidRefreshToken) {
+			t.Errorf("expected invalid refresh token, got %v", err)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("unknown")).Return(entities.RefreshToken{}, gorm.ErrRecordNotFound)
+		_, err := service.Refresh(context.Background(), "unknown", dto.ClientInfo{})
+		if !errors.Is(err, apperrors.ErrInvalidRefreshToken) {
+			t.Errorf("expected invalid refresh token, got %v", err)
+		}
+	})
+}
+
+func TestTokenService_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	service := NewTokenService(repository, mocks.NewMockUserRepository(ctrl), newTestKeyring(t), 15*time.Minute, time.Hour)
+	expiresAt := time.Now().Add(time.Minute)
+
+	repository.EXPECT().RevokeJTI(gomock.Any(), "jti", expiresAt).Return(nil).Times(3)
+	repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("refresh")).
+		Return(entities.RefreshToken{UserID: 8, FamilyID: "family"}, nil)
+	if err := service.Logout(context.Background(), 7, "jti", expiresAt, "", "refresh"); !errors.Is(err, apperrors.ErrInvalidRefreshToken) {
+		t.Errorf("foreign refresh token must be rejected, got %v", err)
+	}
+
+	repository.EXPECT().FindRefreshTokenByHash(gomock.Any(), hashToken("refresh")).
+		Return(entities.RefreshToken{UserID: 7, FamilyID: "family"}, nil)
+	repository.EXPECT().RevokeFamily(gomock.Any(), "family").Return(nil)
+	if err := service.Logout(context.Background(), 7, "jti", expiresAt, "", "refresh"); err != nil {
+		t.Fatal(err)
+	}
+
+	repository.EXPECT().RevokeFamily(gomock.Any(), "session").Return(nil)
+	if err := service.Logout(context.Background(), 7, "jti", expiresAt, "session", ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenService_IsRevoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	service := NewTokenService(repository, mocks.NewMockUserRepository(ctrl), newTestKeyring(t), 15*time.Minute, time.Hour)
+	revokedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		sessionID     string
+		jtiRevoked    bool
+		findCallCount int
+		session       entities.Session
+		findError     error
+		touchCount    int
+		want          bool
+	}{
+		{name: "Revoked token", sessionID: "family", jtiRevoked: true, want: true},
+		{name: "Token without session", want: false},
+		{
+			name:          "Active session seen recently",
+			sessionID:     "family",
+			findCallCount: 1,
+			session:       entities.Session{FamilyID: "family", LastSeenAt: time.Now()},
+			want:          false,
+		},
+		{
+			name:          "Active session updates last seen",
+			sessionID:     "family",
+			findCallCount: 1,
+			session:       entities.Session{FamilyID: "family", LastSeenAt: time.Now().Add(-time.Hour)},
+			touchCount:    1,
+			want:          false,
+		},
+		{
+			name:          "Revoked session",
+			sessionID:     "family",
+			findCallCount: 1,
+			session:       entities.Session{FamilyID: "family", RevokedAt: &revokedAt},
+			want:          true,
+		},
+		{
+			name:          "Unknown session",
+			sessionID:     "family",
+			findCallCount: 1,
+			findError:     gorm.ErrRecordNotFound,
+			want:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository.EXPECT().IsRevoked(gomock.Any(), "jti").Return(tt.jtiRevoked, nil)
+			repository.EXPECT().FindSessionByFamily(gomock.Any(), "family").Return(tt.session, tt.findError).Times(tt.findCallCount)
+			repository.EXPECT().TouchSession(gomock.Any(), "family", gomock.Any()).Return(nil).Times(tt.touchCount)
+			revoked, err := service.IsRevoked(context.Background(), "jti", tt.sessionID)
+			if err != nil || revoked != tt.want {
+				t.Errorf("expected %v, got %v, %v", tt.want, revoked, err)
+			}
+		})
+	}
+}
+
+func TestTokenService_ListSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	service := NewTokenService(repository, mocks.NewMockUserRepository(ctrl), newTestKeyring(t), 15*time.Minute, time.Hour)
+	seenAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	repository.EXPECT().FindActiveSessions(gomock.Any(), uint(7), gomock.Any()).Return([]entities.Session{
+		{Entity: entities.Entity{Model: gorm.Model{ID: 1, CreatedAt: seenAt}}, FamilyID: "current", Device: "Firefox", LastSeenAt: seenAt},
+		{Entity: entities.Entity{Model: gorm.Model{ID: 2, CreatedAt: seenAt}}, FamilyID: "other", Device: "curl", LastSeenAt: seenAt},
+	}, nil)
+
+	sessions, err := service.ListSessions(context.Background(), 7, "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || !sessions[0].Current || sessions[1].Current || sessions[0].LastSeenAt != "2023-05-01T12:00:00Z" {
+		t.Errorf("unexpected sessions: %+v", sessions)
+	}
+}
+
+func TestTokenService_RevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockTokenRepository(ctrl)
+	service := NewTokenService(repository, mocks.NewMockUserRepository(ctrl), newTestKeyring(t), 15*time.Minute, time.Hour)
+
+	repository.EXPECT().FindUserSession(gomock.Any(), uint(7), uint(2)).Return(entities.Session{FamilyID: "family"}, nil)
+	repository.EXPECT().RevokeFamily(gomock.Any(), "family").Return(nil)
+	if err := service.RevokeSession(context.Background(), 7, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	repository.EXPECT().FindUserSession(gomock.Any(), uint(7), uint(3)).Return(entities.Session{}, gorm.ErrRecordNotFound)
+	if err := service.RevokeSession(context.Background(), 7, 3); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func newTestKeyring(t *testing.T) *keyring.Keyring {
+	keys := keyring.New()
+	if err := keys.AddHMAC("test", []byte("abcdefghijklmnopqrstuvwxyz123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetSigningKey("test"); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
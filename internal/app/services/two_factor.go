@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/totp"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount    = 10
+	maxChallengeAttempts = 5
+	// totpSkew допустимое расхождение часов клиента и сервера в шагах
+	totpSkew = 1
+)
+
+// TwoFactorService второй фактор на основе TOTP: подключение, коды восстановления,
+// двухшаговый вход и подтверждение отдельных операций свежим кодом.
+type TwoFactorService struct {
+	userRepository UserRepository
+	repository     TwoFactorRepository
+	guard          *LoginGuard
+	issuer         string
+	challengeTTL   time.Duration
+}
+
+func NewTwoFactorService(
+	userRepository UserRepository,
+	repository TwoFactorRepository,
+	guard *LoginGuard,
+	issuer string,
+	challengeTTL time.Duration,
+) *TwoFactorService {
+	return &TwoFactorService{
+		userRepository: userRepository,
+		repository:     repository,
+		guard:          guard,
+		issuer:         issuer,
+		challengeTTL:   challengeTTL,
+	}
+}
+
+// Enroll создаёт новый секрет. Второй фактор включается только после Confirm.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID uint) (models.TOTPEnrollmentResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.TOTPEnrollmentResponse{}, err
+	}
+	if user.TOTPEnabled {
+		return models.TOTPEnrollmentResponse{}, apperrors.ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPEnrollmentResponse{}, err
+	}
+	if err := s.repository.UpdateTOTP(ctx, userID, secret, false); err != nil {
+		return models.TOTPEnrollmentResponse{}, err
+	}
+	return models.TOTPEnrollmentResponse{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.UserName, secret),
+	}, nil
+}
+
+// Confirm включает второй фактор по первому коду из приложения и возвращает коды восстановления.
+// Коды показываются один раз, в базе хранятся только их хэши.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, apperrors.ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, apperrors.InvalidRequest("two-factor enrollment has not been started")
+	}
+	if err := s.guarded(ctx, userID, func() error { return s.checkTOTP(ctx, user, code) }); err != nil {
+		return nil, err
+	}
+	codes, records, err := generateRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	if err := s.repository.UpdateTOTP(ctx, userID, user.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable отключает второй фактор. Нужен действующий код или код восстановления.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Disable")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return apperrors.InvalidRequest("two-factor authentication is not enabled")
+	}
+	if err := s.guarded(ctx, userID, func() error { return s.verify(ctx, user, code) }); err != nil {
+		return err
+	}
+	if err := s.repository.UpdateTOTP(ctx, userID, "", false); err != nil {
+		return err
+	}
+	return s.repository.ReplaceRecoveryCodes(ctx, userID, nil)
+}
+
+// CreateChallenge открывает второй шаг входа после проверки пароля
+func (s *TwoFactorService) CreateChallenge(ctx context.Context, userID uint) (models.LoginChallengeResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.CreateChallenge")
+	defer span.End()
+	token, err := randomToken(32)
+	if err != nil {
+		return models.LoginChallengeResponse{}, err
+	}
+	err = s.repository.SaveChallenge(ctx, &entities.LoginChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	})
+	if err != nil {
+		return models.LoginChallengeResponse{}, err
+	}
+	return models.LoginChallengeResponse{
+		TwoFactorRequired: true,
+		Challenge:         token,
+		ExpiresIn:         int64(s.challengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteChallenge завершает вход кодом второго фактора или кодом восстановления.
+// После maxChallengeAttempts неверных кодов вход нужно начинать заново с пароля,
+// а общий счётчик неверных кодов пользователя не даёт продолжать перебор новыми попытками входа.
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, challengeToken string, code string) (uint, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.CompleteChallenge")
+	defer span.End()
+	challenge, err := s.repository.FindChallengeByHash(ctx, hashToken(challengeToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, apperrors.ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return 0, err
+	}
+	if challenge.UsedAt != nil || challenge.Attempts >= maxChallengeAttempts || time.Now().After(challenge.ExpiresAt) {
+		return 0, apperrors.ErrInvalidLoginChallenge
+	}
+	user, err := s.userRepository.FindUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.guarded(ctx, user.ID, func() error { return s.verify(ctx, user, code) }); err != nil {
+		if !errors.Is(err, apperrors.ErrInvalidTwoFactorCode) {
+			return 0, err
+		}
+		allowed, incErr := s.repository.IncrementChallengeAttempts(ctx, challenge.ID, maxChallengeAttempts)
+		if incErr != nil {
+			return 0, incErr
+		}
+		if !allowed {
+			return 0, apperrors.ErrInvalidLoginChallenge
+		}
+		return 0, err
+	}
+	marked, err := s.repository.MarkChallengeUsed(ctx, challenge.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !marked {
+		return 0, apperrors.ErrInvalidLoginChallenge
+	}
+	return user.ID, nil
+}
+
+// VerifyFreshCode подтверждение операции текущим кодом из приложения. Коды восстановления не принимаются.
+// Пользователям без подключённого второго фактора код не нужен.
+func (s *TwoFactorService) VerifyFreshCode(ctx context.Context, userID uint, code string) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.VerifyFreshCode")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return nil
+	}
+	if code == "" {
+		return apperrors.ErrTwoFactorRequired
+	}
+	return s.guarded(ctx, userID, func() error { return s.checkTOTP(ctx, user, code) })
+}
+
+// guarded проверяет код с учётом счётчика неверных кодов пользователя. Счётчик общий
+// для входа и подтверждения операций, поэтому перебор не продолжить через другой вызов.
+func (s *TwoFactorService) guarded(ctx context.Context, userID uint, check func() error) error {
+	if err := s.guard.CheckTwoFactor(ctx, userID); err != nil {
+		return err
+	}
+	err := check()
+	if errors.Is(err, apperrors.ErrInvalidTwoFactorCode) {
+		if regErr := s.guard.RegisterTwoFactorFailure(ctx, userID); regErr != nil {
+			return regErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return s.guard.RegisterTwoFactorSuccess(ctx, userID)
+}
+
+// verify принимает код из приложения или код восстановления
+func (s *TwoFactorService) verify(ctx context.Context, user entities.User, code string) error {
+	if len(code) == totp.Digits {
+		return s.checkTOTP(ctx, user, code)
+	}
+	return s.checkRecoveryCode(ctx, user.ID, code)
+}
+
+func (s *TwoFactorService) checkTOTP(ctx context.Context, user entities.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return apperrors.ErrInvalidTwoFactorCode
+	}
+	advanced, err := s.repository.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return apperrors.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *TwoFactorService) checkRecoveryCode(ctx context.Context, userID uint, code string) error {
+	codeHash := hashToken(normalizeRecoveryCode(code))
+	codes, err := s.repository.FindUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, recoveryCode := range codes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode.CodeHash), []byte(codeHash)) != 1 {
+			continue
+		}
+		marked, err := s.repository.MarkRecoveryCodeUsed(ctx, recoveryCode.ID)
+		if err != nil {
+			return err
+		}
+		if marked {
+			return nil
+		}
+	}
+	return apperrors.ErrInvalidTwoFactorCode
+}
+
+// generateRecoveryCodes коды вида XXXXX-XXXXX. При проверке регистр и дефис не важны.
+func generateRecoveryCodes(userID uint) ([]string, []entities.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]entities.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		code := secret[:5] + "-" + secret[5:10]
+		codes = append(codes, code)
+		records = append(records, entities.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code))})
+	}
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"github.com/keyjin88/go-loyalty-system/internal/app/totp"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newOpenGuard счётчик неверных кодов, который никогда не блокирует
+func newOpenGuard(ctrl *gomock.Controller) *LoginGuard {
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	throttles.EXPECT().FindLoginThrottles(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	throttles.EXPECT().IncrementLoginFailures(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+	throttles.EXPECT().ResetLoginFailures(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	policy := LoginPolicy{FreeAttempts: 100, MaxFailures: 100, BaseDelay: time.Second, Lockout: time.Minute}
+	return NewLoginGuard(throttles, policy, policy)
+}
+
+func TestTwoFactorService_Confirm(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	repository := mocks.NewMockTwoFactorRepository(ctrl)
+	service := NewTwoFactorService(users, repository, newOpenGuard(ctrl), "Gophermart", 5*time.Minute)
+	secret, _ := totp.GenerateSecret()
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, UserName: "alice", TOTPSecret: secret}
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil).AnyTimes()
+
+	t.Run("Wrong code", func(t *testing.T) {
+		if _, err := service.Confirm(context.Background(), 7, "000000x"); !errors.Is(err, apperrors.ErrInvalidTwoFactorCode) {
+			t.Errorf("expected invalid code, got %v", err)
+		}
+	})
+
+	t.Run("Replayed code", func(t *testing.T) {
+		repository.EXPECT().AdvanceTOTPStep(gomock.Any(), uint(7), gomock.Any()).Return(false, nil)
+		if _, err := service.Confirm(context.Background(), 7, code); !errors.Is(err, apperrors.ErrInvalidTwoFactorCode) {
+			t.Errorf("expected invalid code, got %v", err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		repository.EXPECT().AdvanceTOTPStep(gomock.Any(), uint(7), gomock.Any()).Return(true, nil)
+		var stored []entities.RecoveryCode
+		repository.EXPECT().ReplaceRecoveryCodes(gomock.Any(), uint(7), gomock.Any()).DoAndReturn(func(_ context.Context, _ uint, codes []entities.RecoveryCode) error {
+			stored = codes
+			return nil
+		})
+		repository.EXPECT().UpdateTOTP(gomock.Any(), uint(7), secret, true).Return(nil)
+		codes, err := service.Confirm(context.Background(), 7, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(codes) != recoveryCodeCount || len(stored) != recoveryCodeCount {
+			t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+		}
+		if stored[0].CodeHash != hashToken(normalizeRecoveryCode(codes[0])) {
+			t.Error("only the hash of a recovery code must be stored")
+		}
+	})
+}
+
+func TestTwoFactorService_CompleteChallenge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	repository := mocks.NewMockTwoFactorRepository(ctrl)
+	service := NewTwoFactorService(users, repository, newOpenGuard(ctrl), "Gophermart", 5*time.Minute)
+	challenge := entities.LoginChallenge{
+		Entity:    entities.Entity{Model: gorm.Model{ID: 3}},
+		UserID:    7,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true}
+	recoveryCode := entities.RecoveryCode{Entity: entities.Entity{Model: gorm.Model{ID: 11}}, CodeHash: hashToken("ABCDEFGHIJ")}
+
+	t.Run("Recovery code", func(t *testing.T) {
+		repository.EXPECT().FindChallengeByHash(gomock.Any(), hashToken("challenge")).Return(challenge, nil)
+		users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil)
+		repository.EXPECT().FindUnusedRecoveryCodes(gomock.Any(), uint(7)).Return([]entities.RecoveryCode{recoveryCode}, nil)
+		repository.EXPECT().MarkRecoveryCodeUsed(gomock.Any(), uint(11)).Return(true, nil)
+		repository.EXPECT().MarkChallengeUsed(gomock.Any(), uint(3)).Return(true, nil)
+		userID, err := service.CompleteChallenge(context.Background(), "challenge", "abcde-fghij")
+		if err != nil || userID != 7 {
+			t.Errorf("unexpected result: %d, %v", userID, err)
+		}
+	})
+
+	t.Run("Attempts exhausted", func(t *testing.T) {
+		repository.EXPECT().FindChallengeByHash(gomock.Any(), hashToken("challenge")).Return(challenge, nil)
+		users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil)
+		repository.EXPECT().FindUnusedRecoveryCodes(gomock.Any(), uint(7)).Return(nil, nil)
+		repository.EXPECT().IncrementChallengeAttempts(gomock.Any(), uint(3), maxChallengeAttempts).Return(false, nil)
+		if _, err := service.CompleteChallenge(context.Background(), "challenge", "wrong-code"); !errors.Is(err, apperrors.ErrInvalidLoginChallenge) {
+			t.Errorf("expected invalid challenge, got %v", err)
+		}
+	})
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		expired := challenge
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		repository.EXPECT().FindChallengeByHash(gomock.Any(), hashToken("challenge")).Return(expired, nil)
+		if _, err := service.CompleteChallenge(context.Background(), "challenge", "123456"); !errors.Is(err, apperrors.ErrInvalidLoginChallenge) {
+			t.Errorf("expected invalid challenge, got %v", err)
+		}
+	})
+}
+
+func TestTwoFactorService_VerifyFreshCodeThrottled(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	policy := LoginPolicy{FreeAttempts: 3, MaxFailures: 5, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	service := NewTwoFactorService(users, mocks.NewMockTwoFactorRepository(ctrl), NewLoginGuard(throttles, policy, policy),
+		"Gophermart", 5*time.Minute)
+	secret, _ := totp.GenerateSecret()
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, TOTPEnabled: true, TOTPSecret: secret}
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil).AnyTimes()
+
+	t.Run("Failure locks out", func(t *testing.T) {
+		throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"2fa:7"}).Return(nil, nil)
+		throttles.EXPECT().IncrementLoginFailures(gomock.Any(), "2fa:7", gomock.Any(), gomock.Any()).Return(5, nil)
+		throttles.EXPECT().BlockLogin(gomock.Any(), "2fa:7", gomock.Any()).Return(nil)
+		if err := service.VerifyFreshCode(context.Background(), 7, "00000x"); !errors.Is(err, apperrors.ErrInvalidTwoFactorCode) {
+			t.Errorf("expected invalid code, got %v", err)
+		}
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		blockedUntil := time.Now().Add(time.Minute)
+		throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"2fa:7"}).
+			Return([]entities.LoginThrottle{{ThrottleKey: "2fa:7", BlockedUntil: &blockedUntil}}, nil)
+		code, _ := totp.Code(secret, totp.Step(time.Now()))
+		err := service.VerifyFreshCode(context.Background(), 7, code)
+		var appErr *apperrors.Error
+		if !errors.As(err, &appErr) || appErr.Code != apperrors.CodeTooManyAttempts || appErr.RetryAfter <= 0 {
+			t.Errorf("expected too many attempts, got %v", err)
+		}
+	})
+}
+
+func TestWithdrawService_SaveWithdrawRequiresTOTPAboveThreshold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	twoFactor := NewTwoFactorService(users, mocks.NewMockTwoFactorRepository(ctrl), newOpenGuard(ctrl), "Gophermart", 5*time.Minute)
+	service := NewWithdrawService(mocks.NewMockWithdrawRepository(ctrl), users, &sync.Mutex{}, twoFactor,
+		NewEventBus(mocks.NewMockEventRepository(ctrl)), 1000)
+
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(entities.User{TOTPEnabled: true}, nil)
+	err := service.SaveWithdraw(context.Background(), dto.WithdrawDTO{OrderNumber: "2377225624", Sum: 1500, UserID: 7})
+	if !errors.Is(err, apperrors.ErrTwoFactorRequired) {
+		t.Errorf("expected two-factor required, got %v", err)
+	}
+}
+
+func TestWithdrawService_SaveWithdrawWithoutTOTPEnrollment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	withdrawals := mocks.NewMockWithdrawRepository(ctrl)
+	events := mocks.NewMockEventRepository(ctrl)
+	twoFactor := NewTwoFactorService(users, mocks.NewMockTwoFactorRepository(ctrl), newOpenGuard(ctrl), "Gophermart", 5*time.Minute)
+	service := NewWithdrawService(withdrawals, users, &sync.Mutex{}, twoFactor, NewEventBus(events), 1000)
+
+	// пользователь без второго фактора списывает сумму выше порога без кода
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, Balance: 2000}
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil).Times(2)
+	withdrawals.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil)
+	users.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	events.EXPECT().SaveEvent(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	err := service.SaveWithdraw(context.Background(), dto.WithdrawDTO{OrderNumber: "2377225624", Sum: 1500, UserID: 7})
+	if err != nil {
+		t.Errorf("expected withdrawal without 2FA to succeed, got %v", err)
+	}
+}
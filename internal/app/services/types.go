@@ -1,26 +1,141 @@
 package services
 
 import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"time"
 )
 
 //go:generate mockgen -destination=mocks/order_repository.go -package=mocks . OrderRepository
 type OrderRepository interface {
-	Save(order *entities.Order) error
-	GetOrderByNumber(number string) (entities.Order, error)
-	GetAllOrders(userID uint) ([]entities.Order, error)
+	Save(ctx context.Context, order *entities.Order) error
+	GetOrderByNumber(ctx context.Context, number string) (entities.Order, error)
+	GetAllOrders(ctx context.Context, userID uint) ([]entities.Order, error)
+	GetOrdersPage(ctx context.Context, filter dto.PageFilter) ([]entities.Order, error)
+	GetOrderAttempts(ctx context.Context, orderID uint) ([]entities.OrderAttempt, error)
+	GetOrdersByNumbers(ctx context.Context, numbers []string) ([]entities.Order, error)
+	SaveAll(ctx context.Context, orders []entities.Order) error
+	GetUserOrderStats(ctx context.Context, userID uint) (dto.Stats, error)
 }
 
 //go:generate mockgen -destination=mocks/user_repository.go -package=mocks . UserRepository
 type UserRepository interface {
-	Save(user *entities.User) error
-	Update(user *entities.User) error
-	FindUserByID(userID uint) (entities.User, error)
-	FindUserByUserName(userName string) (entities.User, error)
+	Save(ctx context.Context, user *entities.User) error
+	Update(ctx context.Context, user *entities.User) error
+	FindUserByID(ctx context.Context, userID uint) (entities.User, error)
+	FindUserByUserName(ctx context.Context, userName string) (entities.User, error)
+	SearchUsers(ctx context.Context, query string, after *dto.PageCursor, limit int) ([]entities.User, error)
+	SoftDeleteUser(ctx context.Context, userID uint, userName string) (bool, error)
 }
 
 //go:generate mockgen -destination=mocks/withdraw_repository.go -package=mocks . WithdrawRepository
 type WithdrawRepository interface {
-	Save(withdraw *entities.Withdraw) error
-	GetWithdrawals(userID uint) ([]entities.Withdraw, error)
+	Save(ctx context.Context, withdraw *entities.Withdraw) error
+	GetWithdrawals(ctx context.Context, userID uint) ([]entities.Withdraw, error)
+	GetWithdrawalsPage(ctx context.Context, filter dto.PageFilter) ([]entities.Withdraw, error)
+	GetUserWithdrawStats(ctx context.Context, userID uint) (dto.Stats, error)
+}
+
+//go:generate mockgen -destination=mocks/token_repository.go -package=mocks . TokenRepository
+type TokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token *entities.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUserTokens(ctx context.Context, userID uint) error
+	RevokeJTI(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	SaveSession(ctx context.Context, session *entities.Session) error
+	FindSessionByFamily(ctx context.Context, familyID string) (entities.Session, error)
+	FindUserSession(ctx context.Context, userID uint, id uint) (entities.Session, error)
+	FindActiveSessions(ctx context.Context, userID uint, now time.Time) ([]entities.Session, error)
+	TouchSession(ctx context.Context, familyID string, lastSeenAt time.Time) error
+	ExtendSession(ctx context.Context, familyID string, ip string, lastSeenAt time.Time, expiresAt time.Time) error
+}
+
+//go:generate mockgen -destination=mocks/oidc_repository.go -package=mocks . OIDCRepository
+type OIDCRepository interface {
+	SaveAuthRequest(ctx context.Context, request *entities.OIDCAuthRequest) error
+	FindAuthRequestByStateHash(ctx context.Context, stateHash string) (entities.OIDCAuthRequest, error)
+	MarkAuthRequestUsed(ctx context.Context, id uint) (bool, error)
+	FindIdentity(ctx context.Context, issuer string, subject string) (entities.ExternalIdentity, error)
+	SaveIdentity(ctx context.Context, identity *entities.ExternalIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *entities.User, identity *entities.ExternalIdentity) error
+}
+
+//go:generate mockgen -destination=mocks/audit_repository.go -package=mocks . AuditRepository
+type AuditRepository interface {
+	Save(ctx context.Context, event *entities.AuditEvent) error
+	FindEvents(ctx context.Context, filter dto.AuditEventFilter) ([]entities.AuditEvent, error)
+}
+
+//go:generate mockgen -destination=mocks/event_repository.go -package=mocks . EventRepository
+type EventRepository interface {
+	SaveEvent(ctx context.Context, event *entities.UserEvent) error
+	FindEventsAfter(ctx context.Context, userID uint, afterID uint, limit int) ([]entities.UserEvent, error)
+}
+
+//go:generate mockgen -destination=mocks/login_throttle_repository.go -package=mocks . LoginThrottleRepository
+type LoginThrottleRepository interface {
+	FindLoginThrottles(ctx context.Context, keys []string) ([]entities.LoginThrottle, error)
+	IncrementLoginFailures(ctx context.Context, key string, now time.Time, resetBefore time.Time) (int, error)
+	BlockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, keys []string) error
+}
+
+//go:generate mockgen -destination=mocks/password_reset_repository.go -package=mocks . PasswordResetRepository
+type PasswordResetRepository interface {
+	SaveResetToken(ctx context.Context, token *entities.PasswordResetToken) error
+	FindResetTokenByHash(ctx context.Context, tokenHash string) (entities.PasswordResetToken, error)
+	MarkResetTokenUsed(ctx context.Context, id uint) (bool, error)
+}
+
+//go:generate mockgen -destination=mocks/notifier.go -package=mocks . Notifier
+type Notifier interface {
+	Send(ctx context.Context, notification dto.Notification) error
+}
+
+//go:generate mockgen -destination=mocks/two_factor_repository.go -package=mocks . TwoFactorRepository
+type TwoFactorRepository interface {
+	UpdateTOTP(ctx context.Context, userID uint, secret string, enabled bool) error
+	AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []entities.RecoveryCode) error
+	FindUnusedRecoveryCodes(ctx context.Context, userID uint) ([]entities.RecoveryCode, error)
+	MarkRecoveryCodeUsed(ctx context.Context, id uint) (bool, error)
+	SaveChallenge(ctx context.Context, challenge *entities.LoginChallenge) error
+	FindChallengeByHash(ctx context.Context, tokenHash string) (entities.LoginChallenge, error)
+	IncrementChallengeAttempts(ctx context.Context, id uint, maxAttempts int) (bool, error)
+	MarkChallengeUsed(ctx context.Context, id uint) (bool, error)
+}
+
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+//go:generate mockgen -destination=mocks/partner_repository.go -package=mocks . PartnerRepository
+type PartnerRepository interface {
+	SavePartner(ctx context.Context, partner *entities.Partner) error
+	FindPartnerByID(ctx context.Context, partnerID uint) (entities.Partner, error)
+	SaveAPIKey(ctx context.Context, key *entities.APIKey) error
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (entities.APIKey, error)
+	RevokeAPIKey(ctx context.Context, partnerID uint, keyID uint) (bool, error)
+	TouchAPIKey(ctx context.Context, keyID uint, usedAt time.Time) error
+	LinkPartnerUser(ctx context.Context, link *entities.PartnerUser) error
+	FindPartnerUser(ctx context.Context, partnerID uint, externalID string) (entities.PartnerUser, error)
+}
+
+//go:generate mockgen -destination=mocks/webhook_repository.go -package=mocks . WebhookRepository
+type WebhookRepository interface {
+	SaveSubscription(ctx context.Context, subscription *entities.WebhookSubscription) error
+	FindSubscriptions(ctx context.Context, partnerID uint) ([]entities.WebhookSubscription, error)
+	FindSubscriptionByID(ctx context.Context, id uint) (entities.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, partnerID uint, id uint) (bool, error)
+	FindSubscriptionTargets(ctx context.Context, userID uint) ([]dto.WebhookTarget, error)
+	SaveDeliveries(ctx context.Context, deliveries []entities.WebhookDelivery) error
+	FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entities.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id uint, dueAt time.Time, leaseUntil time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilter) ([]entities.WebhookDelivery, error)
 }
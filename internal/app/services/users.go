@@ -1,34 +1,53 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"log"
 )
 
+// dummyPasswordHash bcrypt-хэш, с которым сравнивается пароль несуществующего пользователя,
+// чтобы время ответа не выдавало, есть ли такой логин
+const dummyPasswordHash = "$2a$10$din3fsKVCbIPFLwUZUBcSusi1SHD30pMyjnHn0fktk1aMHUhMChmi"
+
 type UserService struct {
 	userRepository UserRepository
+	loginGuard     *LoginGuard
+	policy         CredentialsPolicy
 }
 
-func NewUserService(userRepository UserRepository) *UserService {
-	return &UserService{userRepository: userRepository}
+func NewUserService(userRepository UserRepository, loginGuard *LoginGuard, policy CredentialsPolicy) *UserService {
+	return &UserService{userRepository: userRepository, loginGuard: loginGuard, policy: policy}
 }
 
-func (s *UserService) SaveUser(userDTO dto.UserDTO) (entities.User, error) {
+func (s *UserService) SaveUser(ctx context.Context, userDTO dto.UserDTO) (entities.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SaveUser")
+	defer span.End()
+	violations := append(s.policy.ValidateLogin(userDTO.UserName), s.policy.ValidatePassword(userDTO.Password, userDTO.UserName)...)
+	violations = append(violations, s.policy.ValidateEmail(userDTO.Email)...)
+	if len(violations) > 0 {
+		return entities.User{}, apperrors.ValidationFailed(violations)
+	}
 	user := entities.User{
 		UserName: userDTO.UserName,
 		Password: hashPassword(userDTO.Password),
+		Email:    userDTO.Email,
 	}
-	err := s.userRepository.Save(&user)
+	err := s.userRepository.Save(ctx, &user)
 	if err != nil {
 		pgErr, ok := err.(*pgconn.PgError)
 		if ok && pgErr.Code == pgerrcode.UniqueViolation {
-			return entities.User{}, errors.New("user already exists")
+			return entities.User{}, apperrors.ErrUserAlreadyExists
 		} else {
 			return entities.User{}, err
 		}
@@ -36,20 +55,128 @@ func (s *UserService) SaveUser(userDTO dto.UserDTO) (entities.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetUserByUserName(userDTO dto.UserDTO) (entities.User, error) {
-	user, err := s.userRepository.FindUserByUserName(userDTO.UserName)
+// BootstrapAdmin выдаёт роль администратора пользователю login при запуске. Отсутствующий
+// пользователь создаётся с паролем password. Без пароля отсутствие пользователя считается ошибкой
+// конфигурации: иначе роль получил бы тот, кто первым зарегистрирует этот логин.
+func (s *UserService) BootstrapAdmin(ctx context.Context, login string, password string) error {
+	ctx, span := tracing.Start(ctx, "UserService.BootstrapAdmin")
+	defer span.End()
+	user, err := s.userRepository.FindUserByUserName(ctx, login)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if password == "" {
+			return fmt.Errorf("admin %q is not registered and no admin password is set", login)
+		}
+		violations := append(s.policy.ValidateLogin(login), s.policy.ValidatePassword(password, login)...)
+		if len(violations) > 0 {
+			return apperrors.ValidationFailed(violations)
+		}
+		return s.userRepository.Save(ctx, &entities.User{
+			UserName: login,
+			Password: hashPassword(password),
+			Role:     entities.RoleAdmin,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role == entities.RoleAdmin {
+		return nil
+	}
+	user.Role = entities.RoleAdmin
+	return s.userRepository.Update(ctx, &user)
+}
+
+// GetUserByUserName проверяет логин и пароль. Неизвестный логин и неверный пароль
+// неразличимы для клиента, а частые неудачи временно блокируют вход.
+func (s *UserService) GetUserByUserName(ctx context.Context, userDTO dto.UserDTO) (entities.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByUserName")
+	defer span.End()
+	if err := s.loginGuard.Check(ctx, userDTO.UserName, userDTO.IP); err != nil {
+		return entities.User{}, err
+	}
+	user, err := s.userRepository.FindUserByUserName(ctx, userDTO.UserName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = comparePassword(dummyPasswordHash, userDTO.Password)
+		return s.loginFailed(ctx, userDTO)
+	}
 	if err != nil {
 		return entities.User{}, err
 	}
 	passwordError := comparePassword(user.Password, userDTO.Password)
+	if errors.Is(passwordError, bcrypt.ErrMismatchedHashAndPassword) {
+		return s.loginFailed(ctx, userDTO)
+	}
 	if passwordError != nil {
 		return entities.User{}, passwordError
 	}
+	if err := s.loginGuard.RegisterSuccess(ctx, userDTO.UserName); err != nil {
+		return entities.User{}, err
+	}
 	return user, nil
 }
 
-func (s *UserService) GetUserBalance(userID uint) (models.BalanceResponse, error) {
-	user, err := s.userRepository.FindUserByID(userID)
+func (s *UserService) loginFailed(ctx context.Context, userDTO dto.UserDTO) (entities.User, error) {
+	if err := s.loginGuard.RegisterFailure(ctx, userDTO.UserName, userDTO.IP); err != nil {
+		return entities.User{}, err
+	}
+	return entities.User{}, apperrors.ErrInvalidCredentials
+}
+
+// ChangePassword меняет пароль после проверки старого. Неверный старый пароль
+// учитывается так же, как неудачная попытка входа.
+func (s *UserService) ChangePassword(ctx context.Context, passwordDTO dto.PasswordChangeDTO) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, passwordDTO.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.loginGuard.Check(ctx, user.UserName, passwordDTO.IP); err != nil {
+		return err
+	}
+	passwordError := comparePassword(user.Password, passwordDTO.OldPassword)
+	if errors.Is(passwordError, bcrypt.ErrMismatchedHashAndPassword) {
+		if err := s.loginGuard.RegisterFailure(ctx, user.UserName, passwordDTO.IP); err != nil {
+			return err
+		}
+		return apperrors.New(apperrors.CodeInvalidCredentials, "old password is incorrect")
+	}
+	if passwordError != nil {
+		return passwordError
+	}
+	violations := s.policy.ValidatePassword(passwordDTO.NewPassword, user.UserName)
+	if passwordDTO.NewPassword == passwordDTO.OldPassword {
+		violations = append(violations, "new password must differ from the old one")
+	}
+	if len(violations) > 0 {
+		return apperrors.ValidationFailed(violations)
+	}
+	user.Password = hashPassword(passwordDTO.NewPassword)
+	return s.userRepository.Update(ctx, &user)
+}
+
+// UpdateEmail меняет адрес, на который приходят сообщения для восстановления пароля
+func (s *UserService) UpdateEmail(ctx context.Context, userID uint, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateEmail")
+	defer span.End()
+	if email == "" {
+		return apperrors.ValidationFailed([]string{"email must not be empty"})
+	}
+	if violations := s.policy.ValidateEmail(email); len(violations) > 0 {
+		return apperrors.ValidationFailed(violations)
+	}
+	user, err := s.userRepository.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.Email = email
+	return s.userRepository.Update(ctx, &user)
+}
+
+func (s *UserService) GetUserBalance(ctx context.Context, userID uint) (models.BalanceResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserBalance")
+	defer span.End()
+	user, err := s.userRepository.FindUserByID(ctx, userID)
 	if err != nil {
 		return models.BalanceResponse{}, err
 	}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var testCredentialsPolicy = CredentialsPolicy{
+	LoginMinLength:     3,
+	LoginMaxLength:     64,
+	LoginPattern:       regexp.MustCompile(`^[A-Za-z0-9._@-]+$`),
+	PasswordMinLength:  8,
+	PasswordMinClasses: 2,
+}
+
+func TestUserService_SaveUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	service := NewUserService(users, nil, testCredentialsPolicy)
+
+	_, err := service.SaveUser(context.Background(), dto.UserDTO{UserName: "", Password: "short"})
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.CodeInvalidRequest {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	expected := []string{
+		"login must be between 3 and 64 characters",
+		"password must be at least 8 characters",
+		"password must contain at least 2 of: lowercase letters, uppercase letters, digits, other characters",
+	}
+	if !reflect.DeepEqual(appErr.Violations, expected) {
+		t.Errorf("unexpected violations: %q", appErr.Violations)
+	}
+
+	// служебный логин удалённого пользователя занять нельзя
+	_, err = service.SaveUser(context.Background(), dto.UserDTO{UserName: "Deleted-User-7", Password: "correct horse 1"})
+	if !errors.As(err, &appErr) || !reflect.DeepEqual(appErr.Violations, []string{"login must not start with deleted-user-"}) {
+		t.Errorf("expected reserved login violation, got %v", err)
+	}
+
+	// как и логин, который получил бы пользователь при первом входе через OIDC
+	_, err = service.SaveUser(context.Background(), dto.UserDTO{UserName: "OIDC-1a2b3c4d5e6f7a8b", Password: "correct horse 1"})
+	if !errors.As(err, &appErr) || !reflect.DeepEqual(appErr.Violations, []string{"login must not start with oidc-"}) {
+		t.Errorf("expected reserved login violation, got %v", err)
+	}
+
+	users.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil)
+	if _, err := service.SaveUser(context.Background(), dto.UserDTO{UserName: "alice", Password: "correct horse 1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	policy := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	service := NewUserService(users, NewLoginGuard(throttles, policy, policy), testCredentialsPolicy)
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, UserName: "alice", Password: dummyPasswordHash}
+	throttles.EXPECT().FindLoginThrottles(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil).AnyTimes()
+
+	t.Run("Wrong old password", func(t *testing.T) {
+		throttles.EXPECT().IncrementLoginFailures(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+		err := service.ChangePassword(context.Background(), dto.PasswordChangeDTO{UserID: 7, OldPassword: "wrong", NewPassword: "New password 1", IP: "10.0.0.1"})
+		if !errors.Is(err, apperrors.ErrInvalidCredentials) {
+			t.Errorf("expected invalid credentials, got %v", err)
+		}
+	})
+
+	t.Run("Weak new password", func(t *testing.T) {
+		err := service.ChangePassword(context.Background(), dto.PasswordChangeDTO{UserID: 7, OldPassword: "dummy-password-for-timing", NewPassword: "alice"})
+		var appErr *apperrors.Error
+		if !errors.As(err, &appErr) || len(appErr.Violations) != 3 {
+			t.Errorf("expected three violations, got %v", err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		users.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, updated *entities.User) error {
+			if comparePassword(updated.Password, "New password 1") != nil {
+				t.Error("password hash was not updated")
+			}
+			return nil
+		})
+		err := service.ChangePassword(context.Background(), dto.PasswordChangeDTO{UserID: 7, OldPassword: "dummy-password-for-timing", NewPassword: "New password 1"})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestUserService_GetUserByUserName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	policy := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	service := NewUserService(users, NewLoginGuard(throttles, policy, policy), testCredentialsPolicy)
+	user := entities.User{UserName: "alice", Password: dummyPasswordHash}
+
+	tests := []struct {
+		name        string
+		userDTO     dto.UserDTO
+		findReturn  entities.User
+		findError   error
+		failure     bool
+		expectedErr error
+	}{
+		{
+			name:       "Success",
+			userDTO:    dto.UserDTO{UserName: "alice", Password: "dummy-password-for-timing", IP: "10.0.0.1"},
+			findReturn: user,
+		},
+		{
+			name:        "Wrong password",
+			userDTO:     dto.UserDTO{UserName: "alice", Password: "wrong", IP: "10.0.0.1"},
+			findReturn:  user,
+			failure:     true,
+			expectedErr: apperrors.ErrInvalidCredentials,
+		},
+		{
+			name:        "Unknown user",
+			userDTO:     dto.UserDTO{UserName: "alice", Password: "wrong", IP: "10.0.0.1"},
+			findError:   gorm.ErrRecordNotFound,
+			failure:     true,
+			expectedErr: apperrors.ErrInvalidCredentials,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"login:alice", "ip:10.0.0.1"}).Return(nil, nil)
+			users.EXPECT().FindUserByUserName(gomock.Any(), "alice").Return(tt.findReturn, tt.findError)
+			if tt.failure {
+				throttles.EXPECT().IncrementLoginFailures(gomock.Any(), "login:alice", gomock.Any(), gomock.Any()).Return(1, nil)
+				throttles.EXPECT().IncrementLoginFailures(gomock.Any(), "ip:10.0.0.1", gomock.Any(), gomock.Any()).Return(1, nil)
+			} else {
+				throttles.EXPECT().ResetLoginFailures(gomock.Any(), []string{"login:alice"}).Return(nil)
+			}
+			_, err := service.GetUserByUserName(context.Background(), tt.userDTO)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestUserService_BootstrapAdmin(t *testing.T) {
+	existing := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, UserName: "root", Role: entities.RoleUser}
+	tests := []struct {
+		name      string
+		password  string
+		found     entities.User
+		findError error
+		wantSave  bool
+		wantRole  bool
+		wantErr   bool
+	}{
+		{name: "Existing user promoted", found: existing, wantRole: true},
+		{name: "Existing admin untouched", found: entities.User{UserName: "root", Role: entities.RoleAdmin}},
+		{name: "Missing user created", password: "correct horse 1", findError: gorm.ErrRecordNotFound, wantSave: true},
+		{name: "Missing user without password", findError: gorm.ErrRecordNotFound, wantErr: true},
+		{name: "Missing user with weak password", password: "root", findError: gorm.ErrRecordNotFound, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			users := mocks.NewMockUserRepository(ctrl)
+			service := NewUserService(users, nil, testCredentialsPolicy)
+			users.EXPECT().FindUserByUserName(gomock.Any(), "root").Return(tt.found, tt.findError)
+			if tt.wantSave {
+				users.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user *entities.User) error {
+					if user.UserName != "root" || user.Role != entities.RoleAdmin || comparePassword(user.Password, tt.password) != nil {
+						t.Errorf("unexpected admin %+v", user)
+					}
+					return nil
+				})
+			}
+			if tt.wantRole {
+				users.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user *entities.User) error {
+					if user.ID != 7 || user.Role != entities.RoleAdmin {
+						t.Errorf("unexpected update %+v", user)
+					}
+					return nil
+				})
+			}
+			err := service.BootstrapAdmin(context.Background(), "root", tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
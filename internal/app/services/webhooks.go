@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"gorm.io/gorm"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Заголовки запроса webhook
+const (
+	// WebhookSignatureHeader подпись тела: t=<unix-время>,v1=<hex HMAC-SHA256 от "<t>.<тело>">
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	// WebhookDeliveryHeader идентификатор доставки, одинаковый для всех попыток, чтобы партнёр отбрасывал повторы
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+)
+
+const (
+	// minWebhookSecretLength минимальная длина секрета, заданного партнёром
+	minWebhookSecretLength = 16
+	// webhookBatchSize сколько доставок обрабатывается за один проход очереди
+	webhookBatchSize = 100
+	// maxWebhookErrorLength сколько символов ошибки последней попытки сохраняется в журнале
+	maxWebhookErrorLength = 500
+)
+
+var knownWebhookEvents = map[string]bool{
+	entities.WebhookOrderProcessed:    true,
+	entities.WebhookOrderInvalid:      true,
+	entities.WebhookWithdrawalCreated: true,
+}
+
+// orderWebhookEvents окончательные статусы заказа, о которых сообщается партнёру
+var orderWebhookEvents = map[string]string{
+	"PROCESSED": entities.WebhookOrderProcessed,
+	"INVALID":   entities.WebhookOrderInvalid,
+}
+
+// WebhookPolicy настройки повторных попыток доставки
+type WebhookPolicy struct {
+	// MaxAttempts после стольких неудачных попыток доставка считается проваленной
+	MaxAttempts int
+	// RetryBase пауза после первой неудачи, дальше она удваивается до RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Timeout сколько ждать ответа партнёра
+	Timeout time.Duration
+	// AllowPrivateNetworks разрешает адреса loopback и внутренних сетей, только для локальной разработки
+	AllowPrivateNetworks bool
+}
+
+// errBlockedWebhookAddress адрес webhook указывает во внутреннюю сеть
+var errBlockedWebhookAddress = errors.New("webhook address is not allowed")
+
+// WebhookService подписки партнёров на события их клиентов и доставка этих событий
+type WebhookService struct {
+	repository WebhookRepository
+	client     *http.Client
+	policy     WebhookPolicy
+	// lookupIP разрешает имя хоста подписки, в тестах подменяется
+	lookupIP func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+func NewWebhookService(repository WebhookRepository, policy WebhookPolicy) *WebhookService {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Через прокси проверка адреса при соединении проверяла бы сам прокси, а не партнёра
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Адрес проверяется уже после разрешения имени: DNS партнёра может вернуть при доставке
+		// другой адрес, чем при создании подписки
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || (!policy.AllowPrivateNetworks && blockedWebhookIP(ip)) {
+				return fmt.Errorf("%w: %s", errBlockedWebhookAddress, host)
+			}
+			return nil
+		},
+	}).DialContext
+	return &WebhookService{
+		repository: repository,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   policy.Timeout,
+			// Перенаправление считается неудачной доставкой: адрес подписки должен быть точным
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		policy:   policy,
+		lookupIP: net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+// blockedWebhookIP адреса, на которые нельзя отправлять webhook: запрос к ним из сервиса
+// открыл бы партнёру доступ к внутренней сети
+func blockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// checkWebhookHost проверяет, что все адреса хоста подписки внешние
+func (s *WebhookService) checkWebhookHost(ctx context.Context, host string) error {
+	if s.policy.AllowPrivateNetworks {
+		return nil
+	}
+	addresses, err := s.lookupIP(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if blockedWebhookIP(address.IP) {
+			return errBlockedWebhookAddress
+		}
+	}
+	return nil
+}
+
+// CreateSubscription подписывает адрес партнёра на события. Если секрет не задан, он генерируется
+// и возвращается только в ответе на создание.
+func (s *WebhookService) CreateSubscription(
+	ctx context.Context,
+	subscriptionDTO dto.WebhookSubscriptionDTO,
+) (models.WebhookSubscriptionResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+	var violations []string
+	target, err := url.Parse(subscriptionDTO.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		violations = append(violations, "url must be an absolute http or https URL")
+	} else if err := s.checkWebhookHost(ctx, target.Hostname()); errors.Is(err, errBlockedWebhookAddress) {
+		violations = append(violations, "url must not point to a loopback, private or link-local address")
+	} else if err != nil {
+		violations = append(violations, "url host cannot be resolved")
+	}
+	if len(subscriptionDTO.EventTypes) == 0 {
+		violations = append(violations, "at least one event type is required")
+	}
+	for _, eventType := range subscriptionDTO.EventTypes {
+		if !knownWebhookEvents[eventType] {
+			violations = append(violations, "unknown event type "+eventType)
+		}
+	}
+	secret := subscriptionDTO.Secret
+	if secret != "" && len(secret) < minWebhookSecretLength {
+		violations = append(violations, fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLength))
+	}
+	if len(violations) > 0 {
+		return models.WebhookSubscriptionResponse{}, apperrors.ValidationFailed(violations)
+	}
+	if secret == "" {
+		if secret, err = randomToken(32); err != nil {
+			return models.WebhookSubscriptionResponse{}, err
+		}
+	}
+	subscription := entities.WebhookSubscription{
+		PartnerID:  subscriptionDTO.PartnerID,
+		URL:        subscriptionDTO.URL,
+		Secret:     secret,
+		EventTypes: strings.Join(subscriptionDTO.EventTypes, ","),
+	}
+	if err := s.repository.SaveSubscription(ctx, &subscription); err != nil {
+		return models.WebhookSubscriptionResponse{}, err
+	}
+	response := newWebhookSubscriptionResponse(subscription)
+	response.Secret = secret
+	return response, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, partnerID uint) ([]models.WebhookSubscriptionResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+	subscriptions, err := s.repository.FindSubscriptions(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.WebhookSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		response = append(response, newWebhookSubscriptionResponse(subscription))
+	}
+	return response, nil
+}
+
+// DeleteSubscription удаляет подписку. Ожидающие доставки по ней больше не отправляются.
+func (s *WebhookService) DeleteSubscription(ctx context.Context, partnerID uint, id uint) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+	deleted, err := s.repository.DeleteSubscription(ctx, partnerID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperrors.Newf(apperrors.CodeNotFound, "webhook subscription %d not found", id)
+	}
+	return nil
+}
+
+// GetDeliveries журнал доставок по подписке партнёра, от новых к старым
+func (s *WebhookService) GetDeliveries(ctx context.Context, query dto.WebhookDeliveryQuery) (models.WebhookDeliveryPage, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+	subscription, err := s.repository.FindSubscriptionByID(ctx, query.SubscriptionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && subscription.PartnerID != query.PartnerID) {
+		return models.WebhookDeliveryPage{}, apperrors.Newf(apperrors.CodeNotFound,
+			"webhook subscription %d not found", query.SubscriptionID)
+	}
+	if err != nil {
+		return models.WebhookDeliveryPage{}, err
+	}
+	before, err := decodeCursor(query.Cursor, true, query.SubscriptionID)
+	if err != nil {
+		return models.WebhookDeliveryPage{}, err
+	}
+	filter := dto.WebhookDeliveryFilter{WebhookDeliveryQuery: query, Before: before}
+	filter.Limit = query.Limit + 1
+	deliveries, err := s.repository.FindDeliveries(ctx, filter)
+	if err != nil {
+		return models.WebhookDeliveryPage{}, err
+	}
+	var page models.WebhookDeliveryPage
+	if len(deliveries) > query.Limit {
+		deliveries = deliveries[:query.Limit]
+		last := deliveries[len(deliveries)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID, true, query.SubscriptionID)
+	}
+	page.Items = make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		item := models.WebhookDeliveryResponse{
+			ID:             delivery.ID,
+			EventType:      delivery.EventType,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			ResponseStatus: delivery.ResponseStatus,
+			Error:          delivery.Error,
+			CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+		}
+		if delivery.LastAttemptAt != nil {
+			item.LastAttemptAt = delivery.LastAttemptAt.Format(time.RFC3339)
+		}
+		if delivery.Status == entities.DeliveryPending {
+			item.NextAttemptAt = delivery.NextAttemptAt.Format(time.RFC3339)
+		}
+		if delivery.DeliveredAt != nil {
+			item.DeliveredAt = delivery.DeliveredAt.Format(time.RFC3339)
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// HandleEvent ставит в очередь доставки события пользователя для партнёров, подписанных на него.
+// Вызывается шиной событий для каждого сохранённого события.
+func (s *WebhookService) HandleEvent(ctx context.Context, event entities.UserEvent) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.HandleEvent")
+	defer span.End()
+	eventType, data, err := webhookEventData(event)
+	if err != nil || eventType == "" {
+		return err
+	}
+	targets, err := s.repository.FindSubscriptionTargets(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	var deliveries []entities.WebhookDelivery
+	for _, target := range targets {
+		if !subscribedTo(target.EventTypes, eventType) {
+			continue
+		}
+		payload, err := json.Marshal(models.WebhookPayload{
+			Type:      eventType,
+			CreatedAt: event.CreatedAt.Format(time.RFC3339),
+			Data:      data(target.ExternalID),
+		})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, entities.WebhookDelivery{
+			SubscriptionID: target.SubscriptionID,
+			PartnerID:      target.PartnerID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         entities.DeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.repository.SaveDeliveries(ctx, deliveries)
+}
+
+// DeliverDue отправляет доставки, время которых наступило, пока очередь не опустеет
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeliverDue")
+	defer span.End()
+	for {
+		now := time.Now()
+		deliveries, err := s.repository.FindDueDeliveries(ctx, now, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			// Пока идёт попытка, доставку не возьмёт другой экземпляр сервиса
+			claimed, err := s.repository.ClaimDelivery(ctx, delivery.ID, delivery.NextAttemptAt, now.Add(2*s.policy.Timeout))
+			if err != nil {
+				return err
+			}
+			if claimed {
+				s.deliver(ctx, delivery)
+			}
+		}
+		if len(deliveries) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, delivery entities.WebhookDelivery) {
+	subscription, err := s.repository.FindSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.FromContext(ctx).Errorf("failed to load webhook subscription %d: %v", delivery.SubscriptionID, err)
+		return
+	}
+	now := time.Now()
+	if err != nil {
+		delivery.Status = entities.DeliveryFailed
+		delivery.Error = "subscription deleted"
+	} else {
+		delivery.Attempts++
+		delivery.LastAttemptAt = &now
+		delivery.ResponseStatus, err = s.send(subscription, delivery, now)
+		switch {
+		case err == nil:
+			delivery.Status = entities.DeliveryDelivered
+			delivery.Error = ""
+			delivery.DeliveredAt = &now
+		case delivery.Attempts >= s.policy.MaxAttempts:
+			delivery.Status = entities.DeliveryFailed
+			delivery.Error = truncate(err.Error(), maxWebhookErrorLength)
+		default:
+			delivery.NextAttemptAt = now.Add(s.retryDelay(delivery.Attempts))
+			delivery.Error = truncate(err.Error(), maxWebhookErrorLength)
+		}
+	}
+	if err := s.repository.UpdateDelivery(ctx, &delivery); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send отправляет доставку партнёру и возвращает статус ответа. Успехом считается только ответ 2xx.
+func (s *WebhookService) send(
+	subscription entities.WebhookSubscription,
+	delivery entities.WebhookDelivery,
+	now time.Time,
+) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, subscription.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set(WebhookSignatureHeader, webhookSignature(subscription.Secret, now.Unix(), delivery.Payload))
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// Тело ответа не нужно, но его дочитывание позволяет переиспользовать соединение
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// retryDelay пауза перед следующей попыткой: удваивается с каждой неудачей, но не больше RetryMax
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.policy.RetryBase
+	for i := 1; i < attempts && delay < s.policy.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > s.policy.RetryMax {
+		delay = s.policy.RetryMax
+	}
+	return delay
+}
+
+// webhookEventData определяет, о каком событии партнёра нужно уведомить, и собирает данные для
+// конкретного клиента партнёра. Пустой тип означает, что событие партнёрам не отправляется.
+func webhookEventData(event entities.UserEvent) (string, func(externalID string) any, error) {
+	switch event.Type {
+	case entities.EventOrderStatusChanged:
+		var status models.OrderStatusEvent
+		if err := json.Unmarshal([]byte(event.Payload), &status); err != nil {
+			return "", nil, fmt.Errorf("failed to decode %s event %d: %w", event.Type, event.ID, err)
+		}
+		return orderWebhookEvents[status.Status], func(externalID string) any {
+			return models.WebhookOrderData{ExternalID: externalID, OrderStatusEvent: status}
+		}, nil
+	case entities.EventWithdrawalCreated:
+		var withdrawal models.WithdrawResponse
+		if err := json.Unmarshal([]byte(event.Payload), &withdrawal); err != nil {
+			return "", nil, fmt.Errorf("failed to decode %s event %d: %w", event.Type, event.ID, err)
+		}
+		return entities.WebhookWithdrawalCreated, func(externalID string) any {
+			return models.WebhookWithdrawalData{
+				ExternalID:  externalID,
+				Order:       withdrawal.Order,
+				Sum:         withdrawal.Sum,
+				ProcessedAt: withdrawal.ProcessedAt,
+			}
+		}, nil
+	}
+	return "", nil, nil
+}
+
+// subscribedTo есть ли тип события в списке типов подписки через запятую
+func subscribedTo(eventTypes string, eventType string) bool {
+	for _, subscribed := range strings.Split(eventTypes, ",") {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSignature подпись доставки. Время входит в подпись, чтобы перехваченный запрос
+// нельзя было повторить позже.
+func webhookSignature(secret string, timestamp int64, payload string) string {
+	signedAt := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signedAt + "." + payload))
+	return "t=" + signedAt + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSubscriptionResponse(subscription entities.WebhookSubscription) models.WebhookSubscriptionResponse {
+	return models.WebhookSubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: strings.Split(subscription.EventTypes, ","),
+		CreatedAt:  subscription.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testWebhookPolicy = WebhookPolicy{
+	MaxAttempts: 3,
+	RetryBase:   time.Minute,
+	RetryMax:    time.Hour,
+	Timeout:     time.Second,
+}
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repository := mocks.NewMockWebhookRepository(ctrl)
+	service := NewWebhookService(repository, testWebhookPolicy)
+	service.lookupIP = testLookupIP
+
+	_, err := service.CreateSubscription(context.Background(), dto.WebhookSubscriptionDTO{
+		PartnerID:  2,
+		URL:        "ftp://shop.example/hooks",
+		EventTypes: []string{entities.WebhookOrderProcessed, "order.deleted"},
+		Secret:     "short",
+	})
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || len(appErr.Violations) != 3 {
+		t.Errorf("expected three violations, got %v", err)
+	}
+
+	var saved entities.WebhookSubscription
+	repository.EXPECT().SaveSubscription(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, subscription *entities.WebhookSubscription) error {
+		subscription.ID = 4
+		saved = *subscription
+		return nil
+	})
+	created, err := service.CreateSubscription(context.Background(), dto.WebhookSubscriptionDTO{
+		PartnerID:  2,
+		URL:        "https://shop.example/hooks",
+		EventTypes: []string{entities.WebhookOrderProcessed, entities.WebhookWithdrawalCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 4 || created.Secret == "" || created.Secret != saved.Secret ||
+		saved.EventTypes != "order.processed,withdrawal.created" || len(created.EventTypes) != 2 {
+		t.Errorf("unexpected subscription %+v saved as %+v", created, saved)
+	}
+}
+
+// testLookupIP разрешает имена без обращения к DNS
+func testLookupIP(_ context.Context, host string) ([]net.IPAddr, error) {
+	addresses := map[string]string{
+		"shop.example":     "203.0.113.10",
+		"internal.example": "10.0.0.5",
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	if address, ok := addresses[host]; ok {
+		return []net.IPAddr{{IP: net.ParseIP(address)}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestWebhookService_CreateSubscriptionBlockedAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := NewWebhookService(mocks.NewMockWebhookRepository(ctrl), testWebhookPolicy)
+	service.lookupIP = testLookupIP
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "Loopback", url: "http://127.0.0.1:8080/hooks"},
+		{name: "IPv6 loopback", url: "http://[::1]/hooks"},
+		{name: "Private network", url: "https://192.168.1.10/hooks"},
+		{name: "Link-local metadata", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "Unspecified", url: "http://0.0.0.0/hooks"},
+		{name: "Name resolved to private network", url: "https://internal.example/hooks"},
+		{name: "Unresolvable name", url: "https://missing.example/hooks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateSubscription(context.Background(), dto.WebhookSubscriptionDTO{
+				PartnerID:  2,
+				URL:        tt.url,
+				EventTypes: []string{entities.WebhookOrderProcessed},
+			})
+			var appErr *apperrors.Error
+			if !errors.As(err, &appErr) || len(appErr.Violations) != 1 {
+				t.Errorf("expected url violation, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookService_HandleEvent(t *testing.T) {
+	createdAt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		event     entities.UserEvent
+		findCount int
+		payloads  []string
+	}{
+		{
+			name: "Processed order",
+			event: entities.UserEvent{
+				UserID:  7,
+				Type:    entities.EventOrderStatusChanged,
+				Payload: `{"number":"12345678903","status":"PROCESSED","accrual":500}`,
+			},
+			findCount: 1,
+			payloads: []string{`{"type":"order.processed","created_at":"2023-01-02T15:04:05Z",` +
+				`"data":{"external_id":"c-1","number":"12345678903","status":"PROCESSED","accrual":500}}`},
+		},
+		{
+			name: "Withdrawal",
+			event: entities.UserEvent{
+				UserID:  7,
+				Type:    entities.EventWithdrawalCreated,
+				Payload: `{"order":"2377225624","sum":100,"processed_at":"2023-01-02T15:04:05Z"}`,
+			},
+			findCount: 1,
+			payloads: []string{`{"type":"withdrawal.created","created_at":"2023-01-02T15:04:05Z",` +
+				`"data":{"external_id":"c-2","order":"2377225624","sum":100,"processed_at":"2023-01-02T15:04:05Z"}}`},
+		},
+		{
+			name: "Order still processing",
+			event: entities.UserEvent{
+				UserID:  7,
+				Type:    entities.EventOrderStatusChanged,
+				Payload: `{"number":"12345678903","status":"PROCESSING"}`,
+			},
+		},
+		{
+			name:  "Balance change",
+			event: entities.UserEvent{UserID: 7, Type: entities.EventBalanceChanged, Payload: `{"current":1}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repository := mocks.NewMockWebhookRepository(ctrl)
+			service := NewWebhookService(repository, testWebhookPolicy)
+			repository.EXPECT().FindSubscriptionTargets(gomock.Any(), uint(7)).Return([]dto.WebhookTarget{
+				{SubscriptionID: 1, PartnerID: 2, EventTypes: "order.processed,order.invalid", ExternalID: "c-1"},
+				{SubscriptionID: 3, PartnerID: 4, EventTypes: "withdrawal.created", ExternalID: "c-2"},
+			}, nil).Times(tt.findCount)
+			var payloads []string
+			repository.EXPECT().SaveDeliveries(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deliveries []entities.WebhookDelivery) error {
+				for _, delivery := range deliveries {
+					if delivery.Status != entities.DeliveryPending || delivery.NextAttemptAt.IsZero() {
+						t.Errorf("delivery is not queued: %+v", delivery)
+					}
+					payloads = append(payloads, delivery.Payload)
+				}
+				return nil
+			}).Times(len(tt.payloads))
+
+			tt.event.CreatedAt = createdAt
+			if err := service.HandleEvent(context.Background(), tt.event); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(payloads, "\n") != strings.Join(tt.payloads, "\n") {
+				t.Errorf("unexpected payloads:\n%s\nwant:\n%s", payloads, tt.payloads)
+			}
+		})
+	}
+}
+
+func TestWebhookService_DeliverDue(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	const payload = `{"type":"order.processed"}`
+	tests := []struct {
+		name           string
+		serverStatus   int
+		attempts       int
+		subscriptionOK bool
+		status         string
+		wantAttempts   int
+		retryAfter     time.Duration
+	}{
+		{
+			name:           "Delivered",
+			serverStatus:   http.StatusNoContent,
+			subscriptionOK: true,
+			status:         entities.DeliveryDelivered,
+			wantAttempts:   1,
+		},
+		{
+			name:           "Retried with backoff",
+			serverStatus:   http.StatusInternalServerError,
+			attempts:       1,
+			subscriptionOK: true,
+			status:         entities.DeliveryPending,
+			wantAttempts:   2,
+			retryAfter:     2 * time.Minute,
+		},
+		{
+			name:           "Out of attempts",
+			serverStatus:   http.StatusInternalServerError,
+			attempts:       2,
+			subscriptionOK: true,
+			status:         entities.DeliveryFailed,
+			wantAttempts:   3,
+		},
+		{
+			name:   "Subscription deleted",
+			status: entities.DeliveryFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				var timestamp, signature string
+				for _, part := range strings.Split(r.Header.Get(WebhookSignatureHeader), ",") {
+					key, value, _ := strings.Cut(part, "=")
+					switch key {
+					case "t":
+						timestamp = value
+					case "v1":
+						signature = value
+					}
+				}
+				mac := hmac.New(sha256.New, []byte("subscription-secret"))
+				mac.Write([]byte(timestamp + "." + string(body)))
+				if signature != hex.EncodeToString(mac.Sum(nil)) {
+					t.Errorf("invalid signature %q", r.Header.Get(WebhookSignatureHeader))
+				}
+				if r.Header.Get(WebhookEventHeader) != entities.WebhookOrderProcessed || r.Header.Get(WebhookDeliveryHeader) != "5" {
+					t.Errorf("unexpected headers: %v", r.Header)
+				}
+				w.WriteHeader(tt.serverStatus)
+			}))
+			defer server.Close()
+
+			repository := mocks.NewMockWebhookRepository(ctrl)
+			// тестовый сервер слушает loopback
+			policy := testWebhookPolicy
+			policy.AllowPrivateNetworks = true
+			service := NewWebhookService(repository, policy)
+			dueAt := time.Now().Add(-time.Second)
+			delivery := entities.WebhookDelivery{
+				Entity:         entities.Entity{Model: gorm.Model{ID: 5}},
+				SubscriptionID: 1,
+				EventType:      entities.WebhookOrderProcessed,
+				Payload:        payload,
+				Status:         entities.DeliveryPending,
+				Attempts:       tt.attempts,
+				NextAttemptAt:  dueAt,
+			}
+			repository.EXPECT().FindDueDeliveries(gomock.Any(), gomock.Any(), webhookBatchSize).Return([]entities.WebhookDelivery{delivery}, nil)
+			repository.EXPECT().ClaimDelivery(gomock.Any(), uint(5), dueAt, gomock.Any()).Return(true, nil)
+			if tt.subscriptionOK {
+				repository.EXPECT().FindSubscriptionByID(gomock.Any(), uint(1)).
+					Return(entities.WebhookSubscription{URL: server.URL, Secret: "subscription-secret"}, nil)
+			} else {
+				repository.EXPECT().FindSubscriptionByID(gomock.Any(), uint(1)).Return(entities.WebhookSubscription{}, gorm.ErrRecordNotFound)
+			}
+			var updated entities.WebhookDelivery
+			repository.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, delivery *entities.WebhookDelivery) error {
+				updated = *delivery
+				return nil
+			})
+
+			started := time.Now()
+			if err := service.DeliverDue(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tt.status || updated.Attempts != tt.wantAttempts || updated.ResponseStatus != tt.serverStatus {
+				t.Errorf("unexpected delivery state: %+v", updated)
+			}
+			if tt.status == entities.DeliveryDelivered && (updated.DeliveredAt == nil || updated.Error != "") {
+				t.Errorf("delivered delivery is not marked: %+v", updated)
+			}
+			if tt.retryAfter > 0 && (updated.NextAttemptAt.Before(started.Add(tt.retryAfter)) ||
+				updated.NextAttemptAt.After(time.Now().Add(tt.retryAfter))) {
+				t.Errorf("expected next attempt in %s, got %s", tt.retryAfter, updated.NextAttemptAt.Sub(started))
+			}
+		})
+	}
+}
+
+// TestWebhookService_DeliverDueBlockedAddress проверяет, что адрес проверяется и при соединении:
+// имя хоста могло разрешаться во внешний адрес при создании подписки и во внутренний при доставке
+func TestWebhookService_DeliverDueBlockedAddress(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook must not reach a loopback address")
+	}))
+	defer server.Close()
+
+	repository := mocks.NewMockWebhookRepository(ctrl)
+	service := NewWebhookService(repository, testWebhookPolicy)
+	dueAt := time.Now().Add(-time.Second)
+	delivery := entities.WebhookDelivery{
+		Entity:         entities.Entity{Model: gorm.Model{ID: 5}},
+		SubscriptionID: 1,
+		EventType:      entities.WebhookOrderProcessed,
+		Payload:        `{"type":"order.processed"}`,
+		Status:         entities.DeliveryPending,
+		NextAttemptAt:  dueAt,
+	}
+	repository.EXPECT().FindDueDeliveries(gomock.Any(), gomock.Any(), webhookBatchSize).Return([]entities.WebhookDelivery{delivery}, nil)
+	repository.EXPECT().ClaimDelivery(gomock.Any(), uint(5), dueAt, gomock.Any()).Return(true, nil)
+	repository.EXPECT().FindSubscriptionByID(gomock.Any(), uint(1)).
+		Return(entities.WebhookSubscription{URL: server.URL, Secret: "subscription-secret"}, nil)
+	var updated entities.WebhookDelivery
+	repository.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, delivery *entities.WebhookDelivery) error {
+		updated = *delivery
+		return nil
+	})
+
+	if err := service.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != entities.DeliveryPending || updated.Attempts != 1 || !strings.Contains(updated.Error, "not allowed") {
+		t.Errorf("unexpected delivery state: %+v", updated)
+	}
+}
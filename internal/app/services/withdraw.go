@@ -1,11 +1,14 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/models"
-	"sort"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
 	"sync"
 	"time"
 )
@@ -14,53 +17,119 @@ type WithdrawService struct {
 	withdrawRepository WithdrawRepository
 	userRepository     UserRepository
 	mutex              *sync.Mutex
+	twoFactor          *TwoFactorService
+	events             *EventBus
+	// totpThreshold списания на большую сумму требуют кода второго фактора, 0 - без ограничения
+	totpThreshold float64
 }
 
 func NewWithdrawService(
 	withdrawRepository WithdrawRepository,
 	userRepository UserRepository,
 	mutex *sync.Mutex,
+	twoFactor *TwoFactorService,
+	events *EventBus,
+	totpThreshold float64,
 ) *WithdrawService {
 	return &WithdrawService{
 		withdrawRepository: withdrawRepository,
 		userRepository:     userRepository,
 		mutex:              mutex,
+		twoFactor:          twoFactor,
+		events:             events,
+		totpThreshold:      totpThreshold,
 	}
 }
 
-func (s *WithdrawService) SaveWithdraw(withdrawDTO dto.WithdrawDTO) error {
+// SaveWithdraw списывает баллы и учитывает результат попытки в метриках
+func (s *WithdrawService) SaveWithdraw(ctx context.Context, withdrawDTO dto.WithdrawDTO) error {
+	ctx, span := tracing.Start(ctx, "WithdrawService.SaveWithdraw")
+	defer span.End()
+	err := s.saveWithdraw(ctx, withdrawDTO)
+	var appErr *apperrors.Error
+	switch {
+	case err == nil:
+		metrics.PointsWithdrawn.Add(withdrawDTO.Sum)
+		metrics.Withdrawals.WithLabelValues("success").Inc()
+	case errors.As(err, &appErr):
+		metrics.Withdrawals.WithLabelValues(string(appErr.Code)).Inc()
+	default:
+		metrics.Withdrawals.WithLabelValues("error").Inc()
+	}
+	return err
+}
+
+func (s *WithdrawService) saveWithdraw(ctx context.Context, withdrawDTO dto.WithdrawDTO) error {
+	// Проверки выполняются здесь, чтобы их получали все транспорты: HTTP, gRPC и будущие
+	if withdrawDTO.Sum <= 0 {
+		return apperrors.InvalidRequest("withdrawal sum must be positive")
+	}
+	if !checkOrderNumber(withdrawDTO.OrderNumber) {
+		return apperrors.ErrInvalidOrderNumber
+	}
+	if s.totpThreshold > 0 && withdrawDTO.Sum > s.totpThreshold {
+		if err := s.twoFactor.VerifyFreshCode(ctx, withdrawDTO.UserID, withdrawDTO.TOTPCode); err != nil {
+			return err
+		}
+	}
+	// Ожидание общей блокировки отдельным спаном, чтобы его было видно в трассе списания
+	_, lockSpan := tracing.Start(ctx, "WithdrawService.lock")
 	s.mutex.Lock()
+	lockSpan.End()
 	defer s.mutex.Unlock()
-	user, err := s.userRepository.FindUserByID(withdrawDTO.UserID)
+	user, err := s.userRepository.FindUserByID(ctx, withdrawDTO.UserID)
 	if err != nil {
 		return err
 	}
-	err = s.withdrawRepository.Save(&entities.Withdraw{
+	// Отклонённое списание не сохраняется, иначе оно попало бы в историю и выгрузку операций
+	if user.Balance < withdrawDTO.Sum {
+		return apperrors.ErrInsufficientFunds
+	}
+	withdraw := entities.Withdraw{
 		OrderNumber: withdrawDTO.OrderNumber,
 		Sum:         withdrawDTO.Sum,
 		UserID:      user.ID,
-	})
+	}
+	err = s.withdrawRepository.Save(ctx, &withdraw)
 	if err != nil {
 		return err
 	}
-	if user.Balance < withdrawDTO.Sum {
-		return errors.New("not enough funds")
-	}
 	user.Balance -= withdrawDTO.Sum
 	user.Withdrawn += withdrawDTO.Sum
-	err = s.userRepository.Update(&user)
+	err = s.userRepository.Update(ctx, &user)
 	if err != nil {
 		return err
 	}
+	s.events.PublishLogged(ctx, user.ID, entities.EventBalanceChanged, models.BalanceResponse{
+		Current:   user.Balance,
+		Withdrawn: user.Withdrawn,
+	})
+	s.events.PublishLogged(ctx, user.ID, entities.EventWithdrawalCreated, models.WithdrawResponse{
+		Order:       withdraw.OrderNumber,
+		Sum:         withdraw.Sum,
+		ProcessedAt: withdraw.CreatedAt.Format(time.RFC3339),
+	})
 	return nil
 }
 
-func (s *WithdrawService) GetAllWithdrawals(userID uint) ([]models.WithdrawResponse, error) {
-	withdrawals, err := s.withdrawRepository.GetWithdrawals(userID)
+func (s *WithdrawService) GetWithdrawalsPage(ctx context.Context, query dto.PageQuery) (models.WithdrawPage, error) {
+	ctx, span := tracing.Start(ctx, "WithdrawService.GetWithdrawalsPage")
+	defer span.End()
+	filter, err := newPageFilter(query)
 	if err != nil {
-		return nil, err
+		return models.WithdrawPage{}, err
 	}
-	var response = make([]models.WithdrawResponse, 0)
+	withdrawals, err := s.withdrawRepository.GetWithdrawalsPage(ctx, filter)
+	if err != nil {
+		return models.WithdrawPage{}, err
+	}
+	var page models.WithdrawPage
+	if len(withdrawals) > query.Limit {
+		withdrawals = withdrawals[:query.Limit]
+		last := withdrawals[len(withdrawals)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID, query.Descending, pageFilters(query))
+	}
+	page.Items = make([]models.WithdrawResponse, 0, len(withdrawals))
 	for _, withdraw := range withdrawals {
 		resp := models.WithdrawResponse{
 			Order:         withdraw.OrderNumber,
@@ -68,10 +137,7 @@ func (s *WithdrawService) GetAllWithdrawals(userID uint) ([]models.WithdrawRespo
 			ProcessedDate: withdraw.CreatedAt,
 			ProcessedAt:   withdraw.CreatedAt.Format(time.RFC3339),
 		}
-		response = append(response, resp)
+		page.Items = append(page.Items, resp)
 	}
-	sort.Slice(response, func(i, j int) bool {
-		return response[i].ProcessedDate.Before(response[j].ProcessedDate)
-	})
-	return response, nil
+	return page, nil
 }
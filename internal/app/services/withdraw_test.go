@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/apperrors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+)
+
+func TestWithdrawService_SaveWithdrawValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// репозитории не должны вызываться: некорректное списание отклоняется до обращения к базе
+	service := NewWithdrawService(mocks.NewMockWithdrawRepository(ctrl), mocks.NewMockUserRepository(ctrl),
+		&sync.Mutex{}, nil, nil, 0)
+
+	tests := []struct {
+		name    string
+		dto     dto.WithdrawDTO
+		wantErr error
+	}{
+		{name: "Negative sum", dto: dto.WithdrawDTO{OrderNumber: "2377225624", Sum: -1000, UserID: 7}, wantErr: apperrors.ErrInvalidRequest},
+		{name: "Zero sum", dto: dto.WithdrawDTO{OrderNumber: "2377225624", Sum: 0, UserID: 7}, wantErr: apperrors.ErrInvalidRequest},
+		{name: "Invalid order number", dto: dto.WithdrawDTO{OrderNumber: "2377225625", Sum: 100, UserID: 7}, wantErr: apperrors.ErrInvalidOrderNumber},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.SaveWithdraw(context.Background(), tt.dto)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWithdrawService_SaveWithdrawInsufficientFunds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	withdrawals := mocks.NewMockWithdrawRepository(ctrl)
+	service := NewWithdrawService(withdrawals, users, &sync.Mutex{}, nil, nil, 0)
+
+	user := entities.User{Entity: entities.Entity{Model: gorm.Model{ID: 7}}, Balance: 100}
+	users.EXPECT().FindUserByID(gomock.Any(), uint(7)).Return(user, nil)
+	// отклонённое списание не должно остаться в истории
+	withdrawals.EXPECT().Save(gomock.Any(), gomock.Any()).Times(0)
+	err := service.SaveWithdraw(context.Background(), dto.WithdrawDTO{OrderNumber: "2377225624", Sum: 500, UserID: 7})
+	if !errors.Is(err, apperrors.ErrInsufficientFunds) {
+		t.Errorf("expected insufficient funds, got %v", err)
+	}
+}
@@ -2,37 +2,89 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-gonic/gin"
 	"github.com/keyjin88/go-loyalty-system/internal/app/config"
 	"github.com/keyjin88/go-loyalty-system/internal/app/daemons"
+	"github.com/keyjin88/go-loyalty-system/internal/app/grpcapi"
+	"github.com/keyjin88/go-loyalty-system/internal/app/grpcapi/pb"
 	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/health"
+	"github.com/keyjin88/go-loyalty-system/internal/app/keyring"
 	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/metrics"
 	"github.com/keyjin88/go-loyalty-system/internal/app/middleware"
 	"github.com/keyjin88/go-loyalty-system/internal/app/middleware/compressor"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/notifier"
+	"github.com/keyjin88/go-loyalty-system/internal/app/openapi"
 	"github.com/keyjin88/go-loyalty-system/internal/app/services"
 	"github.com/keyjin88/go-loyalty-system/internal/app/storage"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+const (
+	// hmacKeyID kid, под которым в связку ключей попадает HMAC-секрет из конфигурации
+	hmacKeyID = "hmac"
+	// tracingServiceName имя сервиса в спанах
+	tracingServiceName = "gophermart"
+	// dbConnectRetryInterval пауза между попытками подключиться к БД при запуске
+	dbConnectRetryInterval = time.Second
+)
+
 type API struct {
-	config             *config.Config
-	router             *gin.Engine
-	handlers           *handlers.Handler
-	userService        *services.UserService
-	orderService       *services.OrderService
-	withdrawService    *services.WithdrawService
-	userRepository     *storage.UserRepository
-	orderRepository    *storage.OrderRepository
-	withdrawRepository *storage.WithdrawRepository
+	config              *config.Config
+	router              *gin.Engine
+	grpcServer          *grpc.Server
+	adminServer         *http.Server
+	health              *health.Checker
+	handlers            *handlers.Handler
+	userService         *services.UserService
+	orderService        *services.OrderService
+	withdrawService     *services.WithdrawService
+	tokenService        *services.TokenService
+	adminService        *services.AdminService
+	auditService        *services.AuditService
+	resetService        *services.PasswordResetService
+	twoFactorService    *services.TwoFactorService
+	partnerService      *services.PartnerService
+	accountService      *services.AccountService
+	oidcService         *services.OIDCService
+	eventBus            *services.EventBus
+	webhookService      *services.WebhookService
+	keyring             *keyring.Keyring
+	apiDocument         *openapi3.T
+	apiRouter           routers.Router
+	userRepository      *storage.UserRepository
+	orderRepository     *storage.OrderRepository
+	withdrawRepository  *storage.WithdrawRepository
+	tokenRepository     *storage.TokenRepository
+	auditRepository     *storage.AuditRepository
+	loginThrottles      *storage.LoginThrottleRepository
+	resetRepository     *storage.PasswordResetRepository
+	twoFactorRepository *storage.TwoFactorRepository
+	partnerRepository   *storage.PartnerRepository
+	oidcRepository      *storage.OIDCRepository
+	eventRepository     *storage.EventRepository
+	webhookRepository   *storage.WebhookRepository
 }
 
 func New() *API {
@@ -49,15 +101,62 @@ func (api *API) Start() error {
 	}
 
 	api.config.InitConfig()
-	api.configureRouter()
-	db := api.ConfigDBConnection()
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    api.config.TracingExporter,
+		Endpoint:    api.config.TracingEndpoint,
+		Insecure:    api.config.TracingInsecure,
+		File:        api.config.TracingFile,
+		ServiceName: tracingServiceName,
+		SampleRatio: api.config.TracingSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	// Служебный сервер запускается первым: пока приложение ждёт БД, /healthz уже отвечает,
+	// а /readyz сообщает, что приложение запускается
+	api.health = health.NewChecker(api.config.ReadyTimeout)
+	api.configAdminServer()
+	if api.adminServer != nil {
+		go func() {
+			if err := api.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Log.Infof("Error while start admin server: %v", err)
+			}
+		}()
+	}
+	if err := api.configKeyring(); err != nil {
+		return err
+	}
+	if err := api.configAPIDocument(); err != nil {
+		return err
+	}
+	db, err := api.ConfigDBConnection()
+	if err != nil {
+		return err
+	}
 	api.configStorage(db)
 	// Канал для обработки заказов через сервер Accrual
 	// Если уже есть пулл горутин, то насколько важна буферизация канала? Или я чего-то не понял?
-	orderProcessingChannel := make(chan entities.Order, api.config.ProcessingChannelBufferSize)
+	orderProcessingChannel := make(chan dto.OrderTask, api.config.ProcessingChannelBufferSize)
 	mutex := &sync.Mutex{}
-	api.configService(orderProcessingChannel, mutex)
+	if err := api.configService(orderProcessingChannel, mutex); err != nil {
+		return err
+	}
+	if api.config.AdminLogin != "" {
+		if err := api.userService.BootstrapAdmin(context.Background(), api.config.AdminLogin, api.config.AdminPassword); err != nil {
+			return fmt.Errorf("failed to bootstrap admin: %w", err)
+		}
+	}
 	api.configHandlers()
+	if err := api.configureRouter(); err != nil {
+		return err
+	}
+	api.configGRPCServer()
+	if err := api.configMetrics(db, orderProcessingChannel); err != nil {
+		return err
+	}
+	if err := api.configHealthChecks(db, orderProcessingChannel); err != nil {
+		return err
+	}
 	api.configWorkers(db, orderProcessingChannel, mutex)
 
 	// Создаем HTTP-сервер
@@ -66,18 +165,39 @@ func (api *API) Start() error {
 		Handler: api.router,
 	}
 
+	// Порт занимается до запуска горутины, чтобы ошибка остановила запуск, а не осталась в логе
+	// при уже отмеченной готовности
+	listener, err := net.Listen("tcp", api.config.ServerAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTP: %w", err)
+	}
 	// Запускаем HTTP-сервер в отдельной горутине
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Log.Infof("Error while start server")
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Log.Errorf("Error while serving HTTP: %v", err)
 		}
 	}()
+	if api.grpcServer != nil {
+		listener, err := net.Listen("tcp", api.config.GRPCAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		go func() {
+			if err := api.grpcServer.Serve(listener); err != nil {
+				logger.Log.Infof("Error while start gRPC server: %v", err)
+			}
+		}()
+	}
+	api.health.Started()
 	logger.Log.Infof("Server started")
 	// Ожидаем получения сигнала остановки
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Log.Infof("Stop signal received")
+	// Снимаем готовность и даём балансировщику время заметить это, прежде чем перестать принимать запросы
+	api.health.ShuttingDown()
+	time.Sleep(api.config.ShutdownDelay)
 	// Отменяем контекст для graceful shutdown
 	cancel()
 	// Устанавливаем таймаут для graceful shutdown
@@ -88,69 +208,523 @@ func (api *API) Start() error {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		logger.Log.Infof("Error shutting down")
 	}
+	if api.grpcServer != nil {
+		api.stopGRPCServer(ctxShutdown)
+	}
+	if api.adminServer != nil {
+		if err := api.adminServer.Shutdown(ctxShutdown); err != nil {
+			logger.Log.Infof("Error shutting down admin server")
+		}
+	}
+	// Спаны отправляются последними, чтобы попали и завершившиеся при остановке запросы
+	if err := shutdownTracing(ctxShutdown); err != nil {
+		logger.Log.Infof("Error flushing traces: %v", err)
+	}
 	log.Println("Сервер остановлен")
 	return nil
 }
 
-func (api *API) ConfigDBConnection() *gorm.DB {
-	// Создание пула соединений
-	db, err := gorm.Open(postgres.Open(api.config.DataBaseURI), &gorm.Config{})
-	if err != nil {
-		log.Fatal(err)
+// ConfigDBConnection подключается к БД, повторяя попытки в течение DBConnectTimeout:
+// при одновременном запуске база может стать доступна позже приложения
+func (api *API) ConfigDBConnection() (*gorm.DB, error) {
+	deadline := time.Now().Add(api.config.DBConnectTimeout)
+	for {
+		// Создание пула соединений
+		db, err := gorm.Open(postgres.Open(api.config.DataBaseURI), &gorm.Config{})
+		if err == nil {
+			return configDBPool(db)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		logger.Log.Warnf("Database is not available, retrying in %s: %v", dbConnectRetryInterval, err)
+		time.Sleep(dbConnectRetryInterval)
 	}
+}
+
+func configDBPool(db *gorm.DB) (*gorm.DB, error) {
 	// Установка максимального количества подключений в пуле
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
-	return db
+	if err := db.Use(storage.TracingPlugin{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// configKeyring собирает ключи подписи токенов: HMAC-секрет и ключи RS256/EdDSA из PEM-файлов.
+// Если ключ подписи не указан явно, подписывает первый ключ из списка с закрытой частью.
+func (api *API) configKeyring() error {
+	keys := keyring.New()
+	var signingKeyIDs []string
+	if api.config.SecretKey != "" {
+		if err := keys.AddHMAC(hmacKeyID, []byte(api.config.SecretKey)); err != nil {
+			return err
+		}
+		signingKeyIDs = append(signingKeyIDs, hmacKeyID)
+	}
+	paths, order, err := keyring.ParseKeySpec(api.config.JWTKeys)
+	if err != nil {
+		return err
+	}
+	for _, id := range order {
+		if err := keys.AddPEMFile(id, paths[id]); err != nil {
+			return err
+		}
+	}
+	signingKeyIDs = append(order, signingKeyIDs...)
+	if len(signingKeyIDs) == 0 {
+		logger.Log.Warnf("No JWT keys configured, using a random HMAC key: tokens will not survive a restart")
+		if err := keys.RandomHMAC(hmacKeyID); err != nil {
+			return err
+		}
+		signingKeyIDs = append(signingKeyIDs, hmacKeyID)
+	}
+	if api.config.JWTSigningKeyID != "" {
+		signingKeyIDs = []string{api.config.JWTSigningKeyID}
+	}
+	for _, id := range signingKeyIDs {
+		if err = keys.SetSigningKey(id); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	logger.Log.Infof("Signing tokens with key %s (%s)", keys.SigningKey().ID, keys.SigningKey().Algorithm)
+	api.keyring = keys
+	return nil
+}
+
+// configAPIDocument загружает описание API, по которому проверяются входящие запросы
+func (api *API) configAPIDocument() error {
+	document, err := openapi.Load()
+	if err != nil {
+		return err
+	}
+	apiRouter, err := gorillamux.NewRouter(document)
+	if err != nil {
+		return fmt.Errorf("failed to build OpenAPI router: %w", err)
+	}
+	api.apiDocument = document
+	api.apiRouter = apiRouter
+	return nil
 }
 
 func (api *API) configHandlers() {
-	api.handlers = handlers.NewHandler(api.userService, api.orderService, api.withdrawService, api.config.SecretKey)
+	api.handlers = handlers.NewHandler(
+		api.userService,
+		api.orderService,
+		api.withdrawService,
+		api.tokenService,
+		api.adminService,
+		api.resetService,
+		api.twoFactorService,
+		api.partnerService,
+		api.accountService,
+		api.auditService,
+		api.oidcService,
+		api.eventBus,
+		api.webhookService,
+		api.keyring,
+		api.apiDocument,
+		handlers.SessionCookie{
+			Enabled: api.config.AuthCookie,
+			Secure:  api.config.AuthCookieSecure,
+			Domain:  api.config.AuthCookieDomain,
+		},
+		api.config.OrderBatchMaxSize,
+	)
+}
+
+// configGRPCServer собирает gRPC API. Пустой адрес отключает его.
+func (api *API) configGRPCServer() {
+	if api.config.GRPCAddress == "" {
+		return
+	}
+	api.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			grpcapi.UnaryLoggingInterceptor(),
+			grpcapi.UnaryErrorInterceptor(),
+			grpcapi.UnaryAuditInterceptor(api.auditService),
+			grpcapi.UnaryAuthInterceptor(api.keyring.Keyfunc, api.tokenService),
+		),
+		grpc.ChainStreamInterceptor(
+			otelgrpc.StreamServerInterceptor(),
+			grpcapi.StreamLoggingInterceptor(),
+			grpcapi.StreamErrorInterceptor(),
+			grpcapi.StreamAuditInterceptor(api.auditService),
+			grpcapi.StreamAuthInterceptor(api.keyring.Keyfunc, api.tokenService),
+		),
+	)
+	pb.RegisterGophermartServer(api.grpcServer, grpcapi.NewServer(
+		api.userService,
+		api.orderService,
+		api.withdrawService,
+		api.tokenService,
+		api.twoFactorService,
+		api.eventBus,
+	))
+}
+
+// configMetrics добавляет к метрикам очередь заказов и статистику пула соединений с БД
+func (api *API) configMetrics(db *gorm.DB, channel chan dto.OrderTask) error {
+	if err := metrics.RegisterOrderQueue(channel); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return metrics.RegisterDB(sqlDB)
+}
+
+// configHealthChecks собирает проверки готовности: соединение с БД, версию схемы, доступность
+// системы Accrual и заполненность очереди заказов
+func (api *API) configHealthChecks(db *gorm.DB, channel chan dto.OrderTask) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	api.health.AddCheck(health.DatabaseCheck(sqlDB))
+	api.health.AddCheck(health.Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			version, dirty, err := storage.MigrationVersion(ctx, db)
+			if err != nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("version %d", version)
+			if dirty {
+				return detail, fmt.Errorf("migration %d is dirty", version)
+			}
+			if version < api.config.MinMigrationVersion {
+				return detail, fmt.Errorf("schema version is below %d", api.config.MinMigrationVersion)
+			}
+			return detail, nil
+		},
+	})
+	api.health.AddCheck(health.ReachabilityCheck("accrual", api.config.AccrualSystemAddress, http.DefaultClient))
+	api.health.AddCheck(health.QueueCheck(channel, api.config.ReadyQueueSaturation))
+	return nil
+}
+
+// configAdminServer собирает служебный HTTP-сервер с /metrics, /healthz и /readyz отдельно от API,
+// чтобы они не были доступны снаружи. Пустой адрес отключает его.
+func (api *API) configAdminServer() {
+	if api.config.AdminAddress == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", api.health.LivenessHandler())
+	mux.Handle("/readyz", api.health.ReadinessHandler())
+	api.adminServer = &http.Server{
+		Addr:    api.config.AdminAddress,
+		Handler: mux,
+	}
 }
 
-func (api *API) configureRouter() {
+// stopGRPCServer дожидается завершения вызовов, а по истечении ctx обрывает оставшиеся,
+// в том числе открытые потоки WatchOrders
+func (api *API) stopGRPCServer(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		api.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		api.grpcServer.Stop()
+	}
+}
+
+func (api *API) configureRouter() error {
 	if api.config.GinReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	router := gin.New()
+	// Пробы регистрируются до middleware, чтобы частые проверки оркестратора не засоряли
+	// журнал запросов, трассы и метрики. Они же есть на служебном сервере, но тот по умолчанию
+	// слушает только localhost и снаружи контейнера недоступен.
+	router.GET("/healthz", gin.WrapH(api.health.LivenessHandler()))
+	router.GET("/readyz", gin.WrapH(api.health.ReadinessHandler()))
+	// Без явного списка прокси gin доверяет X-Forwarded-For от любого клиента, и подменой заголовка
+	// можно обойти блокировку входа по IP и исказить адреса в сессиях и журнале аудита
+	if err := router.SetTrustedProxies(trustedProxies(api.config.TrustedProxies)); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.AccessLog())
+	router.Use(middleware.Metrics())
 	router.Use(compressor.CompressionMiddleware())
-	router.Use(gin.Logger())
+	audit := func(eventType string) gin.HandlerFunc {
+		return middleware.AuditEvent(api.auditService, eventType)
+	}
+	validate := middleware.ValidateRequest(api.apiRouter)
 	authGroup := router.Group("/")
+	authGroup.Use(validate)
 	{
-		authGroup.POST("api/user/register", func(c *gin.Context) { api.handlers.RegisterUser(c) })
-		authGroup.POST("api/user/login", func(c *gin.Context) { api.handlers.LoginUser(c) })
+		authGroup.GET(".well-known/jwks.json", func(c *gin.Context) { api.handlers.GetJWKS(c) })
+		authGroup.GET("openapi.json", func(c *gin.Context) { api.handlers.GetOpenAPI(c) })
+		authGroup.POST("api/user/register", audit(entities.AuditRegister),
+			func(c *gin.Context) { api.handlers.RegisterUser(c) })
+		authGroup.POST("api/user/login", audit(entities.AuditLogin),
+			func(c *gin.Context) { api.handlers.LoginUser(c) })
+		authGroup.POST("api/user/login/2fa", audit(entities.AuditLoginTwoFactor),
+			func(c *gin.Context) { api.handlers.CompleteLogin(c) })
+		authGroup.POST("api/user/token/refresh", audit(entities.AuditTokenRefresh),
+			func(c *gin.Context) { api.handlers.RefreshToken(c) })
+		authGroup.POST("api/user/password/reset", audit(entities.AuditPasswordResetRequest),
+			func(c *gin.Context) { api.handlers.RequestPasswordReset(c) })
+		authGroup.POST("api/user/password/reset/confirm", audit(entities.AuditPasswordReset),
+			func(c *gin.Context) { api.handlers.ConfirmPasswordReset(c) })
+		if api.oidcService != nil {
+			authGroup.GET("api/user/oidc/login", func(c *gin.Context) { api.handlers.StartOIDCLogin(c) })
+			authGroup.GET("api/user/oidc/callback", audit(entities.AuditLoginOIDC),
+				func(c *gin.Context) { api.handlers.CompleteOIDCLogin(c) })
+		}
 	}
 	protectedGroup := router.Group("/")
-	protectedGroup.Use(middleware.AuthMiddleware(api.config.SecretKey))
+	protectedGroup.Use(
+		middleware.AuditRejected(api.auditService, entities.AuditTokenRejected),
+		middleware.AuthMiddleware(api.keyring.Keyfunc, api.tokenService),
+		validate,
+	)
 	{
+		protectedGroup.POST("api/user/logout", audit(entities.AuditLogout),
+			func(c *gin.Context) { api.handlers.Logout(c) })
+		protectedGroup.GET("api/user/sessions", func(c *gin.Context) { api.handlers.ListSessions(c) })
+		protectedGroup.DELETE("api/user/sessions/:id", audit(entities.AuditSessionRevoke),
+			func(c *gin.Context) { api.handlers.RevokeSession(c) })
+		if api.oidcService != nil {
+			protectedGroup.POST("api/user/oidc/link", audit(entities.AuditOIDCLink),
+				func(c *gin.Context) { api.handlers.LinkOIDCIdentity(c) })
+		}
+		protectedGroup.DELETE("api/user", audit(entities.AuditAccountDelete),
+			func(c *gin.Context) { api.handlers.DeleteAccount(c) })
+		protectedGroup.GET("api/user/export", func(c *gin.Context) { api.handlers.ExportUserData(c) })
+		protectedGroup.POST("api/user/password", audit(entities.AuditPasswordChange),
+			func(c *gin.Context) { api.handlers.ChangePassword(c) })
+		protectedGroup.PUT("api/user/email", func(c *gin.Context) { api.handlers.UpdateEmail(c) })
+		protectedGroup.POST("api/user/2fa/enroll", audit(entities.AuditTwoFactorEnroll),
+			func(c *gin.Context) { api.handlers.EnrollTwoFactor(c) })
+		protectedGroup.POST("api/user/2fa/confirm", audit(entities.AuditTwoFactorConfirm),
+			func(c *gin.Context) { api.handlers.ConfirmTwoFactor(c) })
+		protectedGroup.POST("api/user/2fa/disable", audit(entities.AuditTwoFactorDisable),
+			func(c *gin.Context) { api.handlers.DisableTwoFactor(c) })
 		protectedGroup.POST("api/user/orders", func(c *gin.Context) { api.handlers.ProcessUserOrder(c) })
+		protectedGroup.POST("api/user/orders/batch", func(c *gin.Context) { api.handlers.ProcessUserOrdersBatch(c) })
 		protectedGroup.GET("api/user/orders", func(c *gin.Context) { api.handlers.GetAllOrders(c) })
+		protectedGroup.GET("api/user/orders/:number", func(c *gin.Context) { api.handlers.GetOrder(c) })
 		protectedGroup.GET("api/user/balance", func(c *gin.Context) { api.handlers.GetBalance(c) })
 		protectedGroup.GET("api/user/withdrawals", func(c *gin.Context) { api.handlers.GetAllWithdrawals(c) })
-		protectedGroup.POST("api/user/balance/withdraw", func(c *gin.Context) { api.handlers.SaveWithdraw(c) })
+		protectedGroup.GET("api/user/events", func(c *gin.Context) { api.handlers.StreamEvents(c) })
+		protectedGroup.POST("api/user/balance/withdraw", audit(entities.AuditWithdraw),
+			func(c *gin.Context) { api.handlers.SaveWithdraw(c) })
+	}
+	adminGroup := router.Group("/api/admin")
+	adminGroup.Use(
+		middleware.AuditRejected(api.auditService, entities.AuditTokenRejected),
+		middleware.AuthMiddleware(api.keyring.Keyfunc, api.tokenService),
+		middleware.RequireRole(entities.RoleAdmin),
+		middleware.AuditMiddleware(api.auditService),
+		validate,
+	)
+	{
+		adminGroup.GET("users", func(c *gin.Context) { api.handlers.SearchUsers(c) })
+		adminGroup.GET("users/:id", func(c *gin.Context) { api.handlers.GetUser(c) })
+		adminGroup.GET("users/:id/balance", func(c *gin.Context) { api.handlers.GetUserBalance(c) })
+		adminGroup.GET("audit-events", func(c *gin.Context) { api.handlers.SearchAuditEvents(c) })
+		adminGroup.POST("partners", func(c *gin.Context) { api.handlers.CreatePartner(c) })
+		adminGroup.POST("partners/:id/keys", func(c *gin.Context) { api.handlers.CreateAPIKey(c) })
+		adminGroup.DELETE("partners/:id/keys/:keyID", func(c *gin.Context) { api.handlers.RevokeAPIKey(c) })
+		adminGroup.PUT("partners/:id/users/:externalID", func(c *gin.Context) { api.handlers.LinkPartnerUser(c) })
+	}
+	partnerGroup := router.Group("/api/partner")
+	partnerGroup.Use(
+		middleware.AuditRejected(api.auditService, entities.AuditAPIKeyRejected),
+		middleware.APIKeyMiddleware(api.partnerService),
+		validate,
+	)
+	{
+		partnerGroup.POST("users/:externalID/orders", middleware.RequireScope(entities.ScopeOrdersWrite),
+			func(c *gin.Context) { api.handlers.ProcessPartnerOrder(c) })
+		partnerGroup.GET("users/:externalID/balance", middleware.RequireScope(entities.ScopeBalanceRead),
+			func(c *gin.Context) { api.handlers.GetPartnerUserBalance(c) })
+		partnerGroup.POST("webhooks", middleware.RequireScope(entities.ScopeWebhooksManage),
+			func(c *gin.Context) { api.handlers.CreateWebhookSubscription(c) })
+		partnerGroup.GET("webhooks", middleware.RequireScope(entities.ScopeWebhooksManage),
+			func(c *gin.Context) { api.handlers.ListWebhookSubscriptions(c) })
+		partnerGroup.DELETE("webhooks/:id", middleware.RequireScope(entities.ScopeWebhooksManage),
+			func(c *gin.Context) { api.handlers.DeleteWebhookSubscription(c) })
+		partnerGroup.GET("webhooks/:id/deliveries", middleware.RequireScope(entities.ScopeWebhooksManage),
+			func(c *gin.Context) { api.handlers.GetWebhookDeliveries(c) })
 	}
 	api.router = router
+	return nil
+}
+
+// trustedProxies разбирает список доверенных прокси через запятую, пустой список - не доверять никому
+func trustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
 }
 
 func (api *API) configStorage(db *gorm.DB) {
 	api.userRepository = storage.NewUserRepository(db)
 	api.orderRepository = storage.NewOrderRepository(db)
 	api.withdrawRepository = storage.NewWithdrawRepository(db)
+	api.tokenRepository = storage.NewTokenRepository(db)
+	api.auditRepository = storage.NewAuditRepository(db)
+	api.loginThrottles = storage.NewLoginThrottleRepository(db)
+	api.resetRepository = storage.NewPasswordResetRepository(db)
+	api.twoFactorRepository = storage.NewTwoFactorRepository(db)
+	api.partnerRepository = storage.NewPartnerRepository(db)
+	api.oidcRepository = storage.NewOIDCRepository(db)
+	api.eventRepository = storage.NewEventRepository(db)
+	api.webhookRepository = storage.NewWebhookRepository(db)
 }
 
-func (api *API) configService(channel chan entities.Order, mutex *sync.Mutex) {
-	api.userService = services.NewUserService(api.userRepository)
-	api.withdrawService = services.NewWithdrawService(api.withdrawRepository, api.userRepository, mutex)
+func (api *API) configService(channel chan dto.OrderTask, mutex *sync.Mutex) error {
+	policy := services.CredentialsPolicy{
+		LoginMinLength:     api.config.LoginMinLength,
+		LoginMaxLength:     api.config.LoginMaxLength,
+		PasswordMinLength:  api.config.PasswordMinLength,
+		PasswordMinClasses: api.config.PasswordMinClasses,
+	}
+	if api.config.LoginPattern != "" {
+		pattern, err := regexp.Compile(api.config.LoginPattern)
+		if err != nil {
+			return fmt.Errorf("invalid login pattern: %w", err)
+		}
+		policy.LoginPattern = pattern
+	}
+	loginGuard := services.NewLoginGuard(
+		api.loginThrottles,
+		services.LoginPolicy{
+			FreeAttempts: api.config.LoginFreeAttempts,
+			MaxFailures:  api.config.LoginMaxFailures,
+			BaseDelay:    api.config.LoginBaseDelay,
+			Lockout:      api.config.LoginLockout,
+		},
+		services.LoginPolicy{
+			FreeAttempts: api.config.LoginIPFreeAttempts,
+			MaxFailures:  api.config.LoginIPMaxFailures,
+			BaseDelay:    api.config.LoginBaseDelay,
+			Lockout:      api.config.LoginLockout,
+		},
+	)
+	api.userService = services.NewUserService(api.userRepository, loginGuard, policy)
+	api.tokenService = services.NewTokenService(
+		api.tokenRepository,
+		api.userRepository,
+		api.keyring,
+		api.config.AccessTokenTTL,
+		api.config.RefreshTokenTTL,
+	)
+	api.twoFactorService = services.NewTwoFactorService(
+		api.userRepository,
+		api.twoFactorRepository,
+		loginGuard,
+		api.config.TOTPIssuer,
+		api.config.LoginChallengeTTL,
+	)
+	api.eventBus = services.NewEventBus(api.eventRepository)
+	api.webhookService = services.NewWebhookService(api.webhookRepository, services.WebhookPolicy{
+		MaxAttempts:          api.config.WebhookMaxAttempts,
+		RetryBase:            api.config.WebhookRetryBase,
+		RetryMax:             api.config.WebhookRetryMax,
+		Timeout:              api.config.WebhookTimeout,
+		AllowPrivateNetworks: api.config.WebhookAllowPrivateNetworks,
+	})
+	api.eventBus.AddListener(api.webhookService.HandleEvent)
+	api.withdrawService = services.NewWithdrawService(
+		api.withdrawRepository,
+		api.userRepository,
+		mutex,
+		api.twoFactorService,
+		api.eventBus,
+		api.config.WithdrawTOTPThreshold,
+	)
 	api.orderService = services.NewOrderService(
 		api.orderRepository,
 		channel,
 	)
+	api.adminService = services.NewAdminService(api.userRepository, api.orderRepository, api.withdrawRepository)
+	api.accountService = services.NewAccountService(api.userRepository, api.orderRepository, api.withdrawRepository)
+	api.auditService = services.NewAuditService(api.auditRepository)
+	api.partnerService = services.NewPartnerService(
+		api.partnerRepository,
+		api.userRepository,
+		api.config.PartnerKeyRateLimit,
+	)
+	if api.config.OIDCIssuer != "" {
+		oidcService, err := services.NewOIDCService(
+			context.Background(),
+			services.OIDCConfig{
+				Issuer:       api.config.OIDCIssuer,
+				ClientID:     api.config.OIDCClientID,
+				ClientSecret: api.config.OIDCClientSecret,
+				RedirectURL:  api.config.OIDCRedirectURL,
+				StateTTL:     api.config.OIDCStateTTL,
+			},
+			api.oidcRepository,
+			api.userRepository,
+		)
+		if err != nil {
+			return err
+		}
+		api.oidcService = oidcService
+	}
+	notifier, err := api.configNotifier()
+	if err != nil {
+		return err
+	}
+	api.resetService = services.NewPasswordResetService(
+		api.userRepository,
+		api.resetRepository,
+		notifier,
+		loginGuard,
+		policy,
+		api.config.PasswordResetTTL,
+	)
+	return nil
+}
+
+func (api *API) configNotifier() (services.Notifier, error) {
+	switch api.config.Notifier {
+	case "log":
+		return notifier.NewLogNotifier(), nil
+	case "file":
+		return notifier.NewFileNotifier(api.config.NotifierFile), nil
+	case "smtp":
+		return notifier.NewSMTPNotifier(
+			api.config.SMTPAddress,
+			api.config.SMTPUsername,
+			api.config.SMTPPassword,
+			api.config.SMTPFrom,
+		)
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", api.config.Notifier)
+	}
 }
 
-func (api *API) configWorkers(db *gorm.DB, channel chan entities.Order, mutex *sync.Mutex) {
-	go daemons.WorkerProcessingOrders(channel, api.config.AccrualSystemAddress, db, api.config.WorkerPoolSize, mutex)
+func (api *API) configWorkers(db *gorm.DB, channel chan dto.OrderTask, mutex *sync.Mutex) {
+	go daemons.WorkerProcessingOrders(channel, api.config.AccrualSystemAddress, db, api.config.WorkerPoolSize, mutex,
+		api.eventBus)
+	go daemons.WorkerDeliveringWebhooks(api.webhookService, api.config.WebhookPollInterval)
 }
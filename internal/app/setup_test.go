@@ -0,0 +1,145 @@
+package app
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/keyjin88/go-loyalty-system/internal/app/config"
+	"github.com/keyjin88/go-loyalty-system/internal/app/handlers"
+	"github.com/keyjin88/go-loyalty-system/internal/app/health"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services"
+	"github.com/keyjin88/go-loyalty-system/internal/app/services/mocks"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRouterMatchesOpenAPI проверяет, что каждый маршрут роутера описан в документе OpenAPI и наоборот
+func TestRouterMatchesOpenAPI(t *testing.T) {
+	api := &API{
+		config:      &config.Config{},
+		oidcService: &services.OIDCService{},
+	}
+	if err := api.configAPIDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.configureRouter(); err != nil {
+		t.Fatal(err)
+	}
+
+	routes := make(map[string]bool)
+	for _, route := range api.router.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		routes[route.Method+" "+path] = true
+		pathItem := api.apiDocument.Paths.Find(path)
+		if pathItem == nil || pathItem.GetOperation(route.Method) == nil {
+			t.Errorf("route %s %s is not described in the OpenAPI document", route.Method, path)
+		}
+	}
+	for path, pathItem := range api.apiDocument.Paths {
+		for method := range pathItem.Operations() {
+			if !routes[method+" "+path] {
+				t.Errorf("operation %s %s has no route", method, path)
+			}
+		}
+	}
+}
+
+// TestLoginThrottleIgnoresForgedForwardedFor проверяет, что подменой X-Forwarded-For нельзя
+// получить новый счётчик неудачных входов по IP
+func TestLoginThrottleIgnoresForgedForwardedFor(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mocks.NewMockUserRepository(ctrl)
+	throttles := mocks.NewMockLoginThrottleRepository(ctrl)
+	audit := mocks.NewMockAuditRepository(ctrl)
+	policy := services.LoginPolicy{FreeAttempts: 10, MaxFailures: 100, BaseDelay: time.Second, Lockout: time.Minute}
+	userService := services.NewUserService(users, services.NewLoginGuard(throttles, policy, policy), services.CredentialsPolicy{})
+	api := &API{
+		config:       &config.Config{},
+		auditService: services.NewAuditService(audit),
+	}
+	api.handlers = handlers.NewHandler(userService, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, handlers.SessionCookie{}, 0)
+	if err := api.configAPIDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.configureRouter(); err != nil {
+		t.Fatal(err)
+	}
+
+	// обе попытки должны попасть в счётчик реального адреса клиента
+	users.EXPECT().FindUserByUserName(gomock.Any(), "alice").Return(entities.User{}, gorm.ErrRecordNotFound).Times(2)
+	throttles.EXPECT().FindLoginThrottles(gomock.Any(), []string{"login:alice", "ip:192.0.2.1"}).Return(nil, nil).Times(2)
+	throttles.EXPECT().IncrementLoginFailures(gomock.Any(), "login:alice", gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+	throttles.EXPECT().IncrementLoginFailures(gomock.Any(), "ip:192.0.2.1", gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+	audit.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	for _, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+		request := httptest.NewRequest(http.MethodPost, "/api/user/login",
+			strings.NewReader(`{"login":"alice","password":"password"}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Forwarded-For", forwarded)
+		request.RemoteAddr = "192.0.2.1:40000"
+		recorder := httptest.NewRecorder()
+		api.router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: "10.0.0.1", want: []string{"10.0.0.1"}},
+		{value: " 10.0.0.1, 192.168.0.0/16 ,", want: []string{"10.0.0.1", "192.168.0.0/16"}},
+	}
+	for _, tt := range tests {
+		if got := trustedProxies(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("trustedProxies(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestRouterServesProbes проверяет, что пробы доступны и на основном адресе
+func TestRouterServesProbes(t *testing.T) {
+	api := &API{
+		config: &config.Config{},
+		health: health.NewChecker(time.Second),
+	}
+	if err := api.configAPIDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.configureRouter(); err != nil {
+		t.Fatal(err)
+	}
+	probe := func(path string) int {
+		recorder := httptest.NewRecorder()
+		api.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if status := probe("/healthz"); status != http.StatusOK {
+		t.Errorf("expected live, got %d", status)
+	}
+	if status := probe("/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready before start, got %d", status)
+	}
+	api.health.Started()
+	if status := probe("/readyz"); status != http.StatusOK {
+		t.Errorf("expected ready after start, got %d", status)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	err := db.AutoMigrate(&entities.AuditEvent{})
+	if err != nil {
+		log.Fatal("failed to migrate audit events table")
+	}
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (r *AuditRepository) Save(ctx context.Context, event *entities.AuditEvent) error {
+	err := r.db.WithContext(ctx).Create(&event).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindEvents ищет события журнала по фильтрам, от новых к старым
+func (r *AuditRepository) FindEvents(ctx context.Context, filter dto.AuditEventFilter) ([]entities.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Model(&entities.AuditEvent{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Before != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.Before.CreatedAt, filter.Before.ID)
+	}
+	var events []entities.AuditEvent
+	result := query.Order("created_at DESC").Order("id DESC").Limit(filter.Limit).Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return events, nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+)
+
+type EventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) *EventRepository {
+	err := db.AutoMigrate(&entities.UserEvent{})
+	if err != nil {
+		log.Fatal("failed to migrate user events table")
+	}
+	return &EventRepository{
+		db: db,
+	}
+}
+
+func (r *EventRepository) SaveEvent(ctx context.Context, event *entities.UserEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// FindEventsAfter события пользователя с идентификатором больше afterID в порядке возникновения
+func (r *EventRepository) FindEventsAfter(ctx context.Context, userID uint, afterID uint, limit int) ([]entities.UserEvent, error) {
+	var events []entities.UserEvent
+	result := r.db.WithContext(ctx).Where("user_id = ? AND id > ?", userID, afterID).Order("id").Limit(limit).Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return events, nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	err := db.AutoMigrate(&entities.LoginThrottle{})
+	if err != nil {
+		log.Fatal("failed to migrate login throttles table")
+	}
+	return &LoginThrottleRepository{
+		db: db,
+	}
+}
+
+func (r *LoginThrottleRepository) FindLoginThrottles(ctx context.Context, keys []string) ([]entities.LoginThrottle, error) {
+	var throttles []entities.LoginThrottle
+	tx := r.db.WithContext(ctx).Where("throttle_key IN ?", keys).Find(&throttles)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return throttles, nil
+}
+
+// IncrementLoginFailures атомарно увеличивает счётчик неудач и возвращает новое значение.
+// Если последняя неудача была раньше resetBefore, счёт начинается заново.
+func (r *LoginThrottleRepository) IncrementLoginFailures(ctx context.Context, key string, now time.Time, resetBefore time.Time) (int, error) {
+	throttle := entities.LoginThrottle{
+		ThrottleKey:   key,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+	tx := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "throttle_key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"failures": gorm.Expr(
+					"CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END",
+					resetBefore,
+				),
+				"last_failure_at": now,
+				"updated_at":      now,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+	).Create(&throttle)
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	return throttle.Failures, nil
+}
+
+func (r *LoginThrottleRepository) BlockLogin(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&entities.LoginThrottle{}).
+		Where("throttle_key = ?", key).
+		Update("blocked_until", until).Error
+}
+
+func (r *LoginThrottleRepository) ResetLoginFailures(ctx context.Context, keys []string) error {
+	return r.db.WithContext(ctx).Model(&entities.LoginThrottle{}).
+		Where("throttle_key IN ?", keys).
+		Updates(map[string]any{"failures": 0, "blocked_until": nil}).Error
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// migrationsTable таблица, в которой утилита migrate хранит версию схемы из database/migrations
+const migrationsTable = "schema_migrations"
+
+// MigrationVersion версия схемы, применённая утилитой migrate, и признак незавершённой миграции.
+// Если migrate не запускалась, возвращает нулевую версию: таблицы тогда создаёт AutoMigrate.
+func MigrationVersion(ctx context.Context, db *gorm.DB) (uint, bool, error) {
+	db = db.WithContext(ctx)
+	if !db.Migrator().HasTable(migrationsTable) {
+		return 0, false, nil
+	}
+	var migration struct {
+		Version uint
+		Dirty   bool
+	}
+	err := db.Table(migrationsTable).Select("version, dirty").Limit(1).Scan(&migration).Error
+	if err != nil {
+		return 0, false, err
+	}
+	return migration.Version, migration.Dirty, nil
+}
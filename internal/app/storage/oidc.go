@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type OIDCRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCRepository(db *gorm.DB) *OIDCRepository {
+	err := db.AutoMigrate(&entities.ExternalIdentity{}, &entities.OIDCAuthRequest{})
+	if err != nil {
+		log.Fatal("failed to migrate OIDC tables")
+	}
+	return &OIDCRepository{
+		db: db,
+	}
+}
+
+func (r *OIDCRepository) SaveAuthRequest(ctx context.Context, request *entities.OIDCAuthRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *OIDCRepository) FindAuthRequestByStateHash(ctx context.Context, stateHash string) (entities.OIDCAuthRequest, error) {
+	var request entities.OIDCAuthRequest
+	tx := r.db.WithContext(ctx).First(&request, "state_hash = ?", stateHash)
+	if tx.Error != nil {
+		return entities.OIDCAuthRequest{}, tx.Error
+	}
+	return request, nil
+}
+
+// MarkAuthRequestUsed помечает вход использованным. Возвращает false, если он уже был использован,
+// так один state нельзя предъявить дважды.
+func (r *OIDCRepository) MarkAuthRequestUsed(ctx context.Context, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.OIDCAuthRequest{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *OIDCRepository) FindIdentity(ctx context.Context, issuer string, subject string) (entities.ExternalIdentity, error) {
+	var identity entities.ExternalIdentity
+	tx := r.db.WithContext(ctx).First(&identity, "issuer = ? AND subject = ?", issuer, subject)
+	if tx.Error != nil {
+		return entities.ExternalIdentity{}, tx.Error
+	}
+	return identity, nil
+}
+
+func (r *OIDCRepository) SaveIdentity(ctx context.Context, identity *entities.ExternalIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// CreateUserWithIdentity заводит пользователя для внешней учётной записи и привязывает её одной транзакцией
+func (r *OIDCRepository) CreateUserWithIdentity(ctx context.Context, user *entities.User, identity *entities.ExternalIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
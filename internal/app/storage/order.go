@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"gorm.io/gorm"
 	"log"
@@ -11,37 +13,91 @@ type OrderRepository struct {
 }
 
 func NewOrderRepository(db *gorm.DB) *OrderRepository {
-	err := db.AutoMigrate(&entities.Order{})
+	err := db.AutoMigrate(&entities.Order{}, &entities.OrderAttempt{})
 	if err != nil {
 		log.Fatal("failed to migrate orders table")
 	}
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_user_created_at ON orders (user_id, created_at, id)").Error
+	if err != nil {
+		log.Fatal("failed to create orders pagination index")
+	}
 	return &OrderRepository{
 		db: db,
 	}
 }
 
-func (r *OrderRepository) Save(order *entities.Order) error {
-	err := r.db.Create(&order).Error
+func (r *OrderRepository) Save(ctx context.Context, order *entities.Order) error {
+	err := r.db.WithContext(ctx).Create(&order).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *OrderRepository) GetOrderByNumber(number string) (entities.Order, error) {
+// GetOrderByNumber ищет заказ и среди удалённых: номер заказа остаётся занятым и после удаления пользователя
+func (r *OrderRepository) GetOrderByNumber(ctx context.Context, number string) (entities.Order, error) {
 	var saverOrder entities.Order
-	tx := r.db.First(&saverOrder, "number = ?", number)
+	tx := r.db.WithContext(ctx).First(&saverOrder, "number = ?", number)
 	if tx.Error != nil {
 		return entities.Order{}, tx.Error
 	}
 	return saverOrder, nil
 }
 
-func (r *OrderRepository) GetAllOrders(userID uint) ([]entities.Order, error) {
+func (r *OrderRepository) GetAllOrders(ctx context.Context, userID uint) ([]entities.Order, error) {
+	var orders []entities.Order
+	result := r.db.WithContext(ctx).Scopes(notDeleted).Where("user_id = ?", userID).Find(&orders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+func (r *OrderRepository) GetOrdersPage(ctx context.Context, filter dto.PageFilter) ([]entities.Order, error) {
 	var orders []entities.Order
-	result := r.db.Where("user_id = ?", userID).Find(&orders)
+	result := applyPageFilter(r.db.WithContext(ctx).Scopes(notDeleted), filter, "accrual").Find(&orders)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return orders, nil
 }
+
+func (r *OrderRepository) GetOrderAttempts(ctx context.Context, orderID uint) ([]entities.OrderAttempt, error) {
+	var attempts []entities.OrderAttempt
+	result := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at").Order("id").Find(&attempts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return attempts, nil
+}
+
+// GetOrdersByNumbers как и GetOrderByNumber, не исключает удалённые заказы
+func (r *OrderRepository) GetOrdersByNumbers(ctx context.Context, numbers []string) ([]entities.Order, error) {
+	var orders []entities.Order
+	result := r.db.WithContext(ctx).Where("number IN ?", numbers).Find(&orders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+// SaveAll сохраняет заказы в одной транзакции: либо все, либо ни одного
+func (r *OrderRepository) SaveAll(ctx context.Context, orders []entities.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&orders, 100).Error
+	})
+}
+
+// GetUserOrderStats количество заказов пользователя и сумма начислений по обработанным
+func (r *OrderRepository) GetUserOrderStats(ctx context.Context, userID uint) (dto.Stats, error) {
+	var stats dto.Stats
+	result := r.db.WithContext(ctx).Model(&entities.Order{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(CASE WHEN status = 'PROCESSED' THEN accrual ELSE 0 END), 0) AS sum").
+		Scopes(notDeleted).
+		Where("user_id = ?", userID).
+		Scan(&stats)
+	if result.Error != nil {
+		return dto.Stats{}, result.Error
+	}
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"gorm.io/gorm"
+)
+
+// applyPageFilter добавляет к запросу фильтры, keyset-пагинацию по (created_at, id) и сортировку.
+// amountColumn - колонка, по которой фильтруется диапазон сумм.
+func applyPageFilter(query *gorm.DB, filter dto.PageFilter, amountColumn string) *gorm.DB {
+	query = query.Where("user_id = ?", filter.UserID)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where(amountColumn+" >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where(amountColumn+" <= ?", *filter.MaxAmount)
+	}
+	direction := "ASC"
+	comparison := ">"
+	if filter.Descending {
+		direction = "DESC"
+		comparison = "<"
+	}
+	if filter.After != nil {
+		query = query.Where("(created_at, id) "+comparison+" (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+	return query.
+		Order("created_at " + direction).
+		Order("id " + direction).
+		Limit(filter.Limit)
+}
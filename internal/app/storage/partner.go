@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+type PartnerRepository struct {
+	db *gorm.DB
+}
+
+func NewPartnerRepository(db *gorm.DB) *PartnerRepository {
+	err := db.AutoMigrate(&entities.Partner{}, &entities.APIKey{}, &entities.PartnerUser{})
+	if err != nil {
+		log.Fatal("failed to migrate partner tables")
+	}
+	return &PartnerRepository{
+		db: db,
+	}
+}
+
+func (r *PartnerRepository) SavePartner(ctx context.Context, partner *entities.Partner) error {
+	return r.db.WithContext(ctx).Create(&partner).Error
+}
+
+func (r *PartnerRepository) FindPartnerByID(ctx context.Context, partnerID uint) (entities.Partner, error) {
+	var partner entities.Partner
+	tx := r.db.WithContext(ctx).First(&partner, "id = ?", partnerID)
+	if tx.Error != nil {
+		return entities.Partner{}, tx.Error
+	}
+	return partner, nil
+}
+
+func (r *PartnerRepository) SaveAPIKey(ctx context.Context, key *entities.APIKey) error {
+	return r.db.WithContext(ctx).Create(&key).Error
+}
+
+func (r *PartnerRepository) FindAPIKeyByPrefix(ctx context.Context, prefix string) (entities.APIKey, error) {
+	var key entities.APIKey
+	tx := r.db.WithContext(ctx).First(&key, "prefix = ?", prefix)
+	if tx.Error != nil {
+		return entities.APIKey{}, tx.Error
+	}
+	return key, nil
+}
+
+// RevokeAPIKey отзывает ключ партнёра. Возвращает false, если ключа нет или он уже отозван.
+func (r *PartnerRepository) RevokeAPIKey(ctx context.Context, partnerID uint, keyID uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.APIKey{}).
+		Where("id = ? AND partner_id = ? AND revoked_at IS NULL", keyID, partnerID).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *PartnerRepository) TouchAPIKey(ctx context.Context, keyID uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entities.APIKey{}).
+		Where("id = ?", keyID).
+		Update("last_used_at", usedAt).Error
+}
+
+// LinkPartnerUser связывает клиента партнёра с пользователем, заменяя прежнюю связь
+func (r *PartnerRepository) LinkPartnerUser(ctx context.Context, link *entities.PartnerUser) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "partner_id"}, {Name: "external_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "updated_at"}),
+	}).Create(&link).Error
+}
+
+func (r *PartnerRepository) FindPartnerUser(ctx context.Context, partnerID uint, externalID string) (entities.PartnerUser, error) {
+	var link entities.PartnerUser
+	tx := r.db.WithContext(ctx).First(&link, "partner_id = ? AND external_id = ?", partnerID, externalID)
+	if tx.Error != nil {
+		return entities.PartnerUser{}, tx.Error
+	}
+	return link, nil
+}
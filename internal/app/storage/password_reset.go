@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	err := db.AutoMigrate(&entities.PasswordResetToken{})
+	if err != nil {
+		log.Fatal("failed to migrate password reset tokens table")
+	}
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+func (r *PasswordResetRepository) SaveResetToken(ctx context.Context, token *entities.PasswordResetToken) error {
+	err := r.db.WithContext(ctx).Create(&token).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *PasswordResetRepository) FindResetTokenByHash(ctx context.Context, tokenHash string) (entities.PasswordResetToken, error) {
+	var token entities.PasswordResetToken
+	tx := r.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash)
+	if tx.Error != nil {
+		return entities.PasswordResetToken{}, tx.Error
+	}
+	return token, nil
+}
+
+// MarkResetTokenUsed помечает токен использованным. Возвращает false, если его уже использовали.
+func (r *PasswordResetRepository) MarkResetTokenUsed(ctx context.Context, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
@@ -0,0 +1,8 @@
+package storage
+
+import "gorm.io/gorm"
+
+// notDeleted исключает записи, помеченные удалёнными (IsDeleted)
+func notDeleted(db *gorm.DB) *gorm.DB {
+	return db.Where("is_deleted = ?", false)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	err := db.AutoMigrate(&entities.RefreshToken{}, &entities.RevokedToken{}, &entities.Session{})
+	if err != nil {
+		log.Fatal("failed to migrate token tables")
+	}
+	return &TokenRepository{
+		db: db,
+	}
+}
+
+func (r *TokenRepository) SaveRefreshToken(ctx context.Context, token *entities.RefreshToken) error {
+	err := r.db.WithContext(ctx).Create(&token).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *TokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
+	var token entities.RefreshToken
+	tx := r.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash)
+	if tx.Error != nil {
+		return entities.RefreshToken{}, tx.Error
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenUsed помечает токен использованным. Возвращает false, если токен уже был использован,
+// так два параллельных обновления одним токеном не получат по новой паре.
+func (r *TokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *TokenRepository) SaveSession(ctx context.Context, session *entities.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *TokenRepository) FindSessionByFamily(ctx context.Context, familyID string) (entities.Session, error) {
+	var session entities.Session
+	tx := r.db.WithContext(ctx).First(&session, "family_id = ?", familyID)
+	if tx.Error != nil {
+		return entities.Session{}, tx.Error
+	}
+	return session, nil
+}
+
+func (r *TokenRepository) FindUserSession(ctx context.Context, userID uint, id uint) (entities.Session, error) {
+	var session entities.Session
+	tx := r.db.WithContext(ctx).First(&session, "id = ? AND user_id = ?", id, userID)
+	if tx.Error != nil {
+		return entities.Session{}, tx.Error
+	}
+	return session, nil
+}
+
+// FindActiveSessions неотозванные и неистёкшие сессии пользователя, последние активные первыми
+func (r *TokenRepository) FindActiveSessions(ctx context.Context, userID uint, now time.Time) ([]entities.Session, error) {
+	var sessions []entities.Session
+	tx := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at desc").
+		Find(&sessions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return sessions, nil
+}
+
+func (r *TokenRepository) TouchSession(ctx context.Context, familyID string, lastSeenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("family_id = ?", familyID).
+		Update("last_seen_at", lastSeenAt).Error
+}
+
+// ExtendSession продлевает сессию при ротации refresh-токена
+func (r *TokenRepository) ExtendSession(ctx context.Context, familyID string, ip string, lastSeenAt time.Time, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("family_id = ?", familyID).
+		Updates(map[string]any{"ip": ip, "last_seen_at": lastSeenAt, "expires_at": expiresAt}).Error
+}
+
+// RevokeFamily завершает сессию: отзывает все refresh-токены цепочки и выданные вместе с ними access-токены
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revoke(ctx, "family_id = ?", familyID)
+}
+
+// RevokeUserTokens завершает все сессии пользователя вместе с их refresh- и access-токенами
+func (r *TokenRepository) RevokeUserTokens(ctx context.Context, userID uint) error {
+	return r.revoke(ctx, "user_id = ?", userID)
+}
+
+func (r *TokenRepository) revoke(ctx context.Context, condition string, value any) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeTokens(tx, condition, value)
+	})
+}
+
+// revokeTokens отзывает refresh- и access-токены и сессии в уже открытой транзакции tx,
+// чтобы отзыв мог быть частью другой операции, например удаления пользователя
+func revokeTokens(tx *gorm.DB, condition string, value any) error {
+	var tokens []entities.RefreshToken
+	err := tx.Where(condition, value).
+		Where("revoked_at IS NULL AND access_expires_at > ?", time.Now()).
+		Find(&tokens).Error
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := revokeJTI(tx, token.AccessJTI, token.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	err = tx.Model(&entities.RefreshToken{}).
+		Where(condition, value).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&entities.Session{}).
+		Where(condition, value).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *TokenRepository) RevokeJTI(ctx context.Context, jti string, expiresAt time.Time) error {
+	return revokeJTI(r.db.WithContext(ctx), jti, expiresAt)
+}
+
+func (r *TokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	tx := r.db.WithContext(ctx).Model(&entities.RevokedToken{}).
+		Where("jti = ? AND expires_at > ?", jti, time.Now()).
+		Count(&count)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return count > 0, nil
+}
+
+func revokeJTI(db *gorm.DB, jti string, expiresAt time.Time) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entities.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
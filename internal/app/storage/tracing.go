@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"github.com/keyjin88/go-loyalty-system/internal/app/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey ключ, под которым спан запроса хранится в экземпляре gorm.DB между колбэками
+const spanKey = "tracing:span"
+
+// TracingPlugin открывает клиентский спан на каждый запрос gorm. Спан становится дочерним
+// к спану из контекста, переданного репозиторию через WithContext.
+type TracingPlugin struct{}
+
+func (TracingPlugin) Name() string {
+	return "tracing"
+}
+
+// registrar колбэк gorm, встающий до или после стандартной обработки запроса
+type registrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func (TracingPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	operations := []struct {
+		name   string
+		before registrar
+		after  registrar
+	}{
+		{"create", callback.Create().Before("gorm:create"), callback.Create().After("gorm:create")},
+		{"query", callback.Query().Before("gorm:query"), callback.Query().After("gorm:query")},
+		{"update", callback.Update().Before("gorm:update"), callback.Update().After("gorm:update")},
+		{"delete", callback.Delete().Before("gorm:delete"), callback.Delete().After("gorm:delete")},
+		{"row", callback.Row().Before("gorm:row"), callback.Row().After("gorm:row")},
+		{"raw", callback.Raw().Before("gorm:raw"), callback.Raw().After("gorm:raw")},
+	}
+	for _, operation := range operations {
+		if err := operation.before.Register("tracing:before_"+operation.name, startSpan(operation.name)); err != nil {
+			return err
+		}
+		if err := operation.after.Register("tracing:after_"+operation.name, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := tracing.Tracer().Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		semconv.DBSQLTable(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	// Отсутствие записи для репозиториев обычный ответ, а не сбой запроса
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	err := db.AutoMigrate(&entities.RecoveryCode{}, &entities.LoginChallenge{})
+	if err != nil {
+		log.Fatal("failed to migrate two-factor tables")
+	}
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+// UpdateTOTP меняет секрет и признак подключения второго фактора.
+// Счётчик шагов не сбрасывается: шаги растут со временем и для нового секрета.
+func (r *TwoFactorRepository) UpdateTOTP(ctx context.Context, userID uint, secret string, enabled bool) error {
+	return r.db.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]any{"totp_secret": secret, "totp_enabled": enabled}).Error
+}
+
+// AdvanceTOTPStep запоминает шаг принятого кода. Возвращает false, если код этого
+// или более позднего шага уже был принят.
+func (r *TwoFactorRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes удаляет прежние коды восстановления пользователя и сохраняет новые
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []entities.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *TwoFactorRepository) FindUnusedRecoveryCodes(ctx context.Context, userID uint) ([]entities.RecoveryCode, error) {
+	var codes []entities.RecoveryCode
+	tx := r.db.WithContext(ctx).Where("user_id = ? AND used_at IS NULL", userID).Find(&codes)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return codes, nil
+}
+
+func (r *TwoFactorRepository) MarkRecoveryCodeUsed(ctx context.Context, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *TwoFactorRepository) SaveChallenge(ctx context.Context, challenge *entities.LoginChallenge) error {
+	err := r.db.WithContext(ctx).Create(&challenge).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) FindChallengeByHash(ctx context.Context, tokenHash string) (entities.LoginChallenge, error) {
+	var challenge entities.LoginChallenge
+	tx := r.db.WithContext(ctx).First(&challenge, "token_hash = ?", tokenHash)
+	if tx.Error != nil {
+		return entities.LoginChallenge{}, tx.Error
+	}
+	return challenge, nil
+}
+
+// IncrementChallengeAttempts учитывает неверный код. Возвращает false, если попытки уже исчерпаны.
+func (r *TwoFactorRepository) IncrementChallengeAttempts(ctx context.Context, id uint, maxAttempts int) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.LoginChallenge{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *TwoFactorRepository) MarkChallengeUsed(ctx context.Context, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"gorm.io/gorm"
 	"log"
+	"strings"
 )
 
 type UserRepository struct {
@@ -20,36 +23,114 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
-func (r *UserRepository) Save(user *entities.User) error {
-	err := r.db.Create(&user).Error
+func (r *UserRepository) Save(ctx context.Context, user *entities.User) error {
+	err := r.db.WithContext(ctx).Create(&user).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *UserRepository) Update(user *entities.User) error {
-	err := r.db.Updates(&user).Error
+func (r *UserRepository) Update(ctx context.Context, user *entities.User) error {
+	err := r.db.WithContext(ctx).Updates(&user).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *UserRepository) FindUserByID(userID uint) (entities.User, error) {
+func (r *UserRepository) FindUserByID(ctx context.Context, userID uint) (entities.User, error) {
 	var savedUser entities.User
-	tx := r.db.First(&savedUser, "id = ?", userID)
+	tx := r.db.WithContext(ctx).Scopes(notDeleted).First(&savedUser, "id = ?", userID)
 	if tx.Error != nil {
 		return entities.User{}, tx.Error
 	}
 	return savedUser, nil
 }
 
-func (r *UserRepository) FindUserByUserName(userName string) (entities.User, error) {
+func (r *UserRepository) FindUserByUserName(ctx context.Context, userName string) (entities.User, error) {
 	var savedUser entities.User
-	tx := r.db.First(&savedUser, "user_name = ?", userName)
+	tx := r.db.WithContext(ctx).Scopes(notDeleted).First(&savedUser, "user_name = ?", userName)
 	if tx.Error != nil {
 		return entities.User{}, tx.Error
 	}
 	return savedUser, nil
 }
+
+// SearchUsers ищет пользователей по подстроке логина, постранично в порядке регистрации
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, after *dto.PageCursor, limit int) ([]entities.User, error) {
+	var users []entities.User
+	tx := r.db.WithContext(ctx).Model(&entities.User{}).Scopes(notDeleted)
+	if query != "" {
+		tx = tx.Where("user_name ILIKE ?", "%"+escapeLike(query)+"%")
+	}
+	if after != nil {
+		tx = tx.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+	result := tx.Order("created_at").Order("id").Limit(limit).Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return users, nil
+}
+
+// SoftDeleteUser помечает пользователя удалённым вместе с его заказами и списаниями.
+// Логин заменяется на userName, контактные данные и секреты стираются, привязки внешних
+// учётных записей и клиентов партнёров, коды сброса пароля и восстановления удаляются,
+// чтобы по ним нельзя было войти или действовать от имени пользователя, все сессии и токены отзываются.
+// Возвращает false, если пользователь уже удалён.
+func (r *UserRepository) SoftDeleteUser(ctx context.Context, userID uint, userName string) (bool, error) {
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.User{}).
+			Where("id = ? AND is_deleted = ?", userID, false).
+			Updates(map[string]any{
+				"is_deleted":   true,
+				"user_name":    userName,
+				"password":     "",
+				"email":        "",
+				"totp_secret":  "",
+				"totp_enabled": false,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		if err := tx.Model(&entities.Order{}).Where("user_id = ?", userID).Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entities.Withdraw{}).Where("user_id = ?", userID).Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&entities.UserEvent{}).Error; err != nil {
+			return err
+		}
+		// Привязка к клиенту партнёра удаляется вместе с остальными: иначе партнёр продолжил бы
+		// загружать заказы удалённого пользователя и получать о нём webhook
+		for _, model := range []any{
+			&entities.ExternalIdentity{},
+			&entities.PartnerUser{},
+			&entities.PasswordResetToken{},
+			&entities.RecoveryCode{},
+			&entities.LoginChallenge{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("link_user_id = ?", userID).Delete(&entities.OIDCAuthRequest{}).Error; err != nil {
+			return err
+		}
+		// Сессии завершаются в той же транзакции: удалённый пользователь не должен остаться
+		// с действующими токенами, если отзыв не удался
+		return revokeTokens(tx, "user_id = ?", userID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	err := db.AutoMigrate(&entities.WebhookSubscription{}, &entities.WebhookDelivery{})
+	if err != nil {
+		log.Fatal("failed to migrate webhook tables")
+	}
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) SaveSubscription(ctx context.Context, subscription *entities.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(&subscription).Error
+}
+
+func (r *WebhookRepository) FindSubscriptions(ctx context.Context, partnerID uint) ([]entities.WebhookSubscription, error) {
+	var subscriptions []entities.WebhookSubscription
+	result := r.db.WithContext(ctx).Where("partner_id = ?", partnerID).Order("id").Find(&subscriptions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) FindSubscriptionByID(ctx context.Context, id uint) (entities.WebhookSubscription, error) {
+	var subscription entities.WebhookSubscription
+	tx := r.db.WithContext(ctx).First(&subscription, "id = ?", id)
+	if tx.Error != nil {
+		return entities.WebhookSubscription{}, tx.Error
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription удаляет подписку партнёра. Возвращает false, если подписки нет.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, partnerID uint, id uint) (bool, error) {
+	tx := r.db.WithContext(ctx).Where("id = ? AND partner_id = ?", id, partnerID).Delete(&entities.WebhookSubscription{})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+// FindSubscriptionTargets подписки партнёров, с клиентами которых связан пользователь
+func (r *WebhookRepository) FindSubscriptionTargets(ctx context.Context, userID uint) ([]dto.WebhookTarget, error) {
+	var targets []dto.WebhookTarget
+	result := r.db.WithContext(ctx).Model(&entities.WebhookSubscription{}).
+		Select("webhook_subscriptions.id AS subscription_id, webhook_subscriptions.partner_id, "+
+			"webhook_subscriptions.event_types, partner_users.external_id").
+		Joins("JOIN partner_users ON partner_users.partner_id = webhook_subscriptions.partner_id "+
+			"AND partner_users.deleted_at IS NULL").
+		Where("partner_users.user_id = ?", userID).
+		Scan(&targets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return targets, nil
+}
+
+func (r *WebhookRepository) SaveDeliveries(ctx context.Context, deliveries []entities.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+// FindDueDeliveries ожидающие доставки, время попытки которых уже наступило
+func (r *WebhookRepository) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entities.WebhookDelivery, error) {
+	var deliveries []entities.WebhookDelivery
+	result := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", entities.DeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery берёт доставку в работу, сдвигая следующую попытку на leaseUntil.
+// Возвращает false, если доставку уже взял другой обработчик.
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id uint, dueAt time.Time, leaseUntil time.Time) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&entities.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", id, entities.DeliveryPending, dueAt).
+		Update("next_attempt_at", leaseUntil)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "error", "delivered_at",
+	).Updates(delivery).Error
+}
+
+// FindDeliveries журнал доставок по подписке, от новых к старым
+func (r *WebhookRepository) FindDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilter) ([]entities.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Where("partner_id = ? AND subscription_id = ?", filter.PartnerID, filter.SubscriptionID)
+	if filter.Before != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.Before.CreatedAt, filter.Before.ID)
+	}
+	var deliveries []entities.WebhookDelivery
+	result := query.Order("created_at DESC").Order("id DESC").Limit(filter.Limit).Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
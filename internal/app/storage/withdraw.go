@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/model/dto"
 	"github.com/keyjin88/go-loyalty-system/internal/app/model/entities"
 	"gorm.io/gorm"
 	"log"
@@ -15,24 +17,50 @@ func NewWithdrawRepository(db *gorm.DB) *WithdrawRepository {
 	if err != nil {
 		log.Fatal("failed to migrate withdraw table")
 	}
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_withdraws_user_created_at ON withdraws (user_id, created_at, id)").Error
+	if err != nil {
+		log.Fatal("failed to create withdraws pagination index")
+	}
 	return &WithdrawRepository{
 		db: db,
 	}
 }
 
-func (r *WithdrawRepository) Save(withdraw *entities.Withdraw) error {
-	err := r.db.Create(&withdraw).Error
+func (r *WithdrawRepository) Save(ctx context.Context, withdraw *entities.Withdraw) error {
+	err := r.db.WithContext(ctx).Create(&withdraw).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *WithdrawRepository) GetWithdrawals(userID uint) ([]entities.Withdraw, error) {
+func (r *WithdrawRepository) GetWithdrawals(ctx context.Context, userID uint) ([]entities.Withdraw, error) {
 	var withdraws []entities.Withdraw
-	result := r.db.Where("user_id = ?", userID).Find(&withdraws)
+	result := r.db.WithContext(ctx).Scopes(notDeleted).Where("user_id = ?", userID).Find(&withdraws)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return withdraws, nil
 }
+
+func (r *WithdrawRepository) GetWithdrawalsPage(ctx context.Context, filter dto.PageFilter) ([]entities.Withdraw, error) {
+	var withdraws []entities.Withdraw
+	result := applyPageFilter(r.db.WithContext(ctx).Scopes(notDeleted), filter, "sum").Find(&withdraws)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return withdraws, nil
+}
+
+func (r *WithdrawRepository) GetUserWithdrawStats(ctx context.Context, userID uint) (dto.Stats, error) {
+	var stats dto.Stats
+	result := r.db.WithContext(ctx).Model(&entities.Withdraw{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(sum), 0) AS sum").
+		Scopes(notDeleted).
+		Where("user_id = ?", userID).
+		Scan(&stats)
+	if result.Error != nil {
+		return dto.Stats{}, result.Error
+	}
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые векторы SHA1 из приложения B RFC 6238, последние шесть цифр
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+	for _, tt := range tests {
+		code, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	previous, _ := Code(secret, Step(now)-1)
+	if step, ok := Validate(secret, previous, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("code from the previous step must be accepted with skew 1")
+	}
+	old, _ := Code(secret, Step(now)-3)
+	if _, ok := Validate(secret, old, now, 1); ok {
+		t.Errorf("code outside the skew window must be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Errorf("code of the wrong length must be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Gophermart", "alice", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/Gophermart:alice?") || !strings.Contains(uri, "secret=SECRET") {
+		t.Errorf("unexpected URI: %s", uri)
+	}
+}
@@ -0,0 +1,122 @@
+// Package tracing настраивает трассировку OpenTelemetry: провайдер, экспортёр и распространение
+// контекста трассы. Спаны создаются в middleware, сервисах, хранилище и обработчике заказов.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"os"
+)
+
+// instrumentationName имя, под которым приложение создаёт свои спаны
+const instrumentationName = "github.com/keyjin88/go-loyalty-system"
+
+// Экспортёры спанов
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	// Exporter куда отправлять спаны: none, stdout, file или otlp
+	Exporter string
+	// Endpoint адрес OTLP-коллектора (gRPC), host:port
+	Endpoint string
+	// Insecure отправлять спаны коллектору без TLS
+	Insecure bool
+	// File файл для экспортёра file, спаны дописываются в него построчно в JSON
+	File string
+	// ServiceName имя сервиса в спанах
+	ServiceName string
+	// SampleRatio доля трасс, которые начинаются в приложении и сохраняются
+	SampleRatio float64
+}
+
+// Setup устанавливает глобальный провайдер трассировки и распространение контекста в заголовках
+// traceparent и baggage. Возвращает функцию, которая отправляет накопленные спаны и останавливает
+// провайдер. При экспортёре none спаны не создаются, но входящий контекст трассы передаётся дальше.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	var closer io.Closer
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var file *os.File
+		file, err = os.OpenFile(config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+		if config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(config.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer трассировщик приложения из глобального провайдера
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start начинает внутренний спан с именем name
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// WithLogger добавляет к логеру из контекста идентификатор трассы, чтобы записи лога можно было
+// сопоставить со спанами. Без активной трассы контекст не меняется.
+func WithLogger(ctx context.Context) context.Context {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ctx
+	}
+	return logger.NewContext(ctx, logger.FromContext(ctx).With("trace_id", spanContext.TraceID().String()))
+}
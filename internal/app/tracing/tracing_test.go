@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"github.com/keyjin88/go-loyalty-system/internal/app/logger"
+	"go.opentelemetry.io/otel"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	if err := logger.Initialize("info"); err != nil {
+		t.Fatal(err)
+	}
+	savedProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(savedProvider)
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter:    ExporterFile,
+		File:        file,
+		ServiceName: "gophermart-test",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := Start(context.Background(), "OrderService.SaveOrder")
+	if !span.SpanContext().IsValid() {
+		t.Fatal("expected a recording span")
+	}
+	if WithLogger(ctx) == ctx {
+		t.Error("expected the logger to get the trace id")
+	}
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"Name":"OrderService.SaveOrder"`, `"Value":"gophermart-test"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("trace file has no %s: %s", expected, data)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "disabled", exporter: ExporterNone},
+		{name: "empty means disabled", exporter: ""},
+		{name: "unknown exporter", exporter: "jaeger", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), Config{Exporter: tt.exporter})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil {
+				if err := shutdown(context.Background()); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}